@@ -4,9 +4,11 @@ import (
 	"bufio"
 	"encoding/json"
 	"fmt"
-	"log"
+	"io"
 	"os"
 	"strings"
+
+	"coursera-go/m/source/cli"
 )
 
 // We have a JSON data structure that holds information about animals. (was a table in the exercise)
@@ -103,8 +105,21 @@ func GetAnimalInformations(animals []Animal, animalName string, information stri
 
 // Here is where the program starts.
 func main() {
-	fmt.Println("Animal Informations")
-	fmt.Println("-------------------")
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// run is the whole program. It gets the command line arguments and the standard streams, and returns the exit code.
+//
+// Without arguments, the program asks the user for requests until "exit", as required by the assignment.
+// Requests can also be given as arguments (an animal and an information each), or one per line in a file, for example:
+// go run AnimalInformations.go --batch cow eat snake speak
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	var options cli.Options
+	flags := cli.NewFlagSet("AnimalInformations", stderr)
+	options.Register(flags)
+	if ok, code := cli.Parse(flags, args); !ok {
+		return code
+	}
 
 	// Let's create an Animal array and unmarshal the JSON data into it.
 
@@ -114,28 +129,125 @@ func main() {
 
 	if err != nil {
 		// handle error
-		log.Fatal(err)
+		return options.Report(stderr, "AnimalInformations", err)
 	}
 
+	if !options.Batch && flags.NArg() == 0 && options.Input == "" {
+		interactive(animals, stdin, stdout)
+		return cli.ExitOK
+	}
+	return options.Report(stderr, "AnimalInformations", batch(animals, options, flags.Args(), stdin, stdout, stderr))
+}
+
+// interactive is the original program: it asks the user for requests, until "exit" or the end of the input.
+func interactive(animals []Animal, stdin io.Reader, stdout io.Writer) {
+	fmt.Fprintln(stdout, "Animal Informations")
+	fmt.Fprintln(stdout, "-------------------")
+
 	// Let's ask the user for the name of the animal and the information they want to know about it.
 	// We'll then call GetAnimalInformations to get the information and print it on screen.
 
+	// The reader is shared by all the requests, so that nothing it has buffered is lost between two of them.
+	reader := bufio.NewReader(stdin)
+
 	// Loop for user commands until "exit".
 	for {
-		animal, info, err := ManageUserInput(animals)
+		animal, info, err := ManageUserInput(animals, reader, stdout)
 		if err != nil {
-			if err.Error() == "exit" {
-				fmt.Println("Exiting program.")
+			if err.Error() == "exit" || err == io.EOF {
+				fmt.Fprintln(stdout, "Exiting program.")
 				break
 			}
-			fmt.Println("Error: ", err)
+			fmt.Fprintln(stdout, "Error: ", err)
+			continue
+		}
+
+		result := GetAnimalInformations(animals, animal, info)
+		fmt.Fprintln(stdout, result)
+	}
+}
+
+// answer is the result of one request, as printed in JSON.
+type answer struct {
+	Animal      string `json:"animal"`
+	Information string `json:"information"`
+	Answer      string `json:"answer,omitempty"`
+	Error       string `json:"error,omitempty"`
+}
+
+// batch answers the requests given as arguments (two words each), or read from the -in file (or standard input), one per line.
+// Invalid requests are reported and skipped. If there was any, the returned error says how many.
+func batch(animals []Animal, options cli.Options, args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	requests, err := readRequests(options, args, stdin)
+	if err != nil {
+		return err
+	}
+
+	answers := make([]answer, 0, len(requests))
+	failed := 0
+	for _, request := range requests {
+		animal, info, err := ParseRequest(animals, request)
+		if err != nil {
+			failed++
+			answers = append(answers, answer{Animal: animal, Information: info, Error: err.Error()})
+			if !options.JSON {
+				fmt.Fprintf(stderr, "%q: %v\n", request, err)
+			}
 			continue
 		}
 
 		result := GetAnimalInformations(animals, animal, info)
-		println(result)
+		answers = append(answers, answer{Animal: animal, Information: info, Answer: result})
+		switch {
+		case options.JSON:
+		case options.Quiet:
+			fmt.Fprintln(stdout, result)
+		default:
+			fmt.Fprintf(stdout, "%s %s: %s\n", animal, info, result)
+		}
 	}
 
+	if options.JSON {
+		if err := cli.WriteJSON(stdout, answers); err != nil {
+			return err
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d invalid request(s) out of %d", failed, len(requests))
+	}
+	return nil
+}
+
+// readRequests returns the requests given as arguments (two words each) or, if there are none, the lines of the -in file (or standard input).
+// Empty lines are skipped, and "exit" ends the requests like in the interactive mode.
+func readRequests(options cli.Options, args []string, stdin io.Reader) (requests []string, err error) {
+	if len(args) > 0 {
+		if len(args)%2 != 0 {
+			return nil, cli.Usagef("requests are an animal and an information, got %d words", len(args))
+		}
+		for i := 0; i < len(args); i += 2 {
+			requests = append(requests, args[i]+" "+args[i+1])
+		}
+		return requests, nil
+	}
+
+	input, _, close, err := cli.OpenInput(options.Input, stdin)
+	if err != nil {
+		return nil, err
+	}
+	defer close()
+
+	scanner := bufio.NewScanner(input)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.EqualFold(line, "exit") {
+			break
+		}
+		if line != "" {
+			requests = append(requests, line)
+		}
+	}
+	return requests, scanner.Err()
 }
 
 // contains is used to check if a string is in a string array. It's just a helper function.
@@ -151,28 +263,15 @@ func contains(s []string, str string) bool {
 }
 
 // ManageUserInput is used to get the user input and sanitize it.
-func ManageUserInput(animals []Animal) (animal string, information string, err error) {
+func ManageUserInput(animals []Animal, reader *bufio.Reader, stdout io.Writer) (animal string, information string, err error) {
 
-	availableAnimals := make([]string, 0, len(animals))
-	// Let's parse the animals and see which animals are available.
-	for _, animal := range animals {
-		// if the animal is not already in the availableAnimals array, we add it.
-		if !contains(availableAnimals, animal.Name) {
-			availableAnimals = append(availableAnimals, animal.Name)
-		}
-	}
+	fmt.Fprintln(stdout, "")
+	fmt.Fprintln(stdout, "Enter a command: an animal and an information request (eat, move or speak).")
+	fmt.Fprintln(stdout, "Available animals: ", availableAnimals(animals))
+	fmt.Fprintf(stdout, `> `)
 
-	fmt.Println("")
-	fmt.Println("Enter a command: an animal and an information request (eat, move or speak).")
-	fmt.Println("Available animals: ", availableAnimals)
-	fmt.Printf(`> `)
-
-	var values = make([]string, 0, 2)
-
-	reader := bufio.NewReader(os.Stdin)
 	input, err := reader.ReadString('\n')
-	if err != nil {
-		fmt.Println("Error reading input: ", err)
+	if err != nil && (err != io.EOF || input == "") {
 		return "", "", err
 	}
 
@@ -180,29 +279,44 @@ func ManageUserInput(animals []Animal) (animal string, information string, err e
 	input = strings.ToLower(strings.TrimSpace(input))
 
 	// If user types "exit", return a special flag to signal the end of input.
-	if input == "exit" || input == "Exit" {
+	if input == "exit" {
 		return "", "", fmt.Errorf("exit")
 	}
 
-	values = strings.Split(input, " ")
+	return ParseRequest(animals, input)
+}
+
+// availableAnimals returns the names of the animals, each name only once.
+func availableAnimals(animals []Animal) []string {
+	names := make([]string, 0, len(animals))
+	for _, animal := range animals {
+		// if the animal is not already in the names array, we add it.
+		if !contains(names, animal.Name) {
+			names = append(names, animal.Name)
+		}
+	}
+	return names
+}
+
+// ParseRequest checks a request (an animal and an information request, like "cow eat") and returns its two words.
+// Even when the request is invalid, it returns the words it found, so that they can be reported.
+func ParseRequest(animals []Animal, request string) (animal string, information string, err error) {
+
+	values := strings.Fields(strings.ToLower(request))
 
 	if len(values) != 2 {
-		fmt.Println("Please enter 2 words: an animal (cow, bird or snake) and an information request (eat, move or speak).")
-		err = fmt.Errorf("Invalid request")
+		err = fmt.Errorf("Invalid request: please enter 2 words, an animal (%s) and an information request (eat, move or speak)", strings.Join(availableAnimals(animals), ", "))
 		return "", "", err
 	}
 
-	if !contains(availableAnimals, values[0]) {
-		fmt.Println("Please enter an animal.")
-		fmt.Println("Available animals: ", availableAnimals)
-		err = fmt.Errorf("Invalid animal")
-		return "", "", err
+	if !contains(availableAnimals(animals), values[0]) {
+		err = fmt.Errorf("Invalid animal: available animals are %s", strings.Join(availableAnimals(animals), ", "))
+		return values[0], values[1], err
 	}
 
 	if values[1] != "eat" && values[1] != "move" && values[1] != "speak" {
-		fmt.Println("Please enter an information request (eat, move or speak).")
-		err = fmt.Errorf("Invalid information request")
-		return "", "", err
+		err = fmt.Errorf("Invalid information request: please enter eat, move or speak")
+		return values[0], values[1], err
 	}
 
 	return values[0], values[1], nil
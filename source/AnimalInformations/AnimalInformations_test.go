@@ -1,7 +1,12 @@
 package main
 
 import (
+	"bytes"
+	"strings"
 	"testing"
+
+	"coursera-go/m/source/cli"
+	"coursera-go/m/source/cli/golden"
 )
 
 var constAnimals = []Animal{
@@ -188,3 +193,34 @@ func TestSpeak(t *testing.T) {
 		})
 	}
 }
+
+func TestRun(t *testing.T) {
+	testCases := []struct {
+		name     string
+		args     []string
+		stdin    string
+		wantCode int
+	}{
+		{name: "interactive", args: nil, stdin: "cow eat\nhello\nkangaroo move\nexit\nbird fly\n", wantCode: cli.ExitOK},
+		{name: "batch arguments", args: []string{"--batch", "cow", "eat", "snake", "speak"}, wantCode: cli.ExitOK},
+		{name: "batch stdin", args: []string{"--batch"}, stdin: "cow eat\n\nBIRD Move\nexit\nsnake eat\n", wantCode: cli.ExitOK},
+		{name: "quiet", args: []string{"--quiet", "cow", "eat", "kangaroo", "speak"}, wantCode: cli.ExitOK},
+		{name: "json", args: []string{"--json", "cow", "eat", "dog", "speak"}, wantCode: cli.ExitFailure},
+		{name: "invalid request", args: []string{"--batch"}, stdin: "cow eat\ncow sing\n", wantCode: cli.ExitFailure},
+		{name: "odd number of words", args: []string{"--batch", "cow", "eat", "bird"}, wantCode: cli.ExitUsage},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			code := run(tc.args, strings.NewReader(tc.stdin), &stdout, &stderr)
+			if code != tc.wantCode {
+				t.Errorf("run(%v) exited with %d; want %d (stderr: %s)", tc.args, code, tc.wantCode, stderr.String())
+			}
+			golden.Assert(t, "run-"+strings.ReplaceAll(tc.name, " ", "-"), stdout.String())
+			if code != cli.ExitOK && stderr.Len() == 0 {
+				t.Errorf("run(%v) failed without an error message", tc.args)
+			}
+		})
+	}
+}
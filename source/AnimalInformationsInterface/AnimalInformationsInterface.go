@@ -2,16 +2,61 @@ package main
 
 import (
 	"bufio"
+	"bytes"
 	"fmt"
+	"io"
 	"os"
 	"strings"
+
+	"coursera-go/m/source/cli"
 )
 
 // Here is our new program. It is similar to the previous version of the program, except that we use the Animal interface instead of the Animal struct.
 
 func main() {
-	fmt.Println("Animal Informations V2 - Interfaces")
-	fmt.Println("-----------------------------------")
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// output is where the animals and the commands print. When it is nil, they print on os.Stdout.
+// The Animal methods have no parameters (this is what the assignment asks for), so this is how run can send what they print elsewhere.
+var output io.Writer
+
+// out returns the writer to print to. We look at os.Stdout each time, rather than once when the program starts, so that the tests can replace it.
+func out() io.Writer {
+	if output != nil {
+		return output
+	}
+	return os.Stdout
+}
+
+// run is the whole program. It gets the command line arguments and the standard streams, and returns the exit code.
+//
+// Without arguments, the program asks the user for commands until "exit", as required by the assignment.
+// Commands can also be given as arguments (one per argument), or one per line in a file, for example:
+// go run AnimalInformationsInterface.go --batch "newanimal bessie cow" "query bessie speak"
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	var options cli.Options
+	flags := cli.NewFlagSet("AnimalInformationsInterface", stderr)
+	options.Register(flags)
+	if ok, code := cli.Parse(flags, args); !ok {
+		return code
+	}
+
+	previous := output
+	output = stdout
+	defer func() { output = previous }()
+
+	if !options.Batch && flags.NArg() == 0 && options.Input == "" {
+		interactive(stdin)
+		return cli.ExitOK
+	}
+	return options.Report(stderr, "AnimalInformationsInterface", batch(options, flags.Args(), stdin, stdout, stderr))
+}
+
+// interactive is the original program: it asks the user for commands, until "exit" or the end of the input.
+func interactive(stdin io.Reader) {
+	fmt.Fprintln(out(), "Animal Informations V2 - Interfaces")
+	fmt.Fprintln(out(), "-----------------------------------")
 
 	// Let's create an empty Animal slice, that will be used to store the animals created by the user.
 
@@ -20,14 +65,21 @@ func main() {
 	// Here we print out some instructions so the user knows what to do. They can see it again by typing "help".
 	PrintInstructions(animals)
 
+	// The reader is shared by all the commands, so that nothing it has buffered is lost between two of them.
+	reader := bufio.NewReader(stdin)
+
 	// Loop for user commands until "exit".
 	for {
-		fmt.Print("> ")
-		reader := bufio.NewReader(os.Stdin)
+		fmt.Fprint(out(), "> ")
 
 		input, err := reader.ReadString('\n')
-		if err != nil {
-			fmt.Println("Technical error: on reading user input ", err)
+		if err == io.EOF && input == "" {
+			// The end of the input is like typing "exit".
+			fmt.Fprintln(out(), "Exiting program.")
+			break
+		}
+		if err != nil && err != io.EOF {
+			fmt.Fprintln(out(), "Technical error: on reading user input ", err)
 			continue
 		}
 
@@ -38,7 +90,7 @@ func main() {
 		if err != nil {
 			// Special case: user wants to exit the program.
 			if err.Error() == "exit" {
-				fmt.Println("Exiting program.")
+				fmt.Fprintln(out(), "Exiting program.")
 				break
 			}
 			// Special case: user wants to see the instructions again.
@@ -47,7 +99,7 @@ func main() {
 				continue
 			} else {
 				// All other errors
-				fmt.Println("Error: ", err)
+				fmt.Fprintln(out(), "Error: ", err)
 			}
 			continue
 		}
@@ -57,6 +109,92 @@ func main() {
 	}
 }
 
+// commandResult is the result of one command, as printed in JSON.
+type commandResult struct {
+	Command string `json:"command"`
+	Output  string `json:"output,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// batch runs the commands given as arguments, or read from the -in file (or standard input), one per line.
+// Invalid commands are reported and skipped. If there was any, the returned error says how many.
+// With --quiet, only the answers to the queries are printed.
+func batch(options cli.Options, args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	commands, err := readCommands(options, args, stdin)
+	if err != nil {
+		return err
+	}
+
+	var animals []Animal
+	results := make([]commandResult, 0, len(commands))
+	failed := 0
+
+	for _, input := range commands {
+		command, err := ManageUserInput(input, animals)
+		if err != nil && err.Error() == "exit" {
+			break
+		}
+		if err != nil && err.Error() != "help" {
+			failed++
+			results = append(results, commandResult{Command: input, Error: err.Error()})
+			if !options.JSON {
+				fmt.Fprintf(stderr, "%q: %v\n", input, err)
+			}
+			continue
+		}
+
+		// We catch what the command prints, to decide what to do with it.
+		var printed bytes.Buffer
+		output = &printed
+		if err != nil {
+			PrintInstructions(animals)
+		} else {
+			animals = ExecuteCommand(command, animals)
+		}
+		output = stdout
+
+		results = append(results, commandResult{Command: input, Output: strings.TrimSpace(printed.String())})
+		switch {
+		case options.JSON:
+		case options.Quiet && !strings.HasPrefix(command, "query"):
+		default:
+			io.Copy(stdout, &printed)
+		}
+	}
+
+	if options.JSON {
+		if err := cli.WriteJSON(stdout, results); err != nil {
+			return err
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d invalid command(s) out of %d", failed, len(commands))
+	}
+	return nil
+}
+
+// readCommands returns the commands given as arguments or, if there are none, the lines of the -in file (or standard input).
+// Empty lines are skipped.
+func readCommands(options cli.Options, args []string, stdin io.Reader) (commands []string, err error) {
+	if len(args) > 0 {
+		return args, nil
+	}
+
+	input, _, close, err := cli.OpenInput(options.Input, stdin)
+	if err != nil {
+		return nil, err
+	}
+	defer close()
+
+	scanner := bufio.NewScanner(input)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			commands = append(commands, line)
+		}
+	}
+	return commands, scanner.Err()
+}
+
 // Let's define an interface, called Animal, that will be used to get the information about the animal.
 // Eat, Move and Speak are the methods that will be used to get the information about the animal.
 // I can't think of a better way to do it, as we'll have to determine on which animal we are working on, and then call the appropriate method.
@@ -86,15 +224,15 @@ type Snake struct {
 
 // For the Cow type, we implement the Eat, Move and Speak methods.
 func (c Cow) Eat() {
-	fmt.Println("grass")
+	fmt.Fprintln(out(), "grass")
 }
 
 func (c Cow) Move() {
-	fmt.Println("walk")
+	fmt.Fprintln(out(), "walk")
 }
 
 func (c Cow) Speak() {
-	fmt.Println("moo")
+	fmt.Fprintln(out(), "moo")
 }
 
 func (c Cow) GetName() string {
@@ -106,15 +244,15 @@ func (c Cow) GetName() string {
 // For the Bird type, we implement the Eat, Move and Speak methods.
 
 func (b Bird) Eat() {
-	fmt.Println("worms")
+	fmt.Fprintln(out(), "worms")
 }
 
 func (b Bird) Move() {
-	fmt.Println("fly")
+	fmt.Fprintln(out(), "fly")
 }
 
 func (b Bird) Speak() {
-	fmt.Println("peep")
+	fmt.Fprintln(out(), "peep")
 }
 
 func (b Bird) GetName() string {
@@ -126,15 +264,15 @@ func (b Bird) GetName() string {
 // For the Snake type, we implement the Eat, Move and Speak methods.
 
 func (s Snake) Eat() {
-	fmt.Println("mice")
+	fmt.Fprintln(out(), "mice")
 }
 
 func (s Snake) Move() {
-	fmt.Println("slither")
+	fmt.Fprintln(out(), "slither")
 }
 
 func (s Snake) Speak() {
-	fmt.Println("hsss")
+	fmt.Fprintln(out(), "hsss")
 }
 
 func (s Snake) GetName() string {
@@ -170,7 +308,7 @@ func GetAnimalInformations(animals []Animal, animalName string, information stri
 				animal.Speak()
 				break
 			default:
-				fmt.Println("Unknown information request")
+				fmt.Fprintln(out(), "Unknown information request")
 			}
 		}
 		if animalfound {
@@ -179,7 +317,7 @@ func GetAnimalInformations(animals []Animal, animalName string, information stri
 	}
 
 	if !animalfound {
-		fmt.Println("Animal not found")
+		fmt.Fprintln(out(), "Animal not found")
 	}
 
 	// Note: as we sanitized the user input, error cases should not happen here. We still check for them in case the code is modified later, or called from another function.
@@ -204,18 +342,18 @@ func ExecuteCommand(command string, animals []Animal) []Animal {
 		switch animalType {
 		case "cow":
 			animals = append(animals, Cow{name: animalName})
-			fmt.Println("Created it!")
+			fmt.Fprintln(out(), "Created it!")
 			break
 		case "bird":
 			animals = append(animals, Bird{name: animalName})
-			fmt.Println("Created it!")
+			fmt.Fprintln(out(), "Created it!")
 			break
 		case "snake":
 			animals = append(animals, Snake{name: animalName})
-			fmt.Println("Created it!")
+			fmt.Fprintln(out(), "Created it!")
 			break
 		default:
-			fmt.Println("Unknown animal type")
+			fmt.Fprintln(out(), "Unknown animal type")
 		}
 		break
 
@@ -226,7 +364,7 @@ func ExecuteCommand(command string, animals []Animal) []Animal {
 		GetAnimalInformations(animals, animalName, info)
 		break
 	default:
-		fmt.Println("Unknown command")
+		fmt.Fprintln(out(), "Unknown command")
 	}
 	return animals
 }
@@ -290,17 +428,17 @@ func ManageUserInput(input string, animals []Animal) (query string, err error) {
 
 // PrintInstructions function will print the instructions to the user. Centralized here to avoid code duplication.
 func PrintInstructions(availableAnimals []Animal) {
-	fmt.Println("")
-	fmt.Println("Enter a command followed by parameters")
-	fmt.Println("newanimal <animal name> <animal type> (animal type = cow, bird or snake)")
-	fmt.Println("query <animal name> <information> (information = eat, move or speak)")
-	fmt.Println("Enter \"exit\" to exit the program.")
-	fmt.Println("Enter \"help\" to display this help again.")
+	fmt.Fprintln(out(), "")
+	fmt.Fprintln(out(), "Enter a command followed by parameters")
+	fmt.Fprintln(out(), "newanimal <animal name> <animal type> (animal type = cow, bird or snake)")
+	fmt.Fprintln(out(), "query <animal name> <information> (information = eat, move or speak)")
+	fmt.Fprintln(out(), "Enter \"exit\" to exit the program.")
+	fmt.Fprintln(out(), "Enter \"help\" to display this help again.")
 	// if no animals are available, we don't display the list of available animals.
 	if len(availableAnimals) == 0 {
-		fmt.Println("No animals available yet.")
+		fmt.Fprintln(out(), "No animals available yet.")
 	} else {
-		fmt.Println("Existing animals: ", Map(availableAnimals, func(animal Animal) string {
+		fmt.Fprintln(out(), "Existing animals: ", Map(availableAnimals, func(animal Animal) string {
 			// Here we do some type assertion (not that is really usefull, but it's a good example)
 			if _, ok := animal.(Cow); ok {
 				return animal.GetName() + " (cow)"
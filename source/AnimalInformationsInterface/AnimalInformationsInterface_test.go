@@ -4,7 +4,11 @@ import (
 	"bytes"
 	"errors"
 	"os"
+	"strings"
 	"testing"
+
+	"coursera-go/m/source/cli"
+	"coursera-go/m/source/cli/golden"
 )
 
 func TestGetAnimalInformations(t *testing.T) {
@@ -244,6 +248,36 @@ func TestManageUserInput(t *testing.T) {
 }
 
 // PLEASE NOTE
+func TestRun(t *testing.T) {
+	testCases := []struct {
+		name     string
+		args     []string
+		stdin    string
+		wantCode int
+	}{
+		{name: "interactive", args: nil, stdin: "newanimal bessie cow\nquery bessie speak\nquery john eat\nhelp\nexit\n", wantCode: cli.ExitOK},
+		{name: "batch arguments", args: []string{"--batch", "newanimal bessie cow", "query bessie eat", "newanimal john bird", "query john move"}, wantCode: cli.ExitOK},
+		{name: "batch stdin", args: []string{"--batch"}, stdin: "newanimal alex snake\n\nquery alex speak\nexit\nquery alex eat\n", wantCode: cli.ExitOK},
+		{name: "quiet", args: []string{"--quiet", "newanimal bessie cow", "query bessie speak"}, wantCode: cli.ExitOK},
+		{name: "json", args: []string{"--json", "newanimal bessie cow", "query bessie speak", "query john eat"}, wantCode: cli.ExitFailure},
+		{name: "invalid command", args: []string{"--batch"}, stdin: "newanimal bessie dog\n", wantCode: cli.ExitFailure},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			code := run(tc.args, strings.NewReader(tc.stdin), &stdout, &stderr)
+			if code != tc.wantCode {
+				t.Errorf("run(%v) exited with %d; want %d (stderr: %s)", tc.args, code, tc.wantCode, stderr.String())
+			}
+			golden.Assert(t, "run-"+strings.ReplaceAll(tc.name, " ", "-"), stdout.String())
+			if code != cli.ExitOK && stderr.Len() == 0 {
+				t.Errorf("run(%v) failed without an error message", tc.args)
+			}
+		})
+	}
+}
+
 //BeforeTest and AfterTest are used to test functions that does not return anything but print to stdout.
 
 // BeforeTest is a helper function to backup the real stdout, create a new pipe (reader and writer ends),
@@ -2,11 +2,15 @@ package main
 
 import (
 	"bufio"
+	"flag"
 	"fmt"
-	"log"
+	"io"
 	"os"
-	"strconv"
 	"strings"
+
+	"coursera-go/m/source/cli"
+	"coursera-go/m/source/generate"
+	"coursera-go/m/source/sorting"
 )
 
 // Dear reader, please note that I tried to make this code as simple as possible.
@@ -14,137 +18,244 @@ import (
 // I hope you find it easy to read and understand.
 
 func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
 
-	// First, we get the numbers to sort.
-	numbers, err := GetNumbersToSort()
-	if err != nil {
-		log.Fatal(err)
-		return
-	}
+// config holds everything given on the command line.
+type config struct {
+	cli.Options
+	algorithm   string
+	traceFormat string
+	traceFile   string
+	showStats   bool
+	replayFile  string
+	output      OutputOptions
+	generated   generate.Flags  // -dist, -seed, -min and -max, to sort generated numbers instead of given ones.
+	size        int             // How many numbers to generate.
+	set         map[string]bool // The flags given on the command line.
+	numbers     []string        // The numbers given as arguments, if any.
+}
 
-	// Sort the numbers with Bubble Sort algorithm.
-	BubbleSort(numbers)
+// run is the whole program. It gets the command line arguments and the standard streams, and returns the exit code.
+// Having it separate from main is what allows us to test the program as a whole.
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	var c config
+	flags := cli.NewFlagSet("BubbleSort", stderr)
+	c.Options.Register(flags)
 
-	// Print out the sorted numbers.
-	PrintResult(numbers)
-}
+	// By default we use bubble sort (this is the BubbleSort program after all), but any algorithm of the sorting package can be picked.
+	flags.StringVar(&c.algorithm, "algo", "bubble", "sorting algorithm to use ("+strings.Join(sorting.Names(), ", ")+")")
+	flags.StringVar(&c.traceFormat, "trace", "", "print each comparison, swap and sweep of the bubble sort (text or json)")
+	flags.StringVar(&c.traceFile, "trace-file", "", "file to write the trace to (default: standard error)")
+	flags.BoolVar(&c.showStats, "stats", false, "print the number of comparisons, swaps and passes of the bubble sort, next to the theoretical ones")
+	flags.StringVar(&c.output.Format, "format", "text", "output format ("+strings.Join(outputFormats, ", ")+")")
+	flags.BoolVar(&c.output.Reverse, "reverse", false, "print the numbers from the biggest to the smallest")
+	flags.BoolVar(&c.output.Unique, "unique", false, "print each value only once")
+	flags.IntVar(&c.output.Top, "top", 0, "only print the N first values (after -reverse and -unique), 0 prints them all")
+	flags.StringVar(&c.replayFile, "replay", "", "print every intermediate state of the slice from a trace written with -trace=json, then exit")
+	c.generated.Register(flags)
+	flags.IntVar(&c.size, "size", 20, "how many numbers to generate with -dist, -seed, -min or -max")
+	if ok, code := cli.Parse(flags, args); !ok {
+		return code
+	}
+	c.numbers = flags.Args()
+	c.set = map[string]bool{}
+	flags.Visit(func(f *flag.Flag) { c.set[f.Name] = true })
 
-func PrintResult(numbers []int) {
-	fmt.Println("Sorted numbers:")
-	for _, num := range numbers {
-		fmt.Printf("%d ", num)
+	// --json is a shortcut for -format=json, and --quiet removes the title of the text format.
+	if c.JSON {
+		c.output.Format = "json"
 	}
-	fmt.Println()
+	c.output.Quiet = c.Quiet
+
+	return c.Report(stderr, "BubbleSort", sortNumbers(c, stdin, stdout, stderr))
 }
 
-func GetNumbersToSort() (numbers []int, err error) {
+// sortNumbers reads, sorts and prints the numbers, as configured on the command line.
+func sortNumbers(c config, stdin io.Reader, stdout, stderr io.Writer) error {
 
-	// Prompt the user for a series of numbers.
-	// Split the input into a slice of strings on each space character.
-	// Trim trailing newline
-	// if the user entered more than 10 numbers, exit
-	// Convert the slice of strings to a slice of integers.
+	// Replay mode: we don't sort anything, we just rebuild the states from the trace.
+	if c.replayFile != "" {
+		file, err := os.Open(c.replayFile)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
 
-	numStrings, err := ManageUserInput()
-	if err != nil {
-		return nil, err
+		return ReplayTrace(file, stdout)
 	}
 
-	numbers, err = StringsToIntegers(numStrings)
+	sorter, err := sorting.Lookup(c.algorithm)
 	if err != nil {
-		return nil, err
+		return &cli.UsageError{Err: err}
 	}
-	return
-}
 
-func StringsToIntegers(numStrings []string) (numbers []int, err error) {
-	// Create a slice of the appropriate size.
-	numbers = make([]int, len(numStrings))
+	if err := c.output.Validate(); err != nil {
+		return &cli.UsageError{Err: err}
+	}
 
-	// Convert each string to an integer.
-	for i, numStr := range numStrings {
-		numbers[i], err = strconv.Atoi(numStr)
-		// In case of error, we return an empty slice and the error.
-		if err != nil {
-			fmt.Println("Error reading numbers: ", err)
-			return nil, err
-		}
+	// Only the bubble sort reports its steps and its stats.
+	isBubble := sorter.Name() == "bubble"
+	if c.showStats && !isBubble {
+		return cli.Usagef("-stats is only available with -algo=bubble")
 	}
-	return numbers, nil
-}
 
-func ManageUserInput() (values []string, err error) {
-	fmt.Println("Enter a series of integers (maximum 10), separated by spaces:")
+	var tracer *traceWriter
+	if c.traceFormat != "" {
+		if !isBubble {
+			return cli.Usagef("-trace is only available with -algo=bubble")
+		}
+
+		traceOutput := stderr
+		if c.traceFile != "" {
+			file, err := os.Create(c.traceFile)
+			if err != nil {
+				return err
+			}
+			defer file.Close()
+			traceOutput = file
+		}
 
-	values = make([]string, 0, 10)
+		tracer, err = newTraceWriter(traceOutput, c.traceFormat)
+		if err != nil {
+			return &cli.UsageError{Err: err}
+		}
+	}
 
-	reader := bufio.NewReader(os.Stdin)
-	input, err := reader.ReadString('\n')
+	// First, we get the numbers to sort.
+	numbers, err := getNumbers(c, stdin, stdout, stderr)
 	if err != nil {
-		fmt.Println("Error reading input: ", err)
-		return nil, err
+		return err
+	}
+
+	// Sort the numbers with the chosen algorithm (Bubble Sort unless told otherwise).
+	// The algorithms live in the sorting package, so that other programs can use them too.
+	var stats sorting.Stats
+	if isBubble {
+		var observe sorting.Observer[int]
+		if tracer != nil {
+			observe = tracer.Observe
+		}
+		stats = sorting.BubbleSortObserve(numbers, func(a, b int) bool { return a < b }, observe)
+		if tracer != nil {
+			if err := tracer.Flush(); err != nil {
+				return err
+			}
+		}
+	} else {
+		sorter.Sort(numbers)
 	}
 
-	input = strings.TrimSpace(input)
-	values = strings.Split(input, " ")
+	// Print out the sorted numbers.
+	if err := WriteResult(stdout, PrepareResult(numbers, c.output), c.output); err != nil {
+		return err
+	}
 
-	if len(values) > 10 {
-		fmt.Println("Please enter 10 or fewer numbers.")
-		err = fmt.Errorf("Too many numbers")
-		return nil, err
+	if c.showStats {
+		// Stats are for humans: when the output is meant for another tool, they go to standard error so that they don't get in the way.
+		if c.output.Format != "text" {
+			if c.JSON {
+				return cli.WriteJSON(stderr, stats)
+			}
+			PrintStats(stderr, stats, len(numbers))
+			return nil
+		}
+		PrintStats(stdout, stats, len(numbers))
 	}
-	return
+	return nil
 }
 
-// BubbleSort sorts a slice of integers using the bubble sort algorithm.
+// PrintStats prints the work done by the bubble sort, next to what the theory says for the same number of elements.
+func PrintStats(w io.Writer, stats sorting.Stats, n int) {
+	best, average, worst := sorting.BubbleSortComplexity(n)
 
-// Here is how a bubble sort works:
-// 1. Start at the beginning of the list.
-// 2. Compare the first two elements.
-// 3. If the first is greater than the second, swap them.
-// 4. Go to the next pair, and so on, continuously making sweeps of the list until sorted.
-// 5. In doing so, the smaller items slowly "bubble" up to the beginning of the list.
-// 6. If we ever make a sweep without swapping, the list is sorted and we can stop.
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "Bubble sort statistics for %d numbers:\n", n)
+	fmt.Fprintf(w, "%-12s %10s %10s %12s %10s\n", "", "actual", "best", "average", "worst")
+	fmt.Fprintf(w, "%-12s %10d %10.0f %12.2f %10.0f\n", "comparisons", stats.Comparisons, best.Comparisons, average.Comparisons, worst.Comparisons)
+	fmt.Fprintf(w, "%-12s %10d %10.0f %12.2f %10.0f\n", "swaps", stats.Swaps, best.Swaps, average.Swaps, worst.Swaps)
+	fmt.Fprintf(w, "%-12s %10d %10.0f %12.2f %10.0f\n", "passes", stats.Passes, best.Passes, average.Passes, worst.Passes)
+	fmt.Fprintf(w, "%-12s %10t\n", "early exit", stats.EarlyExit)
+}
 
-// We do it N-1 times, where N is the number of items in the list, to guarantee that it is sorted.
-// If we make a sweep and make no swaps, the list is sorted and we can stop
+// getNumbers generates the numbers to sort when one of -dist, -seed, -min, -max or -size is given, and gets them with GetNumbersToSort otherwise.
+// The seed is printed on stderr, so that the same numbers can be sorted again.
+func getNumbers(c config, stdin io.Reader, stdout, stderr io.Writer) ([]int, error) {
+	if !(c.set["dist"] || c.set["seed"] || c.set["min"] || c.set["max"] || c.set["size"]) {
+		return GetNumbersToSort(c.numbers, c.Options, stdin, stdout)
+	}
 
-func BubbleSort(numbers []int) {
+	if len(c.numbers) > 0 || c.Input != "" {
+		return nil, cli.Usagef("the numbers are either generated (-dist, -seed, -min, -max, -size) or given, not both")
+	}
+	if c.size < 0 {
+		return nil, cli.Usagef("-size must be 0 or more, got %d", c.size)
+	}
+	generator, err := c.generated.Generator()
+	if err != nil {
+		return nil, &cli.UsageError{Err: err}
+	}
+	if c.Verbose() {
+		fmt.Fprintf(stderr, "Generated %d numbers, distribution %s, seed %d\n", c.size, generator, generator.Seed)
+	}
+	return generator.Generate(c.size), nil
+}
 
-	for i := 0; i < len(numbers); i++ {
+// GetNumbersToSort gets the numbers to sort from the first source available:
+// 1. the command line arguments, if there are any (they can't be given with -in as well),
+// 2. the file given with the -in flag ("-" means standard input),
+// 3. standard input. When it is a pipe or a file, or in batch mode, we read it until its end. When it is the terminal, we prompt the user for one line.
+// There is no limit on the number of values.
+func GetNumbersToSort(args []string, options cli.Options, stdin io.Reader, stdout io.Writer) (numbers []int, err error) {
 
-		// At the beginning of each sweep, we setup a flag to indicate if we made a swap (we did not yet, so false)
-		didSwap := false
-		firstIndex := 0
+	if len(args) > 0 {
+		// Picking one of them would silently ignore the other.
+		if options.Input != "" {
+			return nil, cli.Usagef("give the numbers as arguments or with -in, not both")
+		}
+		return StringsToIntegers(args)
+	}
 
-		// We need to make sure that we don't go out of bounds
-		for firstIndex < (len(numbers) - i - 1) {
-			firstNumber := numbers[firstIndex]
-			secondNumber := numbers[firstIndex+1]
+	if options.Input == "" && !options.Batch && cli.IsTerminal(stdin) {
+		return ManageUserInput(stdin, stdout)
+	}
 
-			// If the first number is greater than the second number, swap them
-			if firstNumber > secondNumber {
-				Swap(numbers, firstIndex)
-				// We made a swap, so set the flag to true
-				didSwap = true
-			}
-			firstIndex++
-		}
-		// if we didn't make a swap, the list is sorted and we can stop
-		if !didSwap {
-			break
+	input, name, close, err := cli.OpenInput(options.Input, stdin)
+	if err != nil {
+		return nil, err
+	}
+	defer close()
+	return ReadIntegers(input, name)
+}
+
+// StringsToIntegers converts the numbers given as command line arguments.
+// Each argument is read with a NumberScanner, like standard input, so "3,1,2" is three numbers there too.
+// The error tells which argument holds the bad value, and where in it.
+func StringsToIntegers(numStrings []string) (numbers []int, err error) {
+	// There is at least one number per argument, usually exactly one.
+	numbers = make([]int, 0, len(numStrings))
+
+	for i, numStr := range numStrings {
+		values, err := ReadIntegers(strings.NewReader(numStr), fmt.Sprintf("argument %d", i+1))
+		// In case of error, we return an empty slice and the error.
+		if err != nil {
+			return nil, err
 		}
+		numbers = append(numbers, values...)
 	}
+	return numbers, nil
 }
 
-// Swap swaps the position of two elements in a slice of integers.
-func Swap(numbers []int, index int) {
-	// We need to make sure that we don't go out of bounds
-	// We already checked this in BubbleSort, but we check again here for safety (if this code is later on called from another function)
-	if index >= len(numbers)-1 {
-		return
+// ManageUserInput prompts the user for one line of numbers, and reads them.
+func ManageUserInput(stdin io.Reader, stdout io.Writer) (numbers []int, err error) {
+	fmt.Fprintln(stdout, "Enter a series of integers, separated by spaces or commas:")
+
+	reader := bufio.NewReader(stdin)
+	input, err := reader.ReadString('\n')
+	// The user may end the input with Ctrl-D instead of Enter, this is not an error.
+	if err != nil && err != io.EOF {
+		return nil, err
 	}
-	originalValueAtIndex := numbers[index]
-	numbers[index] = numbers[index+1]
-	numbers[index+1] = originalValueAtIndex
+
+	return ReadIntegers(strings.NewReader(input), "stdin")
 }
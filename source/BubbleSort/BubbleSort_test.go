@@ -1,63 +1,18 @@
 package main
 
 import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
 	"reflect"
+	"strings"
 	"testing"
-)
-
-func TestSortUserInput(t *testing.T) {
 
-	testCases := []struct {
-		name  string
-		given []int
-		want  []int
-	}{
-		{
-			name:  "Multiple numbers in reverse order",
-			given: []int{5, 4, 3, 2, 1},
-			want:  []int{1, 2, 3, 4, 5},
-		},
-		{
-			name:  "Empty slice",
-			given: []int{},
-			want:  []int{},
-		},
-		{
-			name:  "Single value",
-			given: []int{1},
-			want:  []int{1},
-		},
-		{
-			name:  "Negative numbers",
-			given: []int{-5, -1, -3, -2, -4},
-			want:  []int{-5, -4, -3, -2, -1},
-		},
-		{
-			name:  "Negative and positive numbers",
-			given: []int{-5, -1, 3, -2, 4},
-			want:  []int{-5, -2, -1, 3, 4},
-		},
-		{
-			name:  "Already sorted",
-			given: []int{1, 2, 3, 4, 5},
-			want:  []int{1, 2, 3, 4, 5},
-		},
-	}
-
-	for _, tc := range testCases {
-		t.Run(tc.name, func(t *testing.T) {
-			original := make([]int, len(tc.given))
-			copy(original, tc.given)
-
-			BubbleSort(tc.given)
-			if !reflect.DeepEqual(tc.given, tc.want) {
-				t.Errorf("BubbleSort(%v) = %v; want %v", original, tc.given, tc.want)
-			} else {
-				t.Logf("BubbleSort(%v) = %v; want %v - is OK", original, tc.given, tc.want)
-			}
-		})
-	}
-}
+	"coursera-go/m/source/cli"
+	"coursera-go/m/source/cli/golden"
+	"coursera-go/m/source/sorting"
+)
 
 func TestStringsToIntegers(t *testing.T) {
 	// Normal Cases
@@ -76,6 +31,11 @@ func TestStringsToIntegers(t *testing.T) {
 			given: []string{"-1", "-2", "-3"},
 			want:  []int{-1, -2, -3},
 		},
+		{
+			name:  "separated by commas",
+			given: []string{"3,1,2", "5", "4,"},
+			want:  []int{3, 1, 2, 5, 4},
+		},
 	}
 
 	for _, tc := range normalTestCases {
@@ -94,18 +54,289 @@ func TestStringsToIntegers(t *testing.T) {
 	errorTestCases := []struct {
 		name  string
 		given []string
+		want  string // The error message.
 	}{
 		{
 			name:  "non-integer strings",
 			given: []string{"a", "b", "c"},
+			want:  `argument 1:1:1: "a" is not an integer`,
+		},
+		{
+			name:  "non-integer after a comma",
+			given: []string{"1", "2,x"},
+			want:  `argument 2:1:3: "x" is not an integer`,
 		},
 	}
 
 	for _, tc := range errorTestCases {
 		t.Run(tc.name, func(t *testing.T) {
 			_, err := StringsToIntegers(tc.given)
+			if err == nil || err.Error() != tc.want {
+				t.Errorf("StringsToIntegers(%v) returned the error %v; want %q", tc.given, err, tc.want)
+			}
+		})
+	}
+}
+
+func TestTraceAndReplay(t *testing.T) {
+	// We write a JSON trace of a sort, and check that replaying it shows every state of the slice.
+	var trace bytes.Buffer
+	tracer, err := newTraceWriter(&trace, "json")
+	if err != nil {
+		t.Fatalf("newTraceWriter returned unexpected error: %v", err)
+	}
+	sorting.BubbleSortObserve([]int{3, 1, 2}, func(a, b int) bool { return a < b }, tracer.Observe)
+	if err := tracer.Flush(); err != nil {
+		t.Fatalf("Flush returned unexpected error: %v", err)
+	}
+
+	var replay bytes.Buffer
+	if err := ReplayTrace(&trace, &replay); err != nil {
+		t.Fatalf("ReplayTrace returned unexpected error: %v", err)
+	}
+
+	want := "step 0: [3 1 2]\nstep 1: [1 3 2]\nstep 2: [1 2 3]\n"
+	if replay.String() != want {
+		t.Errorf("ReplayTrace printed %q; want %q", replay.String(), want)
+	}
+}
+
+func TestReplayTraceErrors(t *testing.T) {
+	testCases := []struct {
+		name  string
+		trace string
+	}{
+		{name: "text trace", trace: "start    [3 1 2]\n"},
+		{name: "empty trace", trace: ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var replay bytes.Buffer
+			if err := ReplayTrace(strings.NewReader(tc.trace), &replay); err == nil {
+				t.Errorf("ReplayTrace(%q) expected an error but got nil", tc.trace)
+			}
+		})
+	}
+}
+
+func TestNewTraceWriterUnknownFormat(t *testing.T) {
+	if _, err := newTraceWriter(&bytes.Buffer{}, "xml"); err == nil {
+		t.Errorf("newTraceWriter with format xml expected an error but got nil")
+	}
+}
+
+func TestReadIntegers(t *testing.T) {
+	testCases := []struct {
+		name  string
+		given string
+		want  []int
+	}{
+		{name: "spaces", given: "3 1 2", want: []int{3, 1, 2}},
+		{name: "commas", given: "3,1,2", want: []int{3, 1, 2}},
+		{name: "new lines", given: "3\n1\n2\n", want: []int{3, 1, 2}},
+		{name: "windows new lines", given: "3\r\n1\r\n2\r\n", want: []int{3, 1, 2}},
+		{name: "mixed separators", given: " 3, 1\t\n\n-2 ,4,", want: []int{3, 1, -2, 4}},
+		{name: "more than 10 numbers", given: "12 11 10 9 8 7 6 5 4 3 2 1", want: []int{12, 11, 10, 9, 8, 7, 6, 5, 4, 3, 2, 1}},
+		{name: "empty input", given: "", want: nil},
+		{name: "only separators", given: " ,\n ", want: nil},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := ReadIntegers(strings.NewReader(tc.given), "test")
+			if err != nil {
+				t.Fatalf("ReadIntegers(%q) returned unexpected error: %v", tc.given, err)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("ReadIntegers(%q) = %v; want %v", tc.given, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestReadIntegersErrorPosition(t *testing.T) {
+	testCases := []struct {
+		name      string
+		given     string
+		wantError string
+	}{
+		{name: "first value", given: "abc 1 2", wantError: `test:1:1: "abc" is not an integer`},
+		{name: "after commas", given: "1,2,x3", wantError: `test:1:5: "x3" is not an integer`},
+		{name: "third line", given: "1 2\n3 4\n  5 6.5 7", wantError: `test:3:5: "6.5" is not an integer`},
+		{name: "after accents", given: "é 1", wantError: `test:1:1: "é" is not an integer`},
+		{name: "out of range", given: "1\n99999999999999999999999", wantError: `test:2:1: "99999999999999999999999" is too big to fit in an integer`},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := ReadIntegers(strings.NewReader(tc.given), "test")
 			if err == nil {
-				t.Errorf("StringsToIntegers(%v) expected an error but got nil", tc.given)
+				t.Fatalf("ReadIntegers(%q) expected an error but got nil", tc.given)
+			}
+			if err.Error() != tc.wantError {
+				t.Errorf("ReadIntegers(%q) error = %q; want %q", tc.given, err.Error(), tc.wantError)
+			}
+			var parseError *ParseError
+			if !errors.As(err, &parseError) {
+				t.Errorf("ReadIntegers(%q) error is a %T; want a *ParseError", tc.given, err)
+			}
+		})
+	}
+}
+
+func TestNumberScannerStreams(t *testing.T) {
+	// The numbers are written through a pipe while they are read, as they would be by another program.
+	reader, writer := io.Pipe()
+	go func() {
+		for i := 0; i < 100000; i++ {
+			fmt.Fprintf(writer, "%d\n", i)
+		}
+		writer.Close()
+	}()
+
+	scanner := NewNumberScanner(reader, "pipe")
+	count := 0
+	for scanner.Scan() {
+		if scanner.Value() != count {
+			t.Fatalf("value %d = %d; want %d", count, scanner.Value(), count)
+		}
+		count++
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatalf("NumberScanner returned unexpected error: %v", err)
+	}
+	if count != 100000 {
+		t.Errorf("NumberScanner read %d numbers; want 100000", count)
+	}
+}
+
+func TestManageUserInput(t *testing.T) {
+	// Only the first line is read from the terminal.
+	got, err := ManageUserInput(strings.NewReader("5 4 3 2 1 0 -1 -2 -3 -4 -5\n6 7\n"), &bytes.Buffer{})
+	if err != nil {
+		t.Fatalf("ManageUserInput returned unexpected error: %v", err)
+	}
+	want := []int{5, 4, 3, 2, 1, 0, -1, -2, -3, -4, -5}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ManageUserInput = %v; want %v", got, want)
+	}
+}
+
+func TestPrepareResult(t *testing.T) {
+	sorted := []int{-4, 1, 2, 2, 3, 7, 7, 9}
+
+	testCases := []struct {
+		name    string
+		options OutputOptions
+		want    []int
+	}{
+		{name: "no options", options: OutputOptions{}, want: []int{-4, 1, 2, 2, 3, 7, 7, 9}},
+		{name: "reverse", options: OutputOptions{Reverse: true}, want: []int{9, 7, 7, 3, 2, 2, 1, -4}},
+		{name: "unique", options: OutputOptions{Unique: true}, want: []int{-4, 1, 2, 3, 7, 9}},
+		{name: "top", options: OutputOptions{Top: 3}, want: []int{-4, 1, 2}},
+		{name: "top is bigger than the input", options: OutputOptions{Top: 30}, want: []int{-4, 1, 2, 2, 3, 7, 7, 9}},
+		{name: "top 3 biggest distinct values", options: OutputOptions{Reverse: true, Unique: true, Top: 3}, want: []int{9, 7, 3}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := PrepareResult(sorted, tc.options)
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("PrepareResult(%v, %+v) = %v; want %v", sorted, tc.options, got, tc.want)
+			}
+		})
+	}
+
+	if !reflect.DeepEqual(sorted, []int{-4, 1, 2, 2, 3, 7, 7, 9}) {
+		t.Errorf("PrepareResult modified its input: %v", sorted)
+	}
+}
+
+func TestWriteResult(t *testing.T) {
+	testCases := []struct {
+		format  string
+		numbers []int
+		want    string
+	}{
+		{format: "text", numbers: []int{1, 2, 3}, want: "Sorted numbers:\n1 2 3 \n"},
+		{format: "json", numbers: []int{1, 2, 3}, want: "[1,2,3]\n"},
+		{format: "json", numbers: nil, want: "[]\n"},
+		{format: "csv", numbers: []int{-1, 2, 3}, want: "-1,2,3\n"},
+		{format: "lines", numbers: []int{1, 2, 3}, want: "1\n2\n3\n"},
+		// The longest bar is 50 characters long: a quarter of it, rounded, is 13.
+		{format: "histogram", numbers: []int{1, 1, 1, 1, 3}, want: "1 | " + strings.Repeat("#", 50) + " 4\n2 |  0\n3 | " + strings.Repeat("#", 13) + " 1\n"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.format, func(t *testing.T) {
+			var out bytes.Buffer
+			if err := WriteResult(&out, tc.numbers, OutputOptions{Format: tc.format}); err != nil {
+				t.Fatalf("WriteResult(%v, %q) returned unexpected error: %v", tc.numbers, tc.format, err)
+			}
+			if got := out.String(); got != tc.want {
+				t.Errorf("WriteResult(%v, %q) = %q; want %q", tc.numbers, tc.format, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestWriteHistogramRanges(t *testing.T) {
+	var out bytes.Buffer
+	if err := WriteHistogram(&out, []int{0, 5, 10, 15, 19}, 2, 3); err != nil {
+		t.Fatalf("WriteHistogram returned unexpected error: %v", err)
+	}
+	want := "  0..9 | ## 2\n10..19 | ### 3\n"
+	if out.String() != want {
+		t.Errorf("WriteHistogram = %q; want %q", out.String(), want)
+	}
+}
+
+func TestOutputOptionsValidate(t *testing.T) {
+	if err := (OutputOptions{Format: "xml"}).Validate(); err == nil {
+		t.Errorf("Validate with format xml expected an error but got nil")
+	}
+	if err := (OutputOptions{Format: "text", Top: -1}).Validate(); err == nil {
+		t.Errorf("Validate with -top=-1 expected an error but got nil")
+	}
+	if err := (OutputOptions{Format: "csv", Top: 3}).Validate(); err != nil {
+		t.Errorf("Validate returned unexpected error: %v", err)
+	}
+}
+
+func TestRun(t *testing.T) {
+	testCases := []struct {
+		name     string
+		args     []string
+		stdin    string
+		wantCode int
+	}{
+		{name: "arguments", args: []string{"--batch", "3", "1", "2"}, wantCode: cli.ExitOK},
+		{name: "arguments with commas", args: []string{"--batch", "3,1,2"}, wantCode: cli.ExitOK},
+		{name: "arguments and in", args: []string{"--batch", "-in", "numbers.txt", "3", "1", "2"}, wantCode: cli.ExitUsage},
+		{name: "stdin", args: []string{"--batch"}, stdin: "5,4\n3 -1\n", wantCode: cli.ExitOK},
+		{name: "quiet", args: []string{"--batch", "--quiet", "3", "1", "2"}, wantCode: cli.ExitOK},
+		{name: "json", args: []string{"--batch", "--json", "-reverse", "3", "1", "2"}, wantCode: cli.ExitOK},
+		{name: "stats", args: []string{"--batch", "-stats", "2", "4", "1", "3", "5"}, wantCode: cli.ExitOK},
+		{name: "invalid number", args: []string{"--batch"}, stdin: "1 2\n3 x\n", wantCode: cli.ExitFailure},
+		{name: "unknown algorithm", args: []string{"--batch", "-algo", "bogo", "1"}, wantCode: cli.ExitUsage},
+		{name: "unknown flag", args: []string{"--bogus"}, wantCode: cli.ExitUsage},
+		{name: "generated", args: []string{"--batch", "-stats", "-dist", "nearly-sorted:2", "-seed", "4", "-max", "100", "-size", "10"}, wantCode: cli.ExitOK},
+		{name: "generated gaussian", args: []string{"--batch", "--quiet", "-dist", "gaussian", "-seed", "9", "-min", "-50", "-max", "50"}, wantCode: cli.ExitOK},
+		{name: "generated and given", args: []string{"--batch", "-seed", "1", "3", "1", "2"}, wantCode: cli.ExitUsage},
+		{name: "unknown distribution", args: []string{"--batch", "-dist", "bogus"}, wantCode: cli.ExitUsage},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			code := run(tc.args, strings.NewReader(tc.stdin), &stdout, &stderr)
+			if code != tc.wantCode {
+				t.Errorf("run(%v) exited with %d; want %d (stderr: %s)", tc.args, code, tc.wantCode, stderr.String())
+			}
+			golden.Assert(t, "run-"+strings.ReplaceAll(tc.name, " ", "-"), stdout.String())
+			if code != cli.ExitOK && stderr.Len() == 0 {
+				t.Errorf("run(%v) failed without an error message", tc.args)
 			}
 		})
 	}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"unicode"
+)
+
+// ParseError is returned when the input holds something that is not an integer.
+// It tells where the bad value is, so that it can be found even in a big file.
+type ParseError struct {
+	Source string // Name of the input: "stdin", a file name...
+	Line   int    // Line of the bad value, starting at 1.
+	Column int    // Column of the first character of the bad value, starting at 1.
+	Token  string // The bad value itself.
+	Err    error  // The error returned by strconv.
+}
+
+func (e *ParseError) Error() string {
+	reason := "is not an integer"
+	if errors.Is(e.Err, strconv.ErrRange) {
+		reason = "is too big to fit in an integer"
+	}
+	return fmt.Sprintf("%s:%d:%d: %q %s", e.Source, e.Line, e.Column, e.Token, reason)
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// NumberScanner reads integers one by one from a reader, so that big inputs never have to be loaded in memory all at once.
+// Numbers can be separated by spaces, tabs, commas or new lines, in any combination.
+// It works like bufio.Scanner:
+//
+//	scanner := NewNumberScanner(file, "numbers.txt")
+//	for scanner.Scan() {
+//		fmt.Println(scanner.Value())
+//	}
+//	if err := scanner.Err(); err != nil {
+//		...
+//	}
+type NumberScanner struct {
+	reader *bufio.Reader
+	source string
+
+	// Position of the next rune to read.
+	line, column int
+
+	token []rune
+	value int
+	err   error
+}
+
+// NewNumberScanner returns a NumberScanner reading from r. The source is the name used in error messages.
+func NewNumberScanner(r io.Reader, source string) *NumberScanner {
+	return &NumberScanner{
+		reader: bufio.NewReader(r),
+		source: source,
+		line:   1,
+		column: 1,
+	}
+}
+
+// isSeparator tells if a rune separates two numbers.
+func isSeparator(r rune) bool {
+	return r == ',' || unicode.IsSpace(r)
+}
+
+// Scan reads the next number. It returns false at the end of the input or on the first error, which Err then returns.
+func (s *NumberScanner) Scan() bool {
+	if s.err != nil {
+		return false
+	}
+
+	s.token = s.token[:0]
+	var tokenLine, tokenColumn int
+
+	for {
+		r, _, err := s.reader.ReadRune()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			s.err = err
+			return false
+		}
+
+		line, column := s.line, s.column
+		if r == '\n' {
+			s.line++
+			s.column = 1
+		} else {
+			s.column++
+		}
+
+		if isSeparator(r) {
+			if len(s.token) > 0 {
+				// The separator ends the number we were reading.
+				break
+			}
+			// Separators before a number are skipped.
+			continue
+		}
+
+		if len(s.token) == 0 {
+			tokenLine, tokenColumn = line, column
+		}
+		s.token = append(s.token, r)
+	}
+
+	if len(s.token) == 0 {
+		// We reached the end of the input without finding another number.
+		return false
+	}
+
+	value, err := strconv.Atoi(string(s.token))
+	if err != nil {
+		s.err = &ParseError{Source: s.source, Line: tokenLine, Column: tokenColumn, Token: string(s.token), Err: err}
+		return false
+	}
+	s.value = value
+	return true
+}
+
+// Value returns the number read by the last call to Scan.
+func (s *NumberScanner) Value() int {
+	return s.value
+}
+
+// Err returns the first error met by Scan, or nil if the whole input was read.
+func (s *NumberScanner) Err() error {
+	return s.err
+}
+
+// ReadIntegers reads all the numbers of r. The source is the name used in error messages.
+func ReadIntegers(r io.Reader, source string) (numbers []int, err error) {
+	scanner := NewNumberScanner(r, source)
+	for scanner.Scan() {
+		numbers = append(numbers, scanner.Value())
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return numbers, nil
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// The -format flag picks how the sorted numbers are printed, so that the output can be piped into other tools:
+// text      the default, a title and the numbers on one line
+// json      a JSON array
+// csv       one CSV record with all the numbers
+// lines     one number per line
+// histogram an ASCII histogram of the values
+var outputFormats = []string{"text", "json", "csv", "lines", "histogram"}
+
+// OutputOptions are the flags that change what is printed.
+type OutputOptions struct {
+	Format  string
+	Reverse bool // Print from the biggest to the smallest.
+	Unique  bool // Print each value only once.
+	Top     int  // Only print the N first values (after -reverse and -unique). 0 means all of them.
+	Quiet   bool // Don't print the title of the text format.
+}
+
+// Validate checks the options before anything is read or sorted, so that a typo is reported right away.
+func (o OutputOptions) Validate() error {
+	if !contains(outputFormats, o.Format) {
+		return fmt.Errorf("unknown output format %q (available: %s)", o.Format, strings.Join(outputFormats, ", "))
+	}
+	if o.Top < 0 {
+		return fmt.Errorf("-top must be 0 (all the values) or more, got %d", o.Top)
+	}
+	return nil
+}
+
+// contains is used to check if a string is in a string array. It's just a helper function.
+func contains(s []string, str string) bool {
+	for _, v := range s {
+		if v == str {
+			return true
+		}
+	}
+	return false
+}
+
+// PrepareResult applies -unique, -reverse and -top to the sorted numbers. It returns a new slice and leaves numbers untouched.
+func PrepareResult(numbers []int, options OutputOptions) []int {
+	result := make([]int, 0, len(numbers))
+	for i, n := range numbers {
+		// The numbers are sorted, so duplicates are next to each other.
+		if options.Unique && i > 0 && n == numbers[i-1] {
+			continue
+		}
+		result = append(result, n)
+	}
+
+	if options.Reverse {
+		for i, j := 0, len(result)-1; i < j; i, j = i+1, j-1 {
+			result[i], result[j] = result[j], result[i]
+		}
+	}
+
+	if options.Top > 0 && options.Top < len(result) {
+		result = result[:options.Top]
+	}
+	return result
+}
+
+// WriteResult prints the numbers in the format of the options.
+func WriteResult(w io.Writer, numbers []int, options OutputOptions) error {
+	switch options.Format {
+	case "text":
+		var b strings.Builder
+		if !options.Quiet {
+			b.WriteString("Sorted numbers:\n")
+		}
+		for _, num := range numbers {
+			fmt.Fprintf(&b, "%d ", num)
+		}
+		b.WriteString("\n")
+		_, err := io.WriteString(w, b.String())
+		return err
+
+	case "json":
+		// A nil slice would be written as null, we want an empty array.
+		if numbers == nil {
+			numbers = []int{}
+		}
+		return json.NewEncoder(w).Encode(numbers)
+
+	case "csv":
+		record := make([]string, len(numbers))
+		for i, n := range numbers {
+			record[i] = strconv.Itoa(n)
+		}
+		writer := csv.NewWriter(w)
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+		writer.Flush()
+		return writer.Error()
+
+	case "lines":
+		var b strings.Builder
+		for _, n := range numbers {
+			b.WriteString(strconv.Itoa(n))
+			b.WriteString("\n")
+		}
+		_, err := io.WriteString(w, b.String())
+		return err
+
+	case "histogram":
+		return WriteHistogram(w, numbers, histogramBuckets, histogramWidth)
+
+	default:
+		return fmt.Errorf("unknown output format %q", options.Format)
+	}
+}
+
+// histogramBuckets is the maximum number of bars of the histogram, and histogramWidth the length of the longest bar.
+const (
+	histogramBuckets = 10
+	histogramWidth   = 50
+)
+
+// WriteHistogram prints an ASCII histogram of the numbers, with at most maxBuckets bars, the longest one being width characters long.
+// When there are few different values, each value gets its own bar. Otherwise, each bar covers a range of values of the same size.
+// The numbers don't need to be sorted.
+func WriteHistogram(w io.Writer, numbers []int, maxBuckets, width int) error {
+	if len(numbers) == 0 {
+		_, err := fmt.Fprintln(w, "(no values)")
+		return err
+	}
+
+	min, max := numbers[0], numbers[0]
+	for _, n := range numbers {
+		if n < min {
+			min = n
+		}
+		if n > max {
+			max = n
+		}
+	}
+
+	// Size of the range of values covered by each bucket. We compute it on unsigned numbers, as max - min can overflow an int.
+	valueRange := uint64(max) - uint64(min)
+	bucketSize := valueRange/uint64(maxBuckets) + 1
+	buckets := int(valueRange/bucketSize) + 1
+
+	counts := make([]int, buckets)
+	for _, n := range numbers {
+		counts[(uint64(n)-uint64(min))/bucketSize]++
+	}
+
+	biggest := 0
+	for _, c := range counts {
+		if c > biggest {
+			biggest = c
+		}
+	}
+
+	// Labels are printed in a column as wide as the widest one.
+	labels := make([]string, buckets)
+	labelWidth := 0
+	for i := range counts {
+		low := int(uint64(min) + uint64(i)*bucketSize)
+		high := int(uint64(low) + bucketSize - 1)
+		if i == buckets-1 {
+			high = max
+		}
+		if low == high {
+			labels[i] = strconv.Itoa(low)
+		} else {
+			labels[i] = fmt.Sprintf("%d..%d", low, high)
+		}
+		if len(labels[i]) > labelWidth {
+			labelWidth = len(labels[i])
+		}
+	}
+
+	var b strings.Builder
+	for i, c := range counts {
+		// Round the bar length, but never hide a bucket that has values.
+		bar := (c*width + biggest/2) / biggest
+		if bar == 0 && c > 0 {
+			bar = 1
+		}
+		fmt.Fprintf(&b, "%*s | %s %d\n", labelWidth, labels[i], strings.Repeat("#", bar), c)
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
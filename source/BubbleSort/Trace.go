@@ -0,0 +1,102 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"coursera-go/m/source/sorting"
+)
+
+// The -trace flag shows each step of the bubble sort, and the -replay flag rebuilds every intermediate state of the slice from a saved trace.
+// Example:
+// echo "3 1 2" | go run . -trace=json -trace-file=trace.jsonl
+// go run . -replay=trace.jsonl
+
+// traceWriter is an observer of the sort that writes each event, either as text or as JSON Lines (one JSON object per line).
+type traceWriter struct {
+	out    *bufio.Writer
+	format string
+	// err is the first write error. The observer can't return errors, so we keep it and report it in Flush.
+	err error
+}
+
+// newTraceWriter returns a traceWriter for the format given to the -trace flag.
+func newTraceWriter(w io.Writer, format string) (*traceWriter, error) {
+	if format != "text" && format != "json" {
+		return nil, fmt.Errorf("unknown trace format %q (available: text, json)", format)
+	}
+	return &traceWriter{out: bufio.NewWriter(w), format: format}, nil
+}
+
+// Observe writes one event. It is given to sorting.BubbleSortObserve.
+func (tw *traceWriter) Observe(e sorting.Event[int]) {
+	if tw.err != nil {
+		return
+	}
+	if tw.format == "json" {
+		var line []byte
+		line, tw.err = json.Marshal(e)
+		if tw.err == nil {
+			_, tw.err = fmt.Fprintf(tw.out, "%s\n", line)
+		}
+		return
+	}
+	_, tw.err = fmt.Fprintln(tw.out, e)
+}
+
+// Flush writes what is left in the buffer and returns the first error that happened while tracing.
+func (tw *traceWriter) Flush() error {
+	if tw.err != nil {
+		return tw.err
+	}
+	return tw.out.Flush()
+}
+
+// ReadTrace reads a trace written with -trace=json. Empty lines are skipped.
+func ReadTrace(r io.Reader) (events []sorting.Event[int], err error) {
+	scanner := bufio.NewScanner(r)
+	// The start event holds the whole slice, so a line can be much longer than the default 64KB limit of the scanner.
+	scanner.Buffer(make([]byte, 0, 64*1024), 1<<30)
+
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		var e sorting.Event[int]
+		if err := json.Unmarshal([]byte(line), &e); err != nil {
+			return nil, fmt.Errorf("line %d: not a JSON trace event (only traces written with -trace=json can be replayed): %v", lineNumber, err)
+		}
+		events = append(events, e)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+// ReplayTrace reads a trace and prints every intermediate state of the slice, one per line.
+func ReplayTrace(r io.Reader, w io.Writer) error {
+	events, err := ReadTrace(r)
+	if err != nil {
+		return err
+	}
+
+	states, err := sorting.Replay(events)
+	if err != nil {
+		return err
+	}
+
+	for step, state := range states {
+		if _, err := fmt.Fprintf(w, "step %d: %v\n", step, state); err != nil {
+			return err
+		}
+	}
+	return nil
+}
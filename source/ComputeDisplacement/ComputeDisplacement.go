@@ -1,74 +1,326 @@
 package main
 
 import (
+	"bufio"
+	"errors"
+	"flag"
 	"fmt"
+	"io"
+	"os"
 	"strconv"
+	"strings"
+
+	"coursera-go/m/source/cli"
+	"coursera-go/m/source/kinematics"
 )
 
 // let's define a function GenDisplaceFn that returns a function which computes displacement as a function of time, assuming the given values acceleration, initial velocity, and initial displacement.
 
+// GenDisplaceFn works in SI units only: a in m/s², v0 in m/s, s0 in m and the time in s, and the displacement is in m.
+// The values do not carry their units through it. Their units are checked and converted to SI when they are read
+// (see kinematics.ParseQuantity), so a velocity given for an acceleration is an error before anything is computed,
+// and the displacements are converted to the -unit only when they are printed.
 func GenDisplaceFn(a, v0, s0 float64) func(time float64) float64 {
 
 	// The function returned by GenDisplaceFn takes one float64 argument t, representing time, and return one float64 argument which is the displacement travelled after time t.
 
-	return func(t float64) float64 {
+	// Let us assume the following formula for displacement s as a function of time t, acceleration a, initial velocity vo, and initial displacement so.
+	// s = ½ a t2 + vot + so
+	// The formula lives in the kinematics package now, next to the velocity, the stopping distance and the rest, so we just hand out its method.
+	return kinematics.New(a, v0, s0).Displacement
+
+}
 
-		// Let us assume the following formula for displacement s as a function of time t, acceleration a, initial velocity vo, and initial displacement so.
-		// s = ½ a t2 + vot + so
-		return 0.5*a*t*t + v0*t + s0
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// config holds everything given on the command line.
+type config struct {
+	cli.Options
+	acceleration, initialVelocity, initialDisplacement float64
+	// set tells which of -a, -v0 and -s0 were given. The others are asked to the user, or are an error in batch mode.
+	set   map[string]bool
+	times []string // The times given as arguments, if any.
+	// unit is the unit the displacements are printed in, as given with -unit. Empty means metres, without showing the unit.
+	unit   string
+	output kinematics.Unit
+	// from, to and step are the range of times of the range mode (see Range.go), in seconds.
+	from, to, step float64
+	format         string
+	// scenarioFile is the JSON file of scenarios to evaluate (see Scenarios.go), and compare shows them side by side.
+	scenarioFile string
+	compare      bool
+}
+
+// quantityFlag is a flag for a value with a unit, like -a 9.81m/s^2. It stores the value in SI units.
+type quantityFlag struct {
+	value     *float64
+	dimension kinematics.Dimension
+}
 
+func (q quantityFlag) String() string {
+	if q.value == nil {
+		return ""
 	}
+	return strconv.FormatFloat(*q.value, 'g', -1, 64)
+}
 
+func (q quantityFlag) Set(s string) (err error) {
+	*q.value, err = kinematics.ParseQuantity(s, q.dimension)
+	return err
 }
 
-func main() {
+// run is the whole program. It gets the command line arguments and the standard streams, and returns the exit code.
+//
+// Without arguments, the program asks for the values one by one, as required by the assignment.
+// It can also run without any question, for example:
+// go run ComputeDisplacement.go --batch -a 10 -v0 2 -s0 1 3 4.5 10
+//
+// Values can have a unit, they are converted to SI units (metres and seconds) before anything is computed.
+// Without a unit, a value is already in SI units. The displacements can be printed in another unit with -unit:
+// go run ComputeDisplacement.go -a 9.81m/s^2 -v0 36km/h -s0 100ft -unit ft 2min
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	// The fit subcommand goes the other way around, from measures to the parameters (see Fit.go).
+	if len(args) > 0 && args[0] == "fit" {
+		return runFit(args[1:], stdin, stdout, stderr)
+	}
+
+	c := config{set: map[string]bool{}}
+	flags := cli.NewFlagSet("ComputeDisplacement", stderr)
+	c.Options.Register(flags)
+	flags.Var(quantityFlag{&c.acceleration, kinematics.Acceleration}, "a", "acceleration, in m/s² unless a unit is given (like 32ft/s^2 or 1g)")
+	flags.Var(quantityFlag{&c.initialVelocity, kinematics.Velocity}, "v0", "initial velocity, in m/s unless a unit is given (like 36km/h or 20mph)")
+	flags.Var(quantityFlag{&c.initialDisplacement, kinematics.Length}, "s0", "initial displacement, in m unless a unit is given (like 100ft or 2km)")
+	flags.StringVar(&c.unit, "unit", "", "unit of length the displacements are printed in (m, km, ft, mi...)")
+	flags.Var(quantityFlag{&c.from, kinematics.Duration}, "from", "first time of the range, with -to")
+	flags.Var(quantityFlag{&c.to, kinematics.Duration}, "to", "compute the displacement for all the times from -from to -to, instead of asking for them")
+	flags.Var(quantityFlag{&c.step, kinematics.Duration}, "step", "time between two results of the range (default: a tenth of the range)")
+	flags.StringVar(&c.format, "format", "table", "output format of the range ("+strings.Join(rangeFormats, ", ")+")")
+	flags.StringVar(&c.scenarioFile, "scenarios", "", "JSON file of named scenarios to evaluate, the arguments are the names of the ones to evaluate (default: all)")
+	flags.BoolVar(&c.compare, "compare", false, "with -scenarios, show the scenarios side by side at the same times")
+	if ok, code := cli.Parse(flags, args); !ok {
+		return code
+	}
+	flags.Visit(func(f *flag.Flag) { c.set[f.Name] = true })
+	c.times = flags.Args()
 
-	// First, well ask the user for the values of acceleration, initial velocity, and initial displacement.
+	// Without -unit, we print plain metres, as the program always did.
+	c.output = kinematics.Unit{Symbol: "", Factor: 1, Dimension: kinematics.Length}
+	if c.unit != "" {
+		unit, err := kinematics.ParseUnit(c.unit)
+		if err != nil {
+			return c.Report(stderr, "ComputeDisplacement", &cli.UsageError{Err: err})
+		}
+		if unit.Dimension != kinematics.Length {
+			return c.Report(stderr, "ComputeDisplacement", cli.Usagef("-unit must be a unit of length, %q is a unit of %s", c.unit, unit.Dimension))
+		}
+		c.output = unit
+	}
+
+	// With a scenario file, everything comes from the file.
+	if c.scenarioFile != "" {
+		return c.Report(stderr, "ComputeDisplacement", evaluateScenarios(c, stdin, stdout))
+	}
+	if c.compare {
+		return c.Report(stderr, "ComputeDisplacement", cli.Usagef("-compare needs -scenarios"))
+	}
+
+	// With a range, we compute a whole trajectory at once.
+	if c.set["to"] {
+		if c.JSON {
+			c.format = "json"
+		}
+		return c.Report(stderr, "ComputeDisplacement", tabulate(c, stdout, stderr))
+	}
+	if c.set["from"] || c.set["step"] || c.set["format"] {
+		return c.Report(stderr, "ComputeDisplacement", cli.Usagef("-from, -step and -format need -to"))
+	}
+
+	// We only ask questions when there is nobody else to give us the times.
+	if !c.Batch && len(c.times) == 0 && c.Input == "" {
+		return c.Report(stderr, "ComputeDisplacement", interactive(c, stdin, stdout))
+	}
+	return c.Report(stderr, "ComputeDisplacement", batch(c, stdin, stdout))
+}
+
+// interactive is the original program: it asks for the values, then for times until the user types X.
+func interactive(c config, stdin io.Reader, stdout io.Writer) (err error) {
+
+	// First, well ask the user for the values of acceleration, initial velocity, and initial displacement (unless they were given as flags).
 	// Then, we'll ask the user for a value of time and compute the displacement after that time.
 	// We'll then ask the user for a new value of time and compute the displacement after that time.
 	// We'll continue asking the user for values of time until they press X to exit.
 
-	var input string
+	reader := bufio.NewReader(stdin)
 
-	acceleration := askForFloat("Please enter the value of acceleration: ")
-	initialVelocity := askForFloat("Please enter the value of initial velocity: ")
-	initialDisplacement := askForFloat("Please enter the value of initial displacement: ")
-	fmt.Println("")
-	fmt.Println("Acceleration: ", acceleration, " Initial Velocity: ", initialVelocity, " Initial Displacement:", initialDisplacement)
+	questions := []struct {
+		flag      string
+		prompt    string
+		value     *float64
+		dimension kinematics.Dimension
+	}{
+		{"a", "Please enter the value of acceleration: ", &c.acceleration, kinematics.Acceleration},
+		{"v0", "Please enter the value of initial velocity: ", &c.initialVelocity, kinematics.Velocity},
+		{"s0", "Please enter the value of initial displacement: ", &c.initialDisplacement, kinematics.Length},
+	}
+	for _, q := range questions {
+		if c.set[q.flag] {
+			continue
+		}
+		if *q.value, err = askForQuantity(reader, stdout, q.prompt, q.dimension); err != nil {
+			return err
+		}
+	}
+	fmt.Fprintln(stdout, "")
+	fmt.Fprintln(stdout, "Acceleration: ", c.acceleration, " Initial Velocity: ", c.initialVelocity, " Initial Displacement:", c.initialDisplacement)
 
 	// Now that we have the values of acceleration, initial velocity, and initial displacement, we can call GenDisplaceFn to generate a function fn which will compute displacement as a function of time.
-	fn := GenDisplaceFn(acceleration, initialVelocity, initialDisplacement)
+	fn := GenDisplaceFn(c.acceleration, c.initialVelocity, c.initialDisplacement)
 
 	// Now we will ask the user for a value of time and compute the displacement after that time, until they press X to exit.
 
 	for {
-
-		fmt.Println("Please enter the value of time (or press X to eXit): ")
-		fmt.Scan(&input)
+		fmt.Fprintln(stdout, "Please enter the value of time (or press X to eXit): ")
+		var input string
+		if _, err := fmt.Fscan(reader, &input); err != nil {
+			// The end of the input is like pressing X.
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
 		if input == "X" || input == "x" {
-			break
+			return nil
 		}
-		time, err := strconv.ParseFloat(input, 64)
+		time, err := kinematics.ParseQuantity(input, kinematics.Duration)
 		if err != nil {
-			fmt.Println("Invalid input. Please enter a number or 'X' to exit.")
+			// A time in metres is not a typo, it is a misunderstanding: we'd rather stop than guess.
+			if isDimensionError(err) {
+				return err
+			}
+			fmt.Fprintln(stdout, "Invalid input. Please enter a number or 'X' to exit.")
 			continue
 		}
-		fmt.Println("Displacement after time ", time, " is ", fn(time))
-		fmt.Println("")
+		printResult(stdout, c.output, result{Time: time, Displacement: c.output.FromSI(fn(time))})
+		fmt.Fprintln(stdout, "")
 	}
+}
 
+// result is the displacement at one time, as printed in JSON. The time is in seconds, the displacement in the unit chosen with -unit.
+type result struct {
+	Time         float64 `json:"time"`
+	Displacement float64 `json:"displacement"`
 }
 
-func askForFloat(prompt string) float64 {
-	var result float64
+// printResult prints one result for humans, with its unit if one was chosen.
+func printResult(w io.Writer, unit kinematics.Unit, r result) {
+	if unit.Symbol == "" {
+		fmt.Fprintln(w, "Displacement after time ", r.Time, " is ", r.Displacement)
+		return
+	}
+	fmt.Fprintln(w, "Displacement after time ", r.Time, "s is ", r.Displacement, unit.Symbol)
+}
+
+// batch computes the displacement for the times given as arguments, or read from the -in file (or standard input), without asking anything.
+func batch(c config, stdin io.Reader, stdout io.Writer) error {
+	for _, name := range []string{"a", "v0", "s0"} {
+		if !c.set[name] {
+			return cli.Usagef("-%s is required when nothing is asked (with --batch, time arguments or -in)", name)
+		}
+	}
+
+	times, err := readTimes(c, stdin)
+	if err != nil {
+		return err
+	}
+
+	fn := GenDisplaceFn(c.acceleration, c.initialVelocity, c.initialDisplacement)
+	results := make([]result, len(times))
+	for i, t := range times {
+		results[i] = result{Time: t, Displacement: c.output.FromSI(fn(t))}
+	}
+
+	switch {
+	case c.JSON:
+		return cli.WriteJSON(stdout, struct {
+			Acceleration        float64  `json:"acceleration"`
+			InitialVelocity     float64  `json:"initialVelocity"`
+			InitialDisplacement float64  `json:"initialDisplacement"`
+			Unit                string   `json:"unit,omitempty"`
+			Results             []result `json:"results"`
+		}{c.acceleration, c.initialVelocity, c.initialDisplacement, c.output.Symbol, results})
+	case c.Quiet:
+		for _, r := range results {
+			fmt.Fprintln(stdout, r.Displacement)
+		}
+	default:
+		for _, r := range results {
+			printResult(stdout, c.output, r)
+		}
+	}
+	return nil
+}
+
+// readTimes returns the times given as arguments or, if there are none, the ones of the -in file (or standard input).
+// Times are separated by spaces or new lines. They are in seconds, unless they have a unit (like 2min).
+func readTimes(c config, stdin io.Reader) (times []float64, err error) {
+	if len(c.times) > 0 {
+		for i, s := range c.times {
+			t, err := kinematics.ParseQuantity(s, kinematics.Duration)
+			if err != nil {
+				return nil, cli.Usagef("argument %d: %v", i+1, err)
+			}
+			times = append(times, t)
+		}
+		return times, nil
+	}
+
+	input, name, close, err := cli.OpenInput(c.Input, stdin)
+	if err != nil {
+		return nil, err
+	}
+	defer close()
+
+	scanner := bufio.NewScanner(input)
+	line := 0
+	for scanner.Scan() {
+		line++
+		for _, s := range strings.Fields(scanner.Text()) {
+			t, err := kinematics.ParseQuantity(s, kinematics.Duration)
+			if err != nil {
+				return nil, fmt.Errorf("%s:%d: %w", name, line, err)
+			}
+			times = append(times, t)
+		}
+	}
+	return times, scanner.Err()
+}
+
+// askForQuantity prompts the user until they enter a number, with an optional unit of the given dimension, and returns it in SI units.
+// A typo is asked again, but a unit of another dimension (like km/h for an acceleration) is an error: asking again would not explain the problem.
+// It also fails if the input ends or can't be read.
+func askForQuantity(reader *bufio.Reader, stdout io.Writer, prompt string, dimension kinematics.Dimension) (float64, error) {
 	for {
-		fmt.Println(prompt)
-		_, err := fmt.Scan(&result)
+		fmt.Fprintln(stdout, prompt)
+		var input string
+		if _, err := fmt.Fscan(reader, &input); err != nil {
+			return 0, err
+		}
+		result, err := kinematics.ParseQuantity(input, dimension)
+		if isDimensionError(err) {
+			return 0, err
+		}
 		if err != nil {
-			fmt.Println("Invalid input. Please enter a number.")
+			fmt.Fprintln(stdout, "Invalid input. Please enter a number.")
 			continue
 		}
-		break
+		return result, nil
 	}
-	return result
+}
+
+// isDimensionError tells if err is about a unit of the wrong dimension.
+func isDimensionError(err error) bool {
+	var dimensionError *kinematics.DimensionError
+	return errors.As(err, &dimensionError)
 }
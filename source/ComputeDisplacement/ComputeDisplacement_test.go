@@ -1,8 +1,15 @@
 package main
 
 import (
+	"bytes"
+	"fmt"
 	"math"
+	"strings"
 	"testing"
+
+	"coursera-go/m/source/cli"
+	"coursera-go/m/source/cli/golden"
+	"coursera-go/m/source/kinematics"
 )
 
 func TestGenDisplaceFn(t *testing.T) {
@@ -44,3 +51,191 @@ func TestGenDisplaceFn(t *testing.T) {
 		})
 	}
 }
+
+// measures are positions of a ball thrown up, measured every half second, in CSV, as the fit subcommand reads them.
+const measures = `time,position
+0,2.3
+0.5, 10.9
+1,17.4
+1.5,21.5
+2,22.1
+# Units are allowed too.
+2.5s,2100cm
+3,17.75
+3.5,10.8
+4,1.5
+`
+
+func TestRun(t *testing.T) {
+	testCases := []struct {
+		name     string
+		args     []string
+		stdin    string
+		wantCode int
+	}{
+		{name: "interactive", args: nil, stdin: "10\n2\nabc\n1\n3\nfoo\n90\nX\n", wantCode: cli.ExitOK},
+		{name: "interactive with flags", args: []string{"-a", "10"}, stdin: "2\n1\n3\n", wantCode: cli.ExitOK},
+		{name: "batch arguments", args: []string{"--batch", "-a", "10", "-v0", "2", "-s0", "1", "3", "90"}, wantCode: cli.ExitOK},
+		{name: "batch stdin", args: []string{"--batch", "-a", "10", "-v0", "2", "-s0", "1"}, stdin: "0 1\n2\n3\n", wantCode: cli.ExitOK},
+		{name: "quiet", args: []string{"--quiet", "-a", "10", "-v0", "2", "-s0", "1", "3", "90"}, wantCode: cli.ExitOK},
+		{name: "json", args: []string{"--json", "-a", "10", "-v0", "2", "-s0", "1", "3"}, wantCode: cli.ExitOK},
+		{name: "missing parameter", args: []string{"--batch", "-a", "10", "3"}, wantCode: cli.ExitUsage},
+		{name: "invalid time", args: []string{"--batch", "-a", "10", "-v0", "2", "-s0", "1"}, stdin: "1\n2 x\n", wantCode: cli.ExitFailure},
+		{name: "interactive input ends", args: nil, stdin: "10\n", wantCode: cli.ExitFailure},
+		{name: "units", args: []string{"-a", "9.81m/s^2", "-v0", "36km/h", "-s0", "100ft", "-unit", "ft", "2min", "1.5"}, wantCode: cli.ExitOK},
+		{name: "units json", args: []string{"--json", "-a", "1g", "-v0", "0", "-s0", "0", "-unit", "km", "1min"}, wantCode: cli.ExitOK},
+		{name: "interactive units", args: nil, stdin: "9.81m/s^2\n36km/h\n100ft\n2min\nX\n", wantCode: cli.ExitOK},
+		{name: "incompatible flag unit", args: []string{"-a", "36km/h", "-v0", "0", "-s0", "0", "3"}, wantCode: cli.ExitUsage},
+		{name: "incompatible time unit", args: []string{"-a", "10", "-v0", "0", "-s0", "0", "3m"}, wantCode: cli.ExitUsage},
+		{name: "output unit is not a length", args: []string{"-a", "10", "-v0", "0", "-s0", "0", "-unit", "km/h", "3"}, wantCode: cli.ExitUsage},
+		{name: "interactive incompatible unit", args: nil, stdin: "10\n2min\n", wantCode: cli.ExitFailure},
+		{name: "range table", args: []string{"-a", "-9.81", "-v0", "20", "-s0", "0", "-to", "4", "-step", "0.5"}, wantCode: cli.ExitOK},
+		{name: "range csv", args: []string{"-a", "-9.81", "-v0", "20", "-s0", "0", "-from", "1", "-to", "2", "-step", "0.3", "-format", "csv"}, wantCode: cli.ExitOK},
+		{name: "range json", args: []string{"--json", "-a", "2", "-v0", "-4", "-s0", "1", "-to", "3", "-step", "1", "-unit", "cm"}, wantCode: cli.ExitOK},
+		{name: "range chart", args: []string{"-a", "-9.81", "-v0", "20", "-s0", "0", "-to", "4", "-step", "0.1", "-format", "chart"}, wantCode: cli.ExitOK},
+		{name: "range chart overflow", args: []string{"--batch", "-a", "1e308", "-v0", "0", "-s0", "0", "-to", "1e10", "-step", "1e9", "-format", "chart"}, wantCode: cli.ExitFailure},
+		{name: "range chart huge span", args: []string{"--batch", "-a", "0", "-v0", "1.7e308", "-s0", "0", "-from", "-1", "-to", "1", "-format", "chart"}, wantCode: cli.ExitOK},
+		{name: "range chart flat", args: []string{"-a", "0", "-v0", "0", "-s0", "3", "-to", "4", "-step", "1", "-format", "chart"}, wantCode: cli.ExitOK},
+		{name: "range quiet", args: []string{"--quiet", "-a", "1", "-v0", "0", "-s0", "0", "-to", "1min", "-step", "20s"}, wantCode: cli.ExitOK},
+		{name: "range backwards", args: []string{"-a", "1", "-v0", "0", "-s0", "0", "-from", "2", "-to", "1"}, wantCode: cli.ExitUsage},
+		{name: "range unknown format", args: []string{"-a", "1", "-v0", "0", "-s0", "0", "-to", "1", "-format", "svg"}, wantCode: cli.ExitUsage},
+		{name: "range without to", args: []string{"-a", "1", "-v0", "0", "-s0", "0", "-step", "1", "3"}, wantCode: cli.ExitUsage},
+		{name: "fit", args: []string{"fit"}, stdin: measures, wantCode: cli.ExitOK},
+		{name: "fit quiet", args: []string{"fit", "--quiet", "-confidence", "0.99"}, stdin: measures, wantCode: cli.ExitOK},
+		{name: "fit json", args: []string{"fit", "--json"}, stdin: measures, wantCode: cli.ExitOK},
+		{name: "fit not a number", args: []string{"fit"}, stdin: "time,position\n0,1\n1,x\n", wantCode: cli.ExitFailure},
+		{name: "fit not enough samples", args: []string{"fit"}, stdin: "0,1\n1,2\n2,4\n", wantCode: cli.ExitFailure},
+		{name: "fit bad confidence", args: []string{"fit", "-confidence", "95"}, stdin: measures, wantCode: cli.ExitUsage},
+		{name: "scenarios", args: []string{"-scenarios", "testdata/scenarios.json"}, wantCode: cli.ExitOK},
+		{name: "scenarios quiet", args: []string{"-scenarios", "testdata/scenarios.json", "--quiet", "thrown up"}, wantCode: cli.ExitOK},
+		{name: "scenarios json", args: []string{"-scenarios", "testdata/scenarios.json", "--json", "-unit", "ft"}, wantCode: cli.ExitOK},
+		{name: "scenarios compare", args: []string{"-scenarios", "testdata/scenarios.json", "-compare", "thrown up", "dropped ball"}, wantCode: cli.ExitOK},
+		{name: "scenarios compare json", args: []string{"-scenarios", "testdata/scenarios.json", "-compare", "--json"}, wantCode: cli.ExitOK},
+		{name: "scenarios unknown name", args: []string{"-scenarios", "testdata/scenarios.json", "thrown down"}, wantCode: cli.ExitFailure},
+		{name: "scenarios with values", args: []string{"-scenarios", "testdata/scenarios.json", "-a", "1"}, wantCode: cli.ExitUsage},
+		{name: "compare without scenarios", args: []string{"-compare", "-a", "1", "-v0", "0", "-s0", "0", "1"}, wantCode: cli.ExitUsage},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			code := run(tc.args, strings.NewReader(tc.stdin), &stdout, &stderr)
+			if code != tc.wantCode {
+				t.Errorf("run(%v) exited with %d; want %d (stderr: %s)", tc.args, code, tc.wantCode, stderr.String())
+			}
+			golden.Assert(t, "run-"+strings.ReplaceAll(tc.name, " ", "-"), stdout.String())
+			if code != cli.ExitOK && stderr.Len() == 0 {
+				t.Errorf("run(%v) failed without an error message", tc.args)
+			}
+		})
+	}
+}
+
+func TestRangeTimes(t *testing.T) {
+	tests := []struct {
+		name           string
+		from, to, step float64
+		want           []float64
+		wantErr        bool
+	}{
+		{name: "whole steps", from: 0, to: 2, step: 0.5, want: []float64{0, 0.5, 1, 1.5, 2}},
+		{name: "rounding errors", from: 0, to: 1, step: 0.1, want: []float64{0, 0.1, 0.2, 0.3, 0.4, 0.5, 0.6, 0.7, 0.8, 0.9, 1}},
+		{name: "last step is shorter", from: 1, to: 2, step: 0.4, want: []float64{1, 1.4, 1.8, 2}},
+		{name: "negative times", from: -1, to: 1, step: 1, want: []float64{-1, 0, 1}},
+		{name: "small step far from 0", from: 1e6, to: 1000000.0000003, step: 1e-7, want: []float64{1e6, 1000000.0000001, 1000000.0000002, 1000000.0000003}},
+		{name: "more decimals in from", from: 0.125, to: 1, step: 0.5, want: []float64{0.125, 0.625, 1}},
+		{name: "step bigger than the range", from: 0, to: 1, step: 5, want: []float64{0, 1}},
+		{name: "empty range", from: 1, to: 1, step: 1, wantErr: true},
+		{name: "zero step", from: 0, to: 1, step: 0, wantErr: true},
+		{name: "too many times", from: 0, to: 1, step: 1e-9, wantErr: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := rangeTimes(tc.from, tc.to, tc.step)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("rangeTimes(%v, %v, %v) returned the error %v; want an error: %t", tc.from, tc.to, tc.step, err, tc.wantErr)
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tc.want) {
+				t.Errorf("rangeTimes(%v, %v, %v) = %v; want %v", tc.from, tc.to, tc.step, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestReadSamples(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    []kinematics.Sample
+		wantErr string
+	}{
+		{name: "plain", input: "0,1\n1,2.5\n", want: []kinematics.Sample{{T: 0, S: 1}, {T: 1, S: 2.5}}},
+		{name: "header, comments and spaces", input: "t, s\n# start\n0, 1\n 1 , 2\n", want: []kinematics.Sample{{T: 0, S: 1}, {T: 1, S: 2}}},
+		{name: "units", input: "1min,1km\n1h,1ft\n", want: []kinematics.Sample{{T: 60, S: 1000}, {T: 3600, S: 0.3048}}},
+		{name: "empty", input: "", want: nil},
+		{name: "header only once", input: "t,s\nt,s\n", wantErr: `measures:2: "t" is not a number`},
+		{name: "wrong dimension", input: "1,2s\n", wantErr: `measures:1: "2s" is a duration, not a length`},
+		{name: "one value", input: "0,1\n2\n", wantErr: "measures:2: want 2 values (time, position), got 1"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := ReadSamples(strings.NewReader(tc.input), "measures")
+			if tc.wantErr != "" {
+				if err == nil || err.Error() != tc.wantErr {
+					t.Fatalf("ReadSamples(%q) returned the error %v; want %q", tc.input, err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tc.want) {
+				t.Errorf("ReadSamples(%q) = %v; want %v", tc.input, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestReadScenarios(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    string
+		wantErr string
+	}{
+		{
+			name:  "times of the file and of the scenario",
+			input: `{"times": [1, "1min"], "scenarios": [{"name": "a", "a": 1, "v0": "2m/s", "s0": "3km"}, {"name": "b", "a": "1g", "v0": 0, "s0": 0, "times": ["2s"]}]}`,
+			want:  "[{a 1 2 3000 [1 60]} {b 9.80665 0 0 [2]}]",
+		},
+		{name: "not JSON", input: `scenarios:`, wantErr: "file: invalid character 's' looking for beginning of value"},
+		{name: "no scenario", input: `{"scenarios": []}`, wantErr: "file: there is no scenario in the file"},
+		{name: "misspelled field", input: `{"scenarios": [{"name": "a", "acceleration": 1}]}`, wantErr: `file: json: unknown field "acceleration"`},
+		{name: "no name", input: `{"times": [1], "scenarios": [{"a": 1, "v0": 0, "s0": 0}]}`, wantErr: "file: scenario 1 has no name"},
+		{name: "same name", input: `{"times": [1], "scenarios": [{"name": "a", "a": 1, "v0": 0, "s0": 0}, {"name": "a", "a": 1, "v0": 0, "s0": 0}]}`, wantErr: `file: scenario 2 ("a"): the name is used by another scenario`},
+		{name: "missing value", input: `{"times": [1], "scenarios": [{"name": "a", "a": 1, "s0": 0}]}`, wantErr: `file: scenario 1 ("a"): v0 is missing`},
+		{name: "wrong unit", input: `{"times": [1], "scenarios": [{"name": "a", "a": 1, "v0": "1m", "s0": 0}]}`, wantErr: `file: scenario 1 ("a"): v0: "1m" is a length, not a velocity`},
+		{name: "no times", input: `{"scenarios": [{"name": "a", "a": 1, "v0": 0, "s0": 0}]}`, wantErr: `file: scenario 1 ("a"): there are no times, in the scenario or for the whole file`},
+		{name: "bad time", input: `{"times": ["soon"], "scenarios": [{"name": "a", "a": 1, "v0": 0, "s0": 0}]}`, wantErr: `file: times: "soon" is not a number`},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := ReadScenarios(strings.NewReader(tc.input), "file")
+			if tc.wantErr != "" {
+				if err == nil || err.Error() != tc.wantErr {
+					t.Fatalf("ReadScenarios returned the error %v; want %q", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if fmt.Sprint(got) != tc.want {
+				t.Errorf("ReadScenarios = %v; want %v", got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+
+	"coursera-go/m/source/cli"
+	"coursera-go/m/source/kinematics"
+)
+
+// The fit subcommand does the opposite of the program: from measured positions, it finds the acceleration, initial velocity
+// and initial displacement to give to GenDisplaceFn. The measures are "time, position" lines of a CSV file, for example:
+// go run ComputeDisplacement.go fit measures.csv
+
+// runFit is the fit subcommand, with its own flags. It returns the exit code.
+func runFit(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	var options cli.Options
+	var confidence float64
+	flags := cli.NewFlagSet("ComputeDisplacement fit", stderr)
+	options.Register(flags)
+	flags.Float64Var(&confidence, "confidence", 0.95, "confidence of the intervals, between 0 and 1")
+	if ok, code := cli.Parse(flags, args); !ok {
+		return code
+	}
+
+	// The file can be given as an argument, or with -in like in the other programs.
+	input := options.Input
+	switch flags.NArg() {
+	case 0:
+	case 1:
+		if input != "" {
+			return options.Report(stderr, "ComputeDisplacement fit", cli.Usagef("give the CSV file as an argument or with -in, not both"))
+		}
+		input = flags.Arg(0)
+	default:
+		return options.Report(stderr, "ComputeDisplacement fit", cli.Usagef("only one CSV file can be fitted at a time, got %d", flags.NArg()))
+	}
+	if !(confidence > 0 && confidence < 1) {
+		return options.Report(stderr, "ComputeDisplacement fit", cli.Usagef("-confidence must be between 0 and 1, got %v", confidence))
+	}
+
+	return options.Report(stderr, "ComputeDisplacement fit", fit(options, input, confidence, stdin, stdout))
+}
+
+// fit reads the samples, fits them and prints the result.
+func fit(options cli.Options, input string, confidence float64, stdin io.Reader, stdout io.Writer) error {
+	reader, name, close, err := cli.OpenInput(input, stdin)
+	if err != nil {
+		return err
+	}
+	defer close()
+
+	samples, err := ReadSamples(reader, name)
+	if err != nil {
+		return err
+	}
+	result, err := kinematics.FitSamples(samples, confidence)
+	if err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+
+	switch {
+	case options.JSON:
+		return writeFitJSON(stdout, samples, result)
+	case options.Quiet:
+		// Just the three values, in the order of the flags -a, -v0 and -s0.
+		fmt.Fprintln(stdout, result.Acceleration.Value, result.InitialVelocity.Value, result.InitialDisplacement.Value)
+	default:
+		printFit(stdout, samples, result)
+	}
+	return nil
+}
+
+// ReadSamples reads "time, position" lines of CSV. Values can have a unit, like "2min, 3km", and are converted to seconds and metres.
+// A first line that is not made of numbers is a header, and is skipped. Lines starting with # are comments.
+func ReadSamples(r io.Reader, source string) ([]kinematics.Sample, error) {
+	reader := csv.NewReader(r)
+	reader.Comment = '#'
+	reader.TrimLeadingSpace = true
+	reader.FieldsPerRecord = -1 // We check the number of fields ourselves, to give a clearer message.
+
+	var samples []kinematics.Sample
+	for first := true; ; first = false {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return samples, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", source, err)
+		}
+		line, _ := reader.FieldPos(0)
+		if len(record) != 2 {
+			return nil, fmt.Errorf("%s:%d: want 2 values (time, position), got %d", source, line, len(record))
+		}
+
+		t, errT := kinematics.ParseQuantity(record[0], kinematics.Duration)
+		s, errS := kinematics.ParseQuantity(record[1], kinematics.Length)
+		if first && errT != nil && errS != nil {
+			// Both values are not numbers: this is a header, like "time,position".
+			continue
+		}
+		for _, err := range []error{errT, errS} {
+			if err != nil {
+				return nil, fmt.Errorf("%s:%d: %w", source, line, err)
+			}
+		}
+		samples = append(samples, kinematics.Sample{T: t, S: s})
+	}
+}
+
+// printFit prints the fitted values, how good the fit is, and each sample next to the fitted position.
+func printFit(w io.Writer, samples []kinematics.Sample, result *kinematics.Fit) {
+	fmt.Fprintf(w, "Fit of %d samples, with %g%% confidence intervals:\n", len(samples), result.Confidence*100)
+	estimates := []struct {
+		name     string
+		estimate kinematics.Estimate
+		unit     string
+	}{
+		{"a", result.Acceleration, "m/s²"},
+		{"v0", result.InitialVelocity, "m/s"},
+		{"s0", result.InitialDisplacement, "m"},
+	}
+	for _, e := range estimates {
+		fmt.Fprintf(w, "  %-2s = %-12.6g %-5s ± %-10.3g (from %.6g to %.6g)\n",
+			e.name, e.estimate.Value, e.unit, e.estimate.StandardError, e.estimate.Low, e.estimate.High)
+	}
+	fmt.Fprintf(w, "  R² = %.6g\n", result.RSquared)
+	fmt.Fprintln(w)
+
+	fmt.Fprintf(w, "%12s  %14s  %14s  %14s\n", "time (s)", "measured (m)", "fitted (m)", "residual (m)")
+	for i, sample := range samples {
+		fmt.Fprintf(w, "%12.6g  %14.6g  %14.6g  %14.3g\n", sample.T, sample.S, sample.S-result.Residuals[i], result.Residuals[i])
+	}
+}
+
+// estimateJSON is an Estimate, as printed in JSON.
+type estimateJSON struct {
+	Value         float64 `json:"value"`
+	StandardError float64 `json:"standardError"`
+	Low           float64 `json:"low"`
+	High          float64 `json:"high"`
+}
+
+// writeFitJSON prints the fit as JSON.
+func writeFitJSON(w io.Writer, samples []kinematics.Sample, result *kinematics.Fit) error {
+	type residual struct {
+		Time     float64 `json:"time"`
+		Measured float64 `json:"measured"`
+		Fitted   float64 `json:"fitted"`
+		Residual float64 `json:"residual"`
+	}
+	residuals := make([]residual, len(samples))
+	for i, sample := range samples {
+		residuals[i] = residual{sample.T, sample.S, sample.S - result.Residuals[i], result.Residuals[i]}
+	}
+
+	return cli.WriteJSON(w, struct {
+		Samples             int          `json:"samples"`
+		Confidence          float64      `json:"confidence"`
+		Acceleration        estimateJSON `json:"acceleration"`
+		InitialVelocity     estimateJSON `json:"initialVelocity"`
+		InitialDisplacement estimateJSON `json:"initialDisplacement"`
+		RSquared            float64      `json:"rSquared"`
+		Residuals           []residual   `json:"residuals"`
+	}{
+		len(samples), result.Confidence,
+		estimateJSON(result.Acceleration), estimateJSON(result.InitialVelocity), estimateJSON(result.InitialDisplacement),
+		result.RSquared, residuals,
+	})
+}
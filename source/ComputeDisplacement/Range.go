@@ -0,0 +1,276 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	"coursera-go/m/source/cli"
+	"coursera-go/m/source/kinematics"
+)
+
+// Instead of asking for one time after another, the range mode computes the displacement for all the times from -from to -to, every -step.
+// It shows a whole trajectory at once, for example:
+// go run ComputeDisplacement.go -a -9.81 -v0 20 -s0 0 -to 4 -step 0.25 -format chart
+
+// rangeFormats are the formats the range mode can print the trajectory in.
+var rangeFormats = []string{"table", "csv", "json", "chart"}
+
+const (
+	// maxRangePoints stops a tiny -step from filling the memory.
+	maxRangePoints = 1_000_000
+	// The size of the chart, in characters. It fits in a standard 80 columns terminal.
+	chartHeight = 20
+	chartWidth  = 64
+)
+
+// rangeTimes returns the times from from to to, every step. The last one is always to, even when the range is not a multiple of step.
+func rangeTimes(from, to, step float64) ([]float64, error) {
+	if !(to > from) {
+		return nil, cli.Usagef("-to must be after -from, got -from %v and -to %v", from, to)
+	}
+	if !(step > 0) {
+		return nil, cli.Usagef("-step must be positive, got %v", step)
+	}
+
+	// The small margin avoids losing the last point to rounding errors, like (1 - 0) / 0.1 = 9.999999999999998.
+	steps := math.Floor((to-from)/step + 1e-9)
+	if steps+2 > maxRangePoints {
+		return nil, cli.Usagef("-step %v is too small: it gives more than %d times", step, maxRangePoints)
+	}
+
+	// from + i * step can't have more decimals than from and step have, the ones after that are rounding errors.
+	places := decimals(from)
+	if d := decimals(step); d > places {
+		places = d
+	}
+	times := make([]float64, 0, int(steps)+2)
+	for i := 0; i <= int(steps); i++ {
+		times = append(times, tidy(from+float64(i)*step, places))
+	}
+	if last := times[len(times)-1]; to-last > 1e-9*step {
+		times = append(times, to)
+	}
+	return times, nil
+}
+
+// tidy removes the rounding errors of the computed times, so that 3 * 0.4 is 1.2 and not 1.2000000000000002, by rounding them to places decimals.
+// Rounding to a number of significant digits instead would merge the times of a small step far from 0, like 1000000 and 1000000.0000001.
+func tidy(t float64, places int) float64 {
+	t, _ = strconv.ParseFloat(strconv.FormatFloat(t, 'f', places, 64), 64)
+	return t
+}
+
+// decimals returns the number of decimals of the shortest way to write v: 2 for 0.25, 7 for 1e-7 and 0 for 1e9.
+func decimals(v float64) int {
+	s := strconv.FormatFloat(v, 'f', -1, 64)
+	if dot := strings.IndexByte(s, '.'); dot >= 0 {
+		return len(s) - dot - 1
+	}
+	return 0
+}
+
+// tabulate computes and prints the displacement over the range of times given with -from, -to and -step, then its peak and minimum.
+func tabulate(c config, stdout, stderr io.Writer) error {
+	for _, name := range []string{"a", "v0", "s0"} {
+		if !c.set[name] {
+			return cli.Usagef("-%s is required with -to", name)
+		}
+	}
+	if len(c.times) > 0 {
+		return cli.Usagef("times can't be given as arguments with -to, they come from -from, -to and -step")
+	}
+	if !contains(rangeFormats, c.format) {
+		return cli.Usagef("unknown format %q, use one of: %s", c.format, strings.Join(rangeFormats, ", "))
+	}
+
+	// Without -step, we show 10 steps.
+	step := c.step
+	if !c.set["step"] {
+		step = (c.to - c.from) / 10
+	}
+	times, err := rangeTimes(c.from, c.to, step)
+	if err != nil {
+		return err
+	}
+
+	fn := GenDisplaceFn(c.acceleration, c.initialVelocity, c.initialDisplacement)
+	results := make([]result, len(times))
+	for i, t := range times {
+		results[i] = result{Time: t, Displacement: c.output.FromSI(fn(t))}
+	}
+
+	// The peak and the minimum are computed exactly, they can be between two steps.
+	lowest, highest := kinematics.New(c.acceleration, c.initialVelocity, c.initialDisplacement).Extremes(c.from, c.to)
+	minimum := result{Time: lowest.Time, Displacement: c.output.FromSI(lowest.Displacement)}
+	peak := result{Time: highest.Time, Displacement: c.output.FromSI(highest.Displacement)}
+
+	unit := c.output.Symbol
+	if unit == "" {
+		unit = "m"
+	}
+
+	switch c.format {
+	case "json":
+		return cli.WriteJSON(stdout, struct {
+			Acceleration        float64  `json:"acceleration"`
+			InitialVelocity     float64  `json:"initialVelocity"`
+			InitialDisplacement float64  `json:"initialDisplacement"`
+			Unit                string   `json:"unit,omitempty"`
+			Results             []result `json:"results"`
+			Peak                result   `json:"peak"`
+			Minimum             result   `json:"minimum"`
+		}{c.acceleration, c.initialVelocity, c.initialDisplacement, c.output.Symbol, results, peak, minimum})
+
+	case "csv":
+		if err := WriteCSV(stdout, results); err != nil {
+			return err
+		}
+		// The CSV is meant for another tool, so the summary goes to standard error, where it doesn't get in the way.
+		if !c.Quiet {
+			printExtremes(stderr, peak, minimum, unit)
+		}
+		return nil
+
+	case "chart":
+		if err := WriteChart(stdout, results, unit, chartHeight, chartWidth); err != nil {
+			return err
+		}
+
+	default:
+		WriteTable(stdout, results, unit, c.Quiet)
+	}
+
+	if !c.Quiet {
+		fmt.Fprintln(stdout)
+		printExtremes(stdout, peak, minimum, unit)
+	}
+	return nil
+}
+
+// printExtremes prints when the displacement is the highest and the lowest.
+func printExtremes(w io.Writer, peak, minimum result, unit string) {
+	fmt.Fprintf(w, "Peak displacement: %v %s at %v s\n", peak.Displacement, unit, peak.Time)
+	fmt.Fprintf(w, "Minimum displacement: %v %s at %v s\n", minimum.Displacement, unit, minimum.Time)
+}
+
+// WriteTable prints the results in two aligned columns, with a header unless quiet is set.
+// The numbers are rounded to 6 significant digits, which is plenty to read, CSV and JSON have them all.
+func WriteTable(w io.Writer, results []result, unit string, quiet bool) {
+	if !quiet {
+		fmt.Fprintf(w, "%12s  %s\n", "time (s)", "displacement ("+unit+")")
+	}
+	for _, r := range results {
+		fmt.Fprintf(w, "%12.6g  %.6g\n", r.Time, r.Displacement)
+	}
+}
+
+// WriteCSV prints the results as CSV, with a header line.
+func WriteCSV(w io.Writer, results []result) error {
+	writer := csv.NewWriter(w)
+	writer.Write([]string{"time", "displacement"})
+	for _, r := range results {
+		writer.Write([]string{strconv.FormatFloat(r.Time, 'g', -1, 64), strconv.FormatFloat(r.Displacement, 'g', -1, 64)})
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// WriteChart draws the displacement over time with characters, one column per time, with the time going right and the displacement going up.
+// When there are more times than width, we only draw some of them, evenly spread.
+// It fails without drawing anything when a displacement is too big for a float64 (infinite), as it has no place on the chart.
+func WriteChart(w io.Writer, results []result, unit string, height, width int) error {
+	for _, r := range results {
+		if math.IsInf(r.Displacement, 0) || math.IsNaN(r.Displacement) {
+			return fmt.Errorf("can't draw the chart: the displacement at %v s is %v, too big to be computed", r.Time, r.Displacement)
+		}
+	}
+	if len(results) == 0 {
+		return nil
+	}
+
+	// Pick the times to draw.
+	points := results
+	if len(points) > width {
+		points = make([]result, width)
+		for i := range points {
+			points[i] = results[i*(len(results)-1)/(width-1)]
+		}
+	}
+
+	// The lowest displacement is at the bottom row, the highest at the top row.
+	lowest, highest := points[0].Displacement, points[0].Displacement
+	for _, p := range points {
+		lowest = math.Min(lowest, p.Displacement)
+		highest = math.Max(highest, p.Displacement)
+	}
+	// The labels of the displacement axis: the highest on the top row, the lowest on the bottom one.
+	top, bottom := formatLabel(highest), formatLabel(lowest)
+	labelWidth := len(top)
+	if len(bottom) > labelWidth {
+		labelWidth = len(bottom)
+	}
+
+	// We work on halves: highest - lowest overflows to +Inf when both are huge and of opposite signs.
+	halfSpan := highest/2 - lowest/2
+
+	grid := make([][]byte, height)
+	for row := range grid {
+		grid[row] = []byte(strings.Repeat(" ", len(points)))
+	}
+	for column, p := range points {
+		// A flat line is drawn in the middle.
+		position := 0.5
+		if halfSpan > 0 {
+			position = (p.Displacement/2 - lowest/2) / halfSpan
+		}
+		row := int(math.Round(position * float64(height-1)))
+		// Whatever the rounding errors, the point stays on the chart.
+		if row < 0 {
+			row = 0
+		} else if row > height-1 {
+			row = height - 1
+		}
+		grid[height-1-row][column] = '*'
+	}
+
+	fmt.Fprintf(w, "Displacement (%s) over time (s)\n", unit)
+	for row, line := range grid {
+		label := ""
+		switch row {
+		case 0:
+			label = top
+		case height - 1:
+			label = bottom
+		}
+		fmt.Fprintf(w, "%*s |%s\n", labelWidth, label, strings.TrimRight(string(line), " "))
+	}
+	fmt.Fprintf(w, "%*s +%s\n", labelWidth, "", strings.Repeat("-", len(points)))
+
+	// The time axis: the first time on the left, the last one on the right.
+	first, last := formatLabel(points[0].Time), formatLabel(points[len(points)-1].Time)
+	gap := len(points) - len(first) - len(last)
+	if gap < 1 {
+		gap = 1
+	}
+	fmt.Fprintf(w, "%*s  %s%s%s\n", labelWidth, "", first, strings.Repeat(" ", gap), last)
+	return nil
+}
+
+// formatLabel writes a number short enough to be a label of the chart.
+func formatLabel(value float64) string {
+	return strconv.FormatFloat(value, 'g', 4, 64)
+}
+
+// contains tells if the list has the value.
+func contains(list []string, value string) bool {
+	for _, v := range list {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,305 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+
+	"coursera-go/m/source/cli"
+	"coursera-go/m/source/kinematics"
+)
+
+// Instead of typing the values for every run, they can be saved as named scenarios in a JSON file, and all evaluated at once:
+// go run ComputeDisplacement.go -scenarios scenarios.json
+// or only some of them, side by side at the same times:
+// go run ComputeDisplacement.go -scenarios scenarios.json -compare "dropped ball" "thrown up"
+//
+// The file looks like this. Values are numbers in SI units, or strings with a unit. The times of the file are used by the scenarios that have none.
+//
+//	{
+//	  "times": [0, 1, 2],
+//	  "scenarios": [
+//	    {"name": "dropped ball", "a": "-1g", "v0": 0, "s0": "100ft"},
+//	    {"name": "thrown up", "a": -9.81, "v0": "36km/h", "s0": 0, "times": ["500ms", 1, 1.5, 2]}
+//	  ]
+//	}
+//
+// (YAML would be nicer to write by hand, but it needs a library, and the programs don't have any dependency.)
+
+// scenarioFile is the content of a scenario file.
+type scenarioFile struct {
+	Times     []quantity `json:"times"`
+	Scenarios []struct {
+		Name  string     `json:"name"`
+		A     *quantity  `json:"a"`
+		V0    *quantity  `json:"v0"`
+		S0    *quantity  `json:"s0"`
+		Times []quantity `json:"times"`
+	} `json:"scenarios"`
+}
+
+// quantity is a value of a scenario file: a number, or a string with a unit. We can only convert it once we know what it measures.
+type quantity string
+
+func (q *quantity) UnmarshalJSON(data []byte) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err == nil {
+		*q = quantity(text)
+		return nil
+	}
+	var number json.Number
+	if err := json.Unmarshal(data, &number); err != nil {
+		return fmt.Errorf("%s is neither a number nor a string with a unit", data)
+	}
+	*q = quantity(number)
+	return nil
+}
+
+// scenario is a scenario, with its values converted to SI units.
+type scenario struct {
+	Name         string
+	Acceleration float64
+	Velocity     float64
+	Displacement float64
+	Times        []float64
+}
+
+// ReadScenarios reads a scenario file, and checks that every scenario has a name, its three values, and times.
+func ReadScenarios(r io.Reader, source string) ([]scenario, error) {
+	var file scenarioFile
+	decoder := json.NewDecoder(r)
+	// A misspelled field would silently be 0 otherwise.
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&file); err != nil {
+		return nil, fmt.Errorf("%s: %w", source, err)
+	}
+	if len(file.Scenarios) == 0 {
+		return nil, fmt.Errorf("%s: there is no scenario in the file", source)
+	}
+
+	defaultTimes, err := convertTimes(file.Times)
+	if err != nil {
+		return nil, fmt.Errorf("%s: times: %w", source, err)
+	}
+
+	scenarios := make([]scenario, len(file.Scenarios))
+	names := map[string]bool{}
+	for i, s := range file.Scenarios {
+		where := fmt.Sprintf("%s: scenario %d (%q)", source, i+1, s.Name)
+		if s.Name == "" {
+			return nil, fmt.Errorf("%s: scenario %d has no name", source, i+1)
+		}
+		if names[s.Name] {
+			return nil, fmt.Errorf("%s: the name is used by another scenario", where)
+		}
+		names[s.Name] = true
+
+		scenarios[i].Name = s.Name
+		values := []struct {
+			field     string
+			value     *quantity
+			dimension kinematics.Dimension
+			target    *float64
+		}{
+			{"a", s.A, kinematics.Acceleration, &scenarios[i].Acceleration},
+			{"v0", s.V0, kinematics.Velocity, &scenarios[i].Velocity},
+			{"s0", s.S0, kinematics.Length, &scenarios[i].Displacement},
+		}
+		for _, v := range values {
+			if v.value == nil {
+				return nil, fmt.Errorf("%s: %s is missing", where, v.field)
+			}
+			if *v.target, err = kinematics.ParseQuantity(string(*v.value), v.dimension); err != nil {
+				return nil, fmt.Errorf("%s: %s: %w", where, v.field, err)
+			}
+		}
+
+		scenarios[i].Times = defaultTimes
+		if s.Times != nil {
+			if scenarios[i].Times, err = convertTimes(s.Times); err != nil {
+				return nil, fmt.Errorf("%s: times: %w", where, err)
+			}
+		}
+		if len(scenarios[i].Times) == 0 {
+			return nil, fmt.Errorf("%s: there are no times, in the scenario or for the whole file", where)
+		}
+	}
+	return scenarios, nil
+}
+
+// convertTimes converts times of a scenario file to seconds.
+func convertTimes(times []quantity) ([]float64, error) {
+	converted := make([]float64, len(times))
+	for i, t := range times {
+		var err error
+		if converted[i], err = kinematics.ParseQuantity(string(t), kinematics.Duration); err != nil {
+			return nil, err
+		}
+	}
+	return converted, nil
+}
+
+// selectScenarios returns the scenarios with the given names, in that order, or all of them when there are no names.
+func selectScenarios(scenarios []scenario, names []string) ([]scenario, error) {
+	if len(names) == 0 {
+		return scenarios, nil
+	}
+	selected := make([]scenario, 0, len(names))
+	for _, name := range names {
+		i := 0
+		for i < len(scenarios) && scenarios[i].Name != name {
+			i++
+		}
+		if i == len(scenarios) {
+			return nil, fmt.Errorf("there is no scenario named %q", name)
+		}
+		selected = append(selected, scenarios[i])
+	}
+	return selected, nil
+}
+
+// evaluateScenarios reads the scenario file and prints, for each scenario given as argument (or all of them), its displacement at its times.
+// With -compare, the scenarios are printed side by side instead.
+func evaluateScenarios(c config, stdin io.Reader, stdout io.Writer) error {
+	for _, name := range []string{"a", "v0", "s0", "to", "from", "step", "format"} {
+		if c.set[name] {
+			return cli.Usagef("-%s can't be used with -scenarios, the values come from the file", name)
+		}
+	}
+
+	input, name, close, err := cli.OpenInput(c.scenarioFile, stdin)
+	if err != nil {
+		return err
+	}
+	defer close()
+	all, err := ReadScenarios(input, name)
+	if err != nil {
+		return err
+	}
+	scenarios, err := selectScenarios(all, c.times)
+	if err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+
+	if c.compare {
+		return compareScenarios(c, scenarios, stdout)
+	}
+
+	type report struct {
+		Name                string   `json:"name"`
+		Acceleration        float64  `json:"acceleration"`
+		InitialVelocity     float64  `json:"initialVelocity"`
+		InitialDisplacement float64  `json:"initialDisplacement"`
+		Unit                string   `json:"unit,omitempty"`
+		Results             []result `json:"results"`
+	}
+	reports := make([]report, len(scenarios))
+	for i, s := range scenarios {
+		fn := GenDisplaceFn(s.Acceleration, s.Velocity, s.Displacement)
+		reports[i] = report{s.Name, s.Acceleration, s.Velocity, s.Displacement, c.output.Symbol, make([]result, len(s.Times))}
+		for j, t := range s.Times {
+			reports[i].Results[j] = result{Time: t, Displacement: c.output.FromSI(fn(t))}
+		}
+	}
+
+	if c.JSON {
+		return cli.WriteJSON(stdout, reports)
+	}
+	for i, r := range reports {
+		if c.Quiet {
+			// One line per scenario, with its displacements.
+			fmt.Fprint(stdout, r.Name+":")
+			for _, result := range r.Results {
+				fmt.Fprint(stdout, " ", result.Displacement)
+			}
+			fmt.Fprintln(stdout)
+			continue
+		}
+
+		if i > 0 {
+			fmt.Fprintln(stdout)
+		}
+		fmt.Fprintf(stdout, "Scenario %q: acceleration %v m/s², initial velocity %v m/s, initial displacement %v m\n",
+			r.Name, r.Acceleration, r.InitialVelocity, r.InitialDisplacement)
+		for _, result := range r.Results {
+			printResult(stdout, c.output, result)
+		}
+	}
+	return nil
+}
+
+// compareScenarios prints the scenarios side by side, one column each, at all the times of all of them.
+func compareScenarios(c config, scenarios []scenario, stdout io.Writer) error {
+	// The times of all the scenarios, sorted, and only once each.
+	var times []float64
+	seen := map[float64]bool{}
+	for _, s := range scenarios {
+		for _, t := range s.Times {
+			if !seen[t] {
+				seen[t] = true
+				times = append(times, t)
+			}
+		}
+	}
+	sort.Float64s(times)
+
+	// displacements[i][j] is the displacement of the scenario i at the time j.
+	displacements := make([][]float64, len(scenarios))
+	for i, s := range scenarios {
+		fn := GenDisplaceFn(s.Acceleration, s.Velocity, s.Displacement)
+		displacements[i] = make([]float64, len(times))
+		for j, t := range times {
+			displacements[i][j] = c.output.FromSI(fn(t))
+		}
+	}
+
+	if c.JSON {
+		type column struct {
+			Name          string    `json:"name"`
+			Displacements []float64 `json:"displacements"`
+		}
+		columns := make([]column, len(scenarios))
+		for i, s := range scenarios {
+			columns[i] = column{s.Name, displacements[i]}
+		}
+		return cli.WriteJSON(stdout, struct {
+			Unit      string    `json:"unit,omitempty"`
+			Times     []float64 `json:"times"`
+			Scenarios []column  `json:"scenarios"`
+		}{c.output.Symbol, times, columns})
+	}
+
+	// Each column is as wide as the name of its scenario, and at least 12 characters.
+	widths := make([]int, len(scenarios))
+	var line bytes.Buffer
+	if !c.Quiet {
+		unit := c.output.Symbol
+		if unit == "" {
+			unit = "m"
+		}
+		fmt.Fprintf(stdout, "Displacement (%s) of each scenario\n", unit)
+	}
+	fmt.Fprintf(&line, "%12s", "time (s)")
+	for i, s := range scenarios {
+		widths[i] = 12
+		if len(s.Name) > widths[i] {
+			widths[i] = len(s.Name)
+		}
+		fmt.Fprintf(&line, "  %*s", widths[i], s.Name)
+	}
+	if !c.Quiet {
+		fmt.Fprintln(stdout, line.String())
+	}
+
+	for j, t := range times {
+		line.Reset()
+		fmt.Fprintf(&line, "%12.6g", t)
+		for i := range scenarios {
+			fmt.Fprintf(&line, "  %*.6g", widths[i], displacements[i][j])
+		}
+		fmt.Fprintln(stdout, line.String())
+	}
+	return nil
+}
@@ -1,11 +1,16 @@
 package main
 
 import (
-	"flag"
 	"fmt"
+	"io"
 	"math/rand"
+	"os"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
+
+	"coursera-go/m/source/cli"
 )
 
 type ChopS struct{ sync.Mutex }
@@ -15,6 +20,7 @@ type Philo struct {
 	id              int
 	portionsLeft    int
 	leftCS, rightCS *ChopS
+	out             io.Writer // Where the philosopher tells when they start and finish eating.
 }
 
 type Channels struct {
@@ -38,12 +44,30 @@ func howManyPhilosophersAreEating(philosophersIsEating []bool) int {
 	return eatingPhilosophers
 }
 
+// HostReport is what the host saw during the dinner. It is filled by Host, and must only be read once Host is done.
+type HostReport struct {
+	Meals           int  // Number of times the host allowed a philosopher to eat.
+	MaxEatingAtOnce int  // Largest number of philosophers eating at the same time (it should never be more than 2).
+	TimedOut        bool // True if the host gave up waiting for the philosophers.
+}
+
 // Host is the goroutine that will make sure that only two philosophers are eating at the same time
 func Host(communicationChannels Channels,
 	numberOfPhilosophers int,
-	wg *sync.WaitGroup) {
+	wg *sync.WaitGroup,
+	report *HostReport) {
 	defer wg.Done()
 
+	// grant gives a philosopher the permission to eat, and keeps track of it in the report.
+	grant := func(philoID int, philosophersIsEating []bool) {
+		philosophersIsEating[philoID] = true
+		report.Meals++
+		if eating := howManyPhilosophersAreEating(philosophersIsEating); eating > report.MaxEatingAtOnce {
+			report.MaxEatingAtOnce = eating
+		}
+		communicationChannels.personalChannels[philoID] <- true
+	}
+
 	// philosophersIsEating is a slice that will keep track of which philosophers are eating.
 	philosophersIsEating := make([]bool, numberOfPhilosophers)
 	waitingQueue := []int{} // A queue to store waiting philosophers.
@@ -64,8 +88,7 @@ func Host(communicationChannels Channels,
 			if !philosophersIsEating[philoID] {
 				if howManyPhilosophersAreEating(philosophersIsEating) < 2 {
 					// Grant permission
-					philosophersIsEating[philoID] = true
-					communicationChannels.personalChannels[philoID] <- true
+					grant(philoID, philosophersIsEating)
 				} else {
 					// Add philosopher to waiting queue.
 					waitingQueue = append(waitingQueue, philoID)
@@ -80,13 +103,13 @@ func Host(communicationChannels Channels,
 					waitingQueue = waitingQueue[1:] // Dequeue.
 
 					// Grant permission.
-					philosophersIsEating[nextPhilo] = true
-					communicationChannels.personalChannels[nextPhilo] <- true
+					grant(nextPhilo, philosophersIsEating)
 				}
 			}
 		case <-time.After(time.Second * 10):
 			// Some reasonable timeout
-			fmt.Println("Timeout, host is exiting.") // Note: this should never happen. It's only a failsafe.
+			// Note: this should never happen. It's only a failsafe.
+			report.TimedOut = true
 			return
 		}
 	}
@@ -108,12 +131,12 @@ func (p Philo) eat(communicationChannels Channels,
 
 		p.pickCS()
 
-		fmt.Println("starting to eat", p.id)
+		fmt.Fprintln(p.out, "starting to eat", p.id)
 
 		// The act of eating is just decreasing the number of portions left, but we can imagine that it's a more complex process.
 		p.portionsLeft--
 
-		fmt.Println("finishing eating", p.id)
+		fmt.Fprintln(p.out, "finishing eating", p.id)
 
 		p.releaseCS()
 
@@ -141,61 +164,115 @@ func (p Philo) pickCS() {
 }
 
 func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// run is the whole program. It gets the command line arguments and the standard streams, and returns the exit code.
+// The number of philosophers and of portions can be given with -n and -p, as arguments, or in the -in file, for example:
+// go run Philosophers.go --quiet 10 5
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	var options cli.Options
+	flags := cli.NewFlagSet("Philosophers", stderr)
+	options.Register(flags)
+	numberOfPhilosophers := flags.Int("n", 5, "Number of philosophers")
+	numberOfPortions := flags.Int("p", 3, "Number of portions per philosopher")
+	if ok, code := cli.Parse(flags, args); !ok {
+		return code
+	}
+
+	err := readSettings(options, flags.Args(), stdin, numberOfPhilosophers, numberOfPortions)
+	if err == nil {
+		err = dinner(options, *numberOfPhilosophers, *numberOfPortions, stdout)
+	}
+	return options.Report(stderr, "Philosophers", err)
+}
+
+// readSettings reads the number of philosophers and of portions from the arguments (or, if there are none, from the -in file),
+// in this order. The values that are not given keep what the flags said.
+func readSettings(options cli.Options, args []string, stdin io.Reader, numberOfPhilosophers, numberOfPortions *int) error {
+	values := args
+	if len(values) == 0 && options.Input != "" {
+		input, _, close, err := cli.OpenInput(options.Input, stdin)
+		if err != nil {
+			return err
+		}
+		defer close()
+		content, err := io.ReadAll(input)
+		if err != nil {
+			return err
+		}
+		values = strings.Fields(string(content))
+	}
+
+	if len(values) > 2 {
+		return cli.Usagef("expected at most 2 values (philosophers and portions), got %d", len(values))
+	}
+	for i, target := range []*int{numberOfPhilosophers, numberOfPortions}[:len(values)] {
+		value, err := strconv.Atoi(values[i])
+		if err != nil {
+			return cli.Usagef("%q is not a valid number", values[i])
+		}
+		*target = value
+	}
+	return nil
+}
+
+// dinner makes the philosophers eat, and prints what happens.
+func dinner(options cli.Options, numberOfPhilosophers, numberOfPortions int, stdout io.Writer) error {
 
-	numberOfPhilosophers := flag.Int("n", 5, "Number of philosophers")
-	numberOfPortions := flag.Int("p", 3, "Number of portions per philosopher")
-	flag.Parse()
+	if numberOfPhilosophers < 2 {
+		return cli.Usagef("There must be at least two philosophers.")
+	}
 
-	if *numberOfPhilosophers < 2 {
-		fmt.Println("There must be at least two philosophers.")
-		return
+	if numberOfPhilosophers > 8000 {
+		return cli.Usagef("There can't be more than 8000 philosophers (hard limit on the number of goroutines).")
 	}
 
-	if *numberOfPhilosophers > 8000 {
-		fmt.Println("There can't be more than 8000 philosophers (hard limit on the number of goroutines).")
-		return
+	if numberOfPortions < 1 {
+		return cli.Usagef("There must be at least one portion per philosopher.")
 	}
 
-	if *numberOfPortions < 1 {
-		fmt.Println("There must be at least one portion per philosopher.")
-		return
+	// messages is where we print everything that is not the final result: it is thrown away with --quiet or --json.
+	messages := io.Discard
+	if options.Verbose() {
+		messages = stdout
 	}
 
 	rand.Seed(time.Now().UnixNano())
-	fmt.Println("Welcome to the dining philosophers problem!")
-	fmt.Println("-------------------------------------------")
-	fmt.Println()
-	fmt.Println("You can change the number of philosophers and the number of portions per philosopher using the -n and -p flags.")
-	fmt.Println("Example: >go run Philosophers.go -n 10 -p 5")
-	fmt.Println()
-	fmt.Println("Number of philosophers:", *numberOfPhilosophers)
-	fmt.Println("Number of portions per philosopher:", *numberOfPortions)
-	fmt.Println()
+	fmt.Fprintln(messages, "Welcome to the dining philosophers problem!")
+	fmt.Fprintln(messages, "-------------------------------------------")
+	fmt.Fprintln(messages)
+	fmt.Fprintln(messages, "You can change the number of philosophers and the number of portions per philosopher using the -n and -p flags.")
+	fmt.Fprintln(messages, "Example: >go run Philosophers.go -n 10 -p 5")
+	fmt.Fprintln(messages)
+	fmt.Fprintln(messages, "Number of philosophers:", numberOfPhilosophers)
+	fmt.Fprintln(messages, "Number of portions per philosopher:", numberOfPortions)
+	fmt.Fprintln(messages)
 
 	// Create a WaitGroup
 	var wg sync.WaitGroup
 
 	// Add all the philosophers + the host to the WaitGroup
-	wg.Add(*numberOfPhilosophers + 1)
+	wg.Add(numberOfPhilosophers + 1)
 
 	// initialize the ChopSticks
-	CSticks := make([]*ChopS, *numberOfPhilosophers)
-	for i := 0; i < *numberOfPhilosophers; i++ {
+	CSticks := make([]*ChopS, numberOfPhilosophers)
+	for i := 0; i < numberOfPhilosophers; i++ {
 		CSticks[i] = new(ChopS)
 	}
 
 	// Initialize the Philosophers
-	philos := make([]*Philo, *numberOfPhilosophers)
-	for i := 0; i < *numberOfPhilosophers; i++ {
-		philos[i] = &Philo{i, i + 1, *numberOfPortions, CSticks[i], CSticks[(i+1)%*numberOfPhilosophers]}
+	philos := make([]*Philo, numberOfPhilosophers)
+	for i := 0; i < numberOfPhilosophers; i++ {
+		philos[i] = &Philo{i, i + 1, numberOfPortions, CSticks[i], CSticks[(i+1)%numberOfPhilosophers], messages}
 	}
 
 	// Create the request channel
 	requestChannel := make(chan int)
 
 	// Create the personal channels
-	personalChannels := make([]chan bool, *numberOfPhilosophers)
-	for i := 0; i < *numberOfPhilosophers; i++ {
+	personalChannels := make([]chan bool, numberOfPhilosophers)
+	for i := 0; i < numberOfPhilosophers; i++ {
 		personalChannels[i] = make(chan bool)
 	}
 
@@ -206,16 +283,40 @@ func main() {
 
 	// Start the host
 
-	go Host(communicationChannels, *numberOfPhilosophers, &wg)
+	// hostDone is closed when the host is done: either all the philosophers have eaten, or it timed out.
+	var report HostReport
+	hostDone := make(chan struct{})
+	go func() {
+		Host(communicationChannels, numberOfPhilosophers, &wg, &report)
+		close(hostDone)
+	}()
 
 	// Make the philosophers eat
-	for i := 0; i < *numberOfPhilosophers; i++ {
+	for i := 0; i < numberOfPhilosophers; i++ {
 		go philos[i].eat(communicationChannels, &wg)
 	}
 
-	// Maker sure that the host has finished before exiting
-	wg.Wait()
+	// If the host gave up, the philosophers still waiting for it would block forever: we can't wait for them.
+	<-hostDone
+	if report.TimedOut {
+		return fmt.Errorf("Timeout, host has exited before all the philosophers were done eating.")
+	}
 
-	fmt.Println("All philosophers are done eating, host has exited, program is done.")
+	// Maker sure that the philosophers have finished before exiting
+	wg.Wait()
 
+	switch {
+	case options.JSON:
+		return cli.WriteJSON(stdout, struct {
+			Philosophers    int `json:"philosophers"`
+			Portions        int `json:"portions"`
+			Meals           int `json:"meals"`
+			MaxEatingAtOnce int `json:"maxEatingAtOnce"`
+		}{numberOfPhilosophers, numberOfPortions, report.Meals, report.MaxEatingAtOnce})
+	case options.Quiet:
+		fmt.Fprintf(stdout, "%d philosophers ate %d meals, at most %d at the same time\n", numberOfPhilosophers, report.Meals, report.MaxEatingAtOnce)
+	default:
+		fmt.Fprintln(stdout, "All philosophers are done eating, host has exited, program is done.")
+	}
+	return nil
 }
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"coursera-go/m/source/cli"
+	"coursera-go/m/source/cli/golden"
+)
+
+func TestRunJSON(t *testing.T) {
+	// The order in which the philosophers eat changes at each run, but the totals don't.
+	testCases := []struct {
+		name             string
+		args             []string
+		wantPhilosophers int
+		wantMeals        int
+	}{
+		{name: "flags", args: []string{"--json", "-n", "4", "-p", "2"}, wantPhilosophers: 4, wantMeals: 8},
+		{name: "arguments", args: []string{"--json", "6", "3"}, wantPhilosophers: 6, wantMeals: 18},
+		{name: "default portions", args: []string{"--json", "2"}, wantPhilosophers: 2, wantMeals: 6},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			if code := run(tc.args, strings.NewReader(""), &stdout, &stderr); code != cli.ExitOK {
+				t.Fatalf("run(%v) exited with %d (stderr: %s)", tc.args, code, stderr.String())
+			}
+
+			var report struct {
+				Philosophers    int `json:"philosophers"`
+				Meals           int `json:"meals"`
+				MaxEatingAtOnce int `json:"maxEatingAtOnce"`
+			}
+			if err := json.Unmarshal(stdout.Bytes(), &report); err != nil {
+				t.Fatalf("the output is not valid JSON: %v\n%s", err, stdout.String())
+			}
+			if report.Philosophers != tc.wantPhilosophers || report.Meals != tc.wantMeals {
+				t.Errorf("run(%v) = %+v; want %d philosophers and %d meals", tc.args, report, tc.wantPhilosophers, tc.wantMeals)
+			}
+			if report.MaxEatingAtOnce < 1 || report.MaxEatingAtOnce > 2 {
+				t.Errorf("run(%v): %d philosophers ate at the same time; want 1 or 2", tc.args, report.MaxEatingAtOnce)
+			}
+		})
+	}
+}
+
+func TestRunInputFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "settings.txt")
+	if err := os.WriteFile(path, []byte("3\n4\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"--json", "-in", path}, strings.NewReader(""), &stdout, &stderr); code != cli.ExitOK {
+		t.Fatalf("run exited with %d (stderr: %s)", code, stderr.String())
+	}
+	if !strings.Contains(stdout.String(), `"meals": 12`) {
+		t.Errorf("3 philosophers with 4 portions should eat 12 meals, got:\n%s", stdout.String())
+	}
+}
+
+func TestRunErrors(t *testing.T) {
+	testCases := []struct {
+		name     string
+		args     []string
+		wantCode int
+	}{
+		{name: "one philosopher", args: []string{"--quiet", "1"}, wantCode: cli.ExitUsage},
+		{name: "no portion", args: []string{"--quiet", "-p", "0"}, wantCode: cli.ExitUsage},
+		{name: "too many values", args: []string{"--quiet", "5", "3", "1"}, wantCode: cli.ExitUsage},
+		{name: "not a number", args: []string{"--quiet", "five"}, wantCode: cli.ExitUsage},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			code := run(tc.args, strings.NewReader(""), &stdout, &stderr)
+			if code != tc.wantCode {
+				t.Errorf("run(%v) exited with %d; want %d", tc.args, code, tc.wantCode)
+			}
+			golden.Assert(t, "run-"+strings.ReplaceAll(tc.name, " ", "-"), stderr.String())
+		})
+	}
+}
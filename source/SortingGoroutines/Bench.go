@@ -0,0 +1,248 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"runtime"
+	"sort"
+	"time"
+
+	"coursera-go/m/source/cli"
+	"coursera-go/m/source/generate"
+	"coursera-go/m/source/sorting"
+)
+
+// Timing one sort with time.Since says little: the next run can take twice as long, and comparing with sort.Ints on another
+// random slice compares different work. The benchmark report sorts the same slices with every contender, several times,
+// and tells how sure we can be of the difference:
+// go run SortingGoroutines.go -bench-report -size 100000 -runs 10
+// or, for only one distribution: go run SortingGoroutines.go -bench-report -size 100000 -dist nearly-sorted:100
+// The benchmarks of SortingGoroutines_test.go use the same distributions and contenders: go test -bench . -benchmem
+
+// distributions are the kinds of slices the contenders are compared on by default. Some sorts are much faster on sorted slices,
+// or on slices with many equal values, so random slices alone would not tell the whole story.
+var distributions = []string{"uniform", "sorted", "reversed", "few-unique"}
+
+// benchGenerators returns the generators of the distributions. Their seed is the size, so that a slice is always the same one for the same size.
+func benchGenerators(size int) []generate.Generator {
+	generators := make([]generate.Generator, len(distributions))
+	for i, distribution := range distributions {
+		var err error
+		generators[i], err = generate.New(distribution, 0, math.MaxInt32, int64(size))
+		if err != nil {
+			panic(err)
+		}
+	}
+	return generators
+}
+
+// contender is one way of sorting a slice. sort sorts the slice in place.
+type contender struct {
+	name string
+	sort func(slice []int)
+}
+
+// contenders returns sort.Ints, which is the baseline, and the two ways of sorting with goroutines of the program, with the given number of workers.
+func contenders(sorter sorting.Sorter, workers, cutoff int) []contender {
+	m := &merger{}
+	return []contender{
+		{"sort.Ints", sort.Ints},
+		{fmt.Sprintf("parts-%d", workers), func(slice []int) {
+			// The merger keeps its buffer from one sort to the next: only the first sort allocates it.
+			splitSort(sorter, partition(slice, workers), m)
+		}},
+		{fmt.Sprintf("recursive-%d", workers), func(slice []int) {
+			sorting.ParallelMergeSort(slice, cutoff, workers)
+		}},
+	}
+}
+
+// benchTime is how long measure sorts the same slice again and again, to time even the tiny slices precisely.
+var benchTime = 100 * time.Millisecond
+
+// measurement is the cost of one sort, averaged over many.
+type measurement struct {
+	Nanoseconds float64 // Time per sort.
+	Allocations float64 // Memory allocations per sort.
+	Bytes       float64 // Bytes allocated per sort.
+}
+
+// measure sorts copies of the input with the contender for at least benchTime, and returns the average cost of one sort.
+// Copying the input is not counted, and does not allocate.
+func measure(c contender, input []int) measurement {
+	slice := make([]int, len(input))
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+
+	var elapsed time.Duration
+	sorts := 0
+	for elapsed < benchTime || sorts == 0 {
+		copy(slice, input)
+		start := time.Now()
+		c.sort(slice)
+		elapsed += time.Since(start)
+		sorts++
+	}
+
+	runtime.ReadMemStats(&after)
+	n := float64(sorts)
+	return measurement{
+		Nanoseconds: float64(elapsed.Nanoseconds()) / n,
+		Allocations: float64(after.Mallocs-before.Mallocs) / n,
+		Bytes:       float64(after.TotalAlloc-before.TotalAlloc) / n,
+	}
+}
+
+// statistics are the mean and the standard deviation of some values, and the 95% confidence interval of the mean.
+type statistics struct {
+	Mean              float64 `json:"mean"`
+	StandardDeviation float64 `json:"standardDeviation"`
+	Low               float64 `json:"low"`
+	High              float64 `json:"high"`
+}
+
+func computeStatistics(values []float64) statistics {
+	n := float64(len(values))
+	var s statistics
+	for _, v := range values {
+		s.Mean += v
+	}
+	s.Mean /= n
+	if len(values) > 1 {
+		for _, v := range values {
+			s.StandardDeviation += (v - s.Mean) * (v - s.Mean)
+		}
+		s.StandardDeviation = math.Sqrt(s.StandardDeviation / (n - 1))
+	}
+	margin := studentT95(len(values)-1) * s.StandardDeviation / math.Sqrt(n)
+	s.Low, s.High = s.Mean-margin, s.Mean+margin
+	return s
+}
+
+// studentT95 returns the value of Student's t-distribution with dof degrees of freedom for a 95% confidence interval.
+// With few runs, the standard deviation is itself uncertain, so the interval must be wider than the usual ±1.96 standard errors.
+func studentT95(dof int) float64 {
+	table := []float64{
+		math.Inf(1), 12.706, 4.303, 3.182, 2.776, 2.571, 2.447, 2.365, 2.306, 2.262, 2.228,
+		2.201, 2.179, 2.160, 2.145, 2.131, 2.120, 2.110, 2.101, 2.093, 2.086,
+		2.080, 2.074, 2.069, 2.064, 2.060, 2.056, 2.052, 2.048, 2.045, 2.042,
+	}
+	switch {
+	case dof < 1:
+		return math.Inf(1)
+	case dof < len(table):
+		return table[dof]
+	case dof < 60:
+		return 2.021
+	case dof < 120:
+		return 2.000
+	}
+	return 1.960
+}
+
+// contenderReport is the result of one contender on one distribution.
+type contenderReport struct {
+	Name        string     `json:"name"`
+	Nanoseconds statistics `json:"nanoseconds"`
+	Speedup     statistics `json:"speedup"` // How many times faster than sort.Ints: more than 1 is faster.
+	Allocations float64    `json:"allocations"`
+	Bytes       float64    `json:"bytes"`
+}
+
+// distributionReport is the result of all the contenders on one distribution.
+type distributionReport struct {
+	Distribution string            `json:"distribution"`
+	Contenders   []contenderReport `json:"contenders"`
+}
+
+// benchReport measures every contender runs times on the slices of every generator, and prints the results.
+// The contenders take turns in each run, so that something slowing the machine down for a while slows them all down.
+// A report takes a while: we check ctx before every measure, and return ctx.Err() without printing anything when it is done.
+func benchReport(ctx context.Context, c config, sorter sorting.Sorter, generators []generate.Generator, stdout io.Writer) error {
+	runs := c.runs
+	if runs < 2 {
+		return cli.Usagef("-runs must be 2 or more to compute a standard deviation, got %d", runs)
+	}
+
+	reports := make([]distributionReport, len(generators))
+	for d, generator := range generators {
+		input := generator.Generate(c.size)
+		all := contenders(sorter, c.workers, c.cutoff)
+
+		// times[i][r] is the time of the contender i in the run r.
+		times := make([][]float64, len(all))
+		last := make([]measurement, len(all))
+		for r := 0; r < runs; r++ {
+			for i, contender := range all {
+				if err := ctx.Err(); err != nil {
+					return err
+				}
+				last[i] = measure(contender, input)
+				times[i] = append(times[i], last[i].Nanoseconds)
+			}
+		}
+
+		reports[d].Distribution = generator.String()
+		for i, contender := range all {
+			// The speedup is computed run by run, against sort.Ints in the same run.
+			speedups := make([]float64, runs)
+			for r := range speedups {
+				speedups[r] = times[0][r] / times[i][r]
+			}
+			reports[d].Contenders = append(reports[d].Contenders, contenderReport{
+				Name:        contender.name,
+				Nanoseconds: computeStatistics(times[i]),
+				Speedup:     computeStatistics(speedups),
+				Allocations: last[i].Allocations,
+				Bytes:       last[i].Bytes,
+			})
+		}
+	}
+
+	if c.JSON {
+		return cli.WriteJSON(stdout, struct {
+			Size         int                  `json:"size"`
+			Workers      int                  `json:"workers"`
+			Runs         int                  `json:"runs"`
+			CPUs         int                  `json:"cpus"`
+			Distribution []distributionReport `json:"distributions"`
+		}{c.size, c.workers, runs, runtime.NumCPU(), reports})
+	}
+
+	if !c.Quiet {
+		fmt.Fprintf(stdout, "Sorting %d integers, %d runs, on %d CPUs (speedups against sort.Ints, with 95%% confidence intervals)\n",
+			c.size, runs, runtime.NumCPU())
+	}
+	for _, report := range reports {
+		if !c.Quiet {
+			fmt.Fprintln(stdout)
+			fmt.Fprintln(stdout, report.Distribution)
+			fmt.Fprintf(stdout, "  %-14s  %12s  %10s  %8s  %-17s  %8s  %10s\n", "contender", "mean", "std dev", "speedup", "interval", "allocs", "bytes")
+		}
+		for _, r := range report.Contenders {
+			if c.Quiet {
+				// One line per contender: distribution, name, mean in nanoseconds and speedup.
+				fmt.Fprintln(stdout, report.Distribution, r.Name, r.Nanoseconds.Mean, r.Speedup.Mean)
+				continue
+			}
+			fmt.Fprintf(stdout, "  %-14s  %12v  %10v  %7.2fx  %-17s  %8.0f  %10.0f\n",
+				r.Name, nanoseconds(r.Nanoseconds.Mean), nanoseconds(r.Nanoseconds.StandardDeviation),
+				r.Speedup.Mean, fmt.Sprintf("[%.2fx, %.2fx]", r.Speedup.Low, r.Speedup.High), r.Allocations, r.Bytes)
+		}
+	}
+	return nil
+}
+
+// nanoseconds turns a number of nanoseconds into a duration, which prints nicely (like 1.5ms), rounded to a thousandth of its unit.
+func nanoseconds(ns float64) time.Duration {
+	d := time.Duration(ns)
+	for _, unit := range []time.Duration{time.Second, time.Millisecond, time.Microsecond} {
+		if d >= unit {
+			return d.Round(unit / 1000)
+		}
+	}
+	return d
+}
@@ -0,0 +1,595 @@
+package main
+
+import (
+	"bufio"
+	"container/heap"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"coursera-go/m/source/cli"
+	"coursera-go/m/source/sorting"
+)
+
+// Above 100M integers, the slice does not fit in memory anymore. The external sort mode sorts files of any size instead,
+// with a fixed amount of memory:
+// go run SortingGoroutines.go -external -in numbers.txt -out sorted.txt -memory 256M
+//
+// It works in two steps:
+//  1. The input is read by chunks that fit in memory. Each chunk is sorted by a goroutine (-workers of them at once)
+//     and written to a temporary file: a sorted "run".
+//  2. The runs are merged into the output with a k-way merge: the next value of every run waits in a heap, which gives
+//     the smallest of them in log(k) steps, so the files are read only once, as the values are needed. When there are more
+//     runs than files we may open at once (-max-open), groups of runs are first merged into bigger runs, until there are few enough of them.
+//
+// The files can be text (integers separated by spaces or new lines, one per line in the output),
+// or binary (64 bits little-endian integers, which is much faster to read and write). The runs are always binary.
+
+// externalOptions are the settings of the external sort.
+type externalOptions struct {
+	Memory  int64          // Bytes the chunks may use, for all the workers together.
+	MaxOpen int            // Most files open at once while merging, the output included, and the input if CloseInput is nil.
+	Workers int            // Chunks sorted at once.
+	TempDir string         // Where the runs are written, the default temporary directory if empty.
+	Format  string         // Format of the input and the output: "text" or "binary".
+	Sorter  sorting.Sorter // The algorithm used to sort each chunk.
+	// Progress receives the runs written, then the bytes merged by all the passes, if it is not nil.
+	Progress progressFunc
+	// Verify checks the output while it is written: its order, and its checksum against the one of the input (see Verify.go).
+	Verify bool
+	// CloseInput, if not nil, closes the input once it has been read into runs: the merges don't need it, and it would
+	// be one more file open than MaxOpen.
+	CloseInput func() error
+}
+
+// externalFormats are the formats of the files of the external sort.
+var externalFormats = []string{"text", "binary"}
+
+// externalStats tells what the external sort did.
+type externalStats struct {
+	Values int64 `json:"values"` // Integers sorted.
+	Runs   int   `json:"runs"`   // Sorted runs written by the first step.
+	Passes int   `json:"passes"` // Merge passes, the last one writing the output.
+	// Verification is the result of the checks, with Verify.
+	Verification *verification `json:"verification,omitempty"`
+}
+
+// minMergeBuffer is the smallest buffer given to each file while merging: smaller reads would cost more than they save.
+const minMergeBuffer = 4096
+
+// externalSort sorts the integers of r into w, as explained above. The name of the input is used in error messages.
+// It stops and returns ctx.Err() when the context is done, and the temporary files are removed whatever happens.
+func externalSort(ctx context.Context, r io.Reader, name string, w io.Writer, o externalOptions) (stats externalStats, err error) {
+	chunkSize := o.Memory / 8 / int64(o.Workers)
+	if chunkSize < 1 {
+		return stats, fmt.Errorf("the memory limit of %d bytes is too small for %d workers: each one needs 8 bytes at least", o.Memory, o.Workers)
+	}
+	if o.MaxOpen < 3 {
+		return stats, fmt.Errorf("at least 3 files must be open at once to merge, got %d", o.MaxOpen)
+	}
+
+	dir, err := os.MkdirTemp(o.TempDir, "sorting-runs-")
+	if err != nil {
+		return stats, err
+	}
+	defer os.RemoveAll(dir)
+
+	runs, values, before, err := writeRuns(ctx, newValueReader(r, name, o.Format), dir, int(chunkSize), o)
+	if err != nil {
+		return stats, err
+	}
+	stats.Values, stats.Runs = values, len(runs)
+	if o.CloseInput != nil {
+		if err := o.CloseInput(); err != nil {
+			return stats, err
+		}
+	}
+
+	// Every pass merges all the values once: now that we know how many runs there are, we know how many bytes will be merged.
+	group := o.MaxOpen - 1
+	current := progress{PartsDone: len(runs), Bytes: 8 * values * int64(mergePasses(len(runs), group))}
+	reportMerge := func(n int) {
+		if o.Progress != nil {
+			current.BytesMerged += 8 * int64(n)
+			o.Progress(current)
+		}
+	}
+	reportMerge(0)
+
+	// Each file read or written while merging gets its share of the memory.
+	buffer := int(o.Memory / int64(o.MaxOpen))
+	if buffer < minMergeBuffer {
+		buffer = minMergeBuffer
+	}
+
+	// While there are too many runs to merge them all into the output, we merge groups of them into bigger runs.
+	for len(runs) > group {
+		stats.Passes++
+		var merged []string
+		for start := 0; start < len(runs); start += group {
+			end := start + group
+			if end > len(runs) {
+				end = len(runs)
+			}
+			path := filepath.Join(dir, fmt.Sprintf("pass-%d-run-%d", stats.Passes, len(merged)))
+			if err := mergeRunsToFile(ctx, runs[start:end], path, buffer, reportMerge); err != nil {
+				return stats, err
+			}
+			merged = append(merged, path)
+			// The merged runs are not needed anymore: removing them now halves the disk space used.
+			for _, run := range runs[start:end] {
+				os.Remove(run)
+			}
+		}
+		runs = merged
+	}
+
+	stats.Passes++
+	output := newValueWriter(w, o.Format, buffer)
+	checked := &verifyingWriter{valueWriter: output, firstUnsorted: -1}
+	if o.Verify {
+		output = checked
+	}
+	if err := mergeRuns(ctx, runs, output, buffer, reportMerge); err != nil {
+		return stats, err
+	}
+	if err := output.Flush(); err != nil {
+		return stats, err
+	}
+
+	if o.Verify {
+		v := checked.verification(before)
+		stats.Verification = &v
+		if !v.ok() {
+			return stats, fmt.Errorf("verification failed: %v", v)
+		}
+	}
+	return stats, nil
+}
+
+// verifyingWriter checks the values on their way to the output: that each one is not smaller than the one before it,
+// and their checksum.
+type verifyingWriter struct {
+	valueWriter
+	written       int
+	last          int
+	firstUnsorted int // -1 as long as the values are in order.
+	sum           uint64
+}
+
+func (v *verifyingWriter) Write(value int) error {
+	if v.written > 0 && value < v.last && v.firstUnsorted < 0 {
+		v.firstUnsorted = v.written
+	}
+	v.written++
+	v.last = value
+	v.sum += mix(value)
+	return v.valueWriter.Write(value)
+}
+
+// verification returns the result of the checks, before being the checksum of the input.
+func (v *verifyingWriter) verification(before uint64) verification {
+	result := verification{Values: v.written, Sorted: v.firstUnsorted < 0, ChecksumBefore: before, ChecksumAfter: v.sum}
+	if !result.Sorted {
+		result.FirstUnsorted = v.firstUnsorted
+	}
+	return result
+}
+
+// mergePasses returns the number of passes needed to merge runs, group runs at a time: the last one writes the output.
+func mergePasses(runs, group int) int {
+	passes := 1
+	for runs > group {
+		runs = (runs + group - 1) / group
+		passes++
+	}
+	return passes
+}
+
+// writeRuns reads chunks of the input, and sorts each one in a goroutine that writes it to a run file in dir.
+// Only Workers chunks are ever in memory: the next chunk is read into the buffer of a chunk that has been written.
+// It stops reading when the context is done, and waits for the chunks being written before returning ctx.Err().
+// With Verify, it also returns the checksum of the input (see Verify.go), taken before the chunks are sorted.
+func writeRuns(ctx context.Context, input valueReader, dir string, chunkSize int, o externalOptions) (runs []string, values int64, sum uint64, err error) {
+	buffers := make(chan []int, o.Workers)
+	for i := 0; i < o.Workers; i++ {
+		buffers <- make([]int, chunkSize)
+	}
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var writeErr error
+	written := 0
+	for {
+		if err = ctx.Err(); err != nil {
+			break
+		}
+		buffer := <-buffers
+		n, readErr := readChunk(input, buffer)
+		if n > 0 {
+			path := filepath.Join(dir, fmt.Sprintf("run-%d", len(runs)))
+			runs = append(runs, path)
+			values += int64(n)
+			wg.Add(1)
+			go func(chunk []int) {
+				defer wg.Done()
+				var chunkSum uint64
+				if o.Verify {
+					chunkSum = sumMixed(chunk)
+				}
+				o.Sorter.Sort(chunk)
+				err := writeRun(path, chunk)
+				mu.Lock()
+				if err != nil && writeErr == nil {
+					writeErr = err
+				}
+				sum += chunkSum
+				written++
+				if o.Progress != nil {
+					o.Progress(progress{PartsDone: written})
+				}
+				mu.Unlock()
+				buffers <- chunk[:cap(chunk)]
+			}(buffer[:n])
+		} else {
+			buffers <- buffer
+		}
+
+		if readErr == io.EOF {
+			break
+		}
+		if readErr != nil {
+			err = readErr
+			break
+		}
+	}
+
+	wg.Wait()
+	if err == nil {
+		err = writeErr
+	}
+	return runs, values, sum, err
+}
+
+// readChunk fills the chunk with values of the input. It returns how many it read, and io.EOF once the input is over.
+func readChunk(input valueReader, chunk []int) (int, error) {
+	for i := range chunk {
+		v, err := input.Read()
+		if err != nil {
+			return i, err
+		}
+		chunk[i] = v
+	}
+	return len(chunk), nil
+}
+
+// writeRun writes the sorted chunk to a binary run file.
+func writeRun(path string, chunk []int) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	output := newValueWriter(file, "binary", 64*1024)
+	for _, v := range chunk {
+		if err := output.Write(v); err != nil {
+			file.Close()
+			return err
+		}
+	}
+	if err := output.Flush(); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// mergeRunsToFile merges runs into a new binary run file.
+func mergeRunsToFile(ctx context.Context, runs []string, path string, buffer int, merged func(n int)) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	output := newValueWriter(file, "binary", buffer)
+	if err := mergeRuns(ctx, runs, output, buffer, merged); err != nil {
+		file.Close()
+		return err
+	}
+	if err := output.Flush(); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// mergeStep is the number of values mergeRuns merges between two looks at the context, and two reports of its progress.
+const mergeStep = 1 << 16
+
+// mergeRuns merges the sorted runs into the output. It keeps the next value of every run in a heap,
+// and when values are equal, the one of the first run comes first.
+// Every mergeStep values, it checks the context, and tells merged how many values it wrote since the last time.
+func mergeRuns(ctx context.Context, runs []string, output valueWriter, buffer int, merged func(n int)) error {
+	h := &runHeap{}
+	for i, path := range runs {
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+
+		input := newValueReader(bufio.NewReaderSize(file, buffer), path, "binary")
+		v, err := input.Read()
+		if err == io.EOF {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		h.runs = append(h.runs, runCursor{value: v, run: i, input: input})
+	}
+	heap.Init(h)
+
+	written := 0
+	for h.Len() > 0 {
+		if written == mergeStep {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			merged(written)
+			written = 0
+		}
+		// The smallest value is at the top of the heap. We write it, and the next value of its run takes its place.
+		top := &h.runs[0]
+		if err := output.Write(top.value); err != nil {
+			return err
+		}
+		written++
+		v, err := top.input.Read()
+		switch {
+		case err == io.EOF:
+			heap.Pop(h)
+		case err != nil:
+			return err
+		default:
+			top.value = v
+			heap.Fix(h, 0)
+		}
+	}
+	merged(written)
+	return nil
+}
+
+// runCursor is the next value of a run being merged.
+type runCursor struct {
+	value int
+	run   int
+	input valueReader
+}
+
+// runHeap is a heap of run cursors, ordered by their value. It implements heap.Interface.
+type runHeap struct {
+	runs []runCursor
+}
+
+func (h *runHeap) Len() int { return len(h.runs) }
+
+func (h *runHeap) Less(i, j int) bool {
+	a, b := h.runs[i], h.runs[j]
+	if a.value != b.value {
+		return a.value < b.value
+	}
+	return a.run < b.run
+}
+
+func (h *runHeap) Swap(i, j int) { h.runs[i], h.runs[j] = h.runs[j], h.runs[i] }
+
+func (h *runHeap) Push(x any) { h.runs = append(h.runs, x.(runCursor)) }
+
+func (h *runHeap) Pop() any {
+	last := h.runs[len(h.runs)-1]
+	h.runs = h.runs[:len(h.runs)-1]
+	return last
+}
+
+// valueReader reads integers one by one. Read returns io.EOF at the end of the input.
+type valueReader interface {
+	Read() (int, error)
+}
+
+// valueWriter writes integers one by one. Flush must be called at the end.
+type valueWriter interface {
+	Write(v int) error
+	Flush() error
+}
+
+// newValueReader reads the integers of r in the given format. The name is used in error messages.
+func newValueReader(r io.Reader, name, format string) valueReader {
+	if format == "binary" {
+		return &binaryReader{reader: bufio.NewReader(r), name: name}
+	}
+	scanner := bufio.NewScanner(r)
+	scanner.Split(bufio.ScanWords)
+	return &textReader{scanner: scanner, name: name}
+}
+
+// newValueWriter writes integers to w in the given format, with a buffer of the given size.
+func newValueWriter(w io.Writer, format string, buffer int) valueWriter {
+	if format == "binary" {
+		return &binaryWriter{writer: bufio.NewWriterSize(w, buffer)}
+	}
+	return &textWriter{writer: bufio.NewWriterSize(w, buffer)}
+}
+
+// textReader reads integers separated by spaces, tabs or new lines.
+type textReader struct {
+	scanner *bufio.Scanner
+	name    string
+	count   int
+}
+
+func (t *textReader) Read() (int, error) {
+	if !t.scanner.Scan() {
+		if err := t.scanner.Err(); err != nil {
+			return 0, fmt.Errorf("%s: %w", t.name, err)
+		}
+		return 0, io.EOF
+	}
+	t.count++
+	v, err := strconv.Atoi(t.scanner.Text())
+	if err != nil {
+		return 0, fmt.Errorf("%s: value %d: %q is not a valid integer", t.name, t.count, t.scanner.Text())
+	}
+	return v, nil
+}
+
+// binaryReader reads 64 bits little-endian integers.
+type binaryReader struct {
+	reader *bufio.Reader
+	name   string
+	bytes  [8]byte
+}
+
+func (b *binaryReader) Read() (int, error) {
+	n, err := io.ReadFull(b.reader, b.bytes[:])
+	if errors.Is(err, io.ErrUnexpectedEOF) {
+		return 0, fmt.Errorf("%s: the file ends in the middle of an integer (%d bytes out of 8)", b.name, n)
+	}
+	if err != nil {
+		return 0, err
+	}
+	return int(int64(binary.LittleEndian.Uint64(b.bytes[:]))), nil
+}
+
+// textWriter writes one integer per line.
+type textWriter struct {
+	writer *bufio.Writer
+	digits []byte
+}
+
+func (t *textWriter) Write(v int) error {
+	t.digits = strconv.AppendInt(t.digits[:0], int64(v), 10)
+	t.digits = append(t.digits, '\n')
+	_, err := t.writer.Write(t.digits)
+	return err
+}
+
+func (t *textWriter) Flush() error { return t.writer.Flush() }
+
+// binaryWriter writes 64 bits little-endian integers.
+type binaryWriter struct {
+	writer *bufio.Writer
+	bytes  [8]byte
+}
+
+func (b *binaryWriter) Write(v int) error {
+	binary.LittleEndian.PutUint64(b.bytes[:], uint64(int64(v)))
+	_, err := b.writer.Write(b.bytes[:])
+	return err
+}
+
+func (b *binaryWriter) Flush() error { return b.writer.Flush() }
+
+// sortExternally is the external sort mode: it sorts the -in file (or standard input) into the -out file (or standard output).
+// The summary goes to standard output, or to standard error when the sorted values are written there.
+func sortExternally(ctx context.Context, c config, sorter sorting.Sorter, stdin io.Reader, stdout, stderr io.Writer) error {
+	if c.autoMode || c.report || len(c.numbers) > 0 {
+		return cli.Usagef("-external sorts the -in file (or standard input), it can't be used with -auto, -bench-report or numbers")
+	}
+	if !contains(externalFormats, c.external.Format) {
+		return cli.Usagef("unknown format %q (available: %s)", c.external.Format, strings.Join(externalFormats, ", "))
+	}
+	c.external.Workers, c.external.Sorter, c.external.Verify = c.workers, sorter, c.verify
+
+	input, name, close, err := cli.OpenInput(c.Input, stdin)
+	if err != nil {
+		return err
+	}
+	// externalSort closes the input as soon as it has read it, this is for when it fails before. Closing it twice does no harm.
+	defer close()
+	c.external.CloseInput = close
+
+	report, finish := startProgress(c, stderr)
+	c.external.Progress = report
+
+	var stats externalStats
+	summary := stdout
+	if c.output == "" || c.output == "-" {
+		summary = stderr
+		stats, err = externalSort(ctx, input, name, stdout, c.external)
+	} else {
+		stats, err = sortToFile(ctx, input, name, c.output, c.external)
+	}
+	finish()
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case c.JSON:
+		return cli.WriteJSON(summary, stats)
+	case c.Verbose():
+		fmt.Fprintf(summary, "Sorted %d integers: %d sorted runs, merged in %d passes\n", stats.Values, stats.Runs, stats.Passes)
+		if stats.Verification != nil {
+			fmt.Fprintf(summary, "Verified: %v\n", stats.Verification)
+		}
+	}
+	return nil
+}
+
+// sortToFile sorts into a temporary file next to the output, which replaces the output once everything went well:
+// a failed sort does not leave half a file, and the output can be the input itself.
+func sortToFile(ctx context.Context, input io.Reader, name, output string, o externalOptions) (externalStats, error) {
+	file, err := os.CreateTemp(filepath.Dir(output), "."+filepath.Base(output)+"-")
+	if err != nil {
+		return externalStats{}, err
+	}
+	defer os.Remove(file.Name()) // Does nothing once it has been renamed.
+
+	stats, err := externalSort(ctx, input, name, file, o)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return stats, err
+	}
+	return stats, os.Rename(file.Name(), output)
+}
+
+// contains tells if the list holds the value.
+func contains(list []string, value string) bool {
+	for _, v := range list {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// byteSize is a number of bytes given on the command line, like 4096, 512K, 64M or 2G.
+type byteSize int64
+
+func (b *byteSize) String() string {
+	return strconv.FormatInt(int64(*b), 10)
+}
+
+func (b *byteSize) Set(value string) error {
+	multiplier := int64(1)
+	for i, suffix := range []string{"K", "M", "G"} {
+		if strings.HasSuffix(strings.ToUpper(value), suffix) {
+			multiplier = 1 << (10 * (i + 1))
+			value = value[:len(value)-1]
+		}
+	}
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || n < 0 {
+		return errors.New("want a number of bytes, like 4096, 512K, 64M or 2G")
+	}
+	*b = byteSize(n * multiplier)
+	return nil
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"sync"
+)
+
+// Once the parts are sorted, they must be merged. Merging them two by two with merge allocates a new slice at every merge:
+// with 4 parts, the two levels of merges allocate 2N integers, which the garbage collector then has to clean up.
+// At 100M integers, that's 1.6GB of garbage for each sort.
+//
+// A merger does it with a single buffer, as big as the slice, which it keeps for the next sorts: the first level merges the
+// parts of the slice into the buffer, the next one merges them back from the buffer into the slice, and so on (they "ping-pong").
+// If the last level wrote in the buffer, the result is copied back in the slice: the slice is always sorted in place.
+// The merges of a level are all independent, so each one runs in its own goroutine.
+
+// merger merges the sorted parts of slices. Its zero value is ready to use. It must not be used by two sorts at the same time.
+type merger struct {
+	buffer []int // Kept from one merge to the next, and only replaced when a bigger slice comes.
+}
+
+// mergeParts merges the consecutive sorted parts of slice back into slice. ends are the indexes where the parts end:
+// the first part is slice[:ends[0]], the second one slice[ends[0]:ends[1]], and so on, up to ends[len(ends)-1] == len(slice).
+//
+// It stops and returns ctx.Err() when the context is done. The merges already started are finished first, so stopping takes
+// at most the time of one merge, and the slice still holds all its values, in no particular order.
+// If merged is not nil, it is called after each merge with the number of values it merged. It is never called by two goroutines at the same time.
+func (m *merger) mergeParts(ctx context.Context, slice []int, ends []int, merged func(n int)) error {
+	if cap(m.buffer) < len(slice) {
+		m.buffer = make([]int, len(slice))
+	}
+	src, dst := slice, m.buffer[:len(slice)]
+	inBuffer := false // Tells if src is the buffer.
+
+	var mutex sync.Mutex
+	report := func(n int) {
+		if merged != nil {
+			mutex.Lock()
+			merged(n)
+			mutex.Unlock()
+		}
+	}
+
+	for len(ends) > 1 {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		var wg sync.WaitGroup
+		next := make([]int, 0, (len(ends)+1)/2)
+		start := 0
+		for i := 0; i < len(ends); i += 2 {
+			if i+1 == len(ends) {
+				// The last part has no other part to be merged with: it goes to the next level as it is.
+				copy(dst[start:ends[i]], src[start:ends[i]])
+				report(ends[i] - start)
+				next = append(next, ends[i])
+				break
+			}
+			middle, end := ends[i], ends[i+1]
+			wg.Add(1)
+			go func(start, middle, end int) {
+				defer wg.Done()
+				mergeTo(dst[start:end], src[start:middle], src[middle:end])
+				report(end - start)
+			}(start, middle, end)
+			next = append(next, end)
+			start = end
+		}
+		wg.Wait()
+
+		src, dst = dst, src
+		inBuffer = !inBuffer
+		ends = next
+	}
+
+	if inBuffer {
+		copy(slice, src)
+	}
+	return nil
+}
+
+// mergeLevels returns the number of levels of merges needed to merge n parts two by two: every value is merged once per level.
+func mergeLevels(parts int) int {
+	levels := 0
+	for parts > 1 {
+		parts = (parts + 1) / 2
+		levels++
+	}
+	return levels
+}
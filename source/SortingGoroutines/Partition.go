@@ -0,0 +1,26 @@
+package main
+
+// partition cuts the slice in n parts of about the same size: their sizes differ by 1 at most, the bigger ones first.
+// The parts share the memory of the slice, so sorting them in place sorts the pieces of the slice.
+// There are never empty parts: when the slice has fewer than n values, there are only as many parts as values (and one empty part for an empty slice).
+func partition(slice []int, n int) [][]int {
+	if n > len(slice) {
+		n = len(slice)
+	}
+	if n < 1 {
+		n = 1
+	}
+
+	parts := make([][]int, n)
+	size, bigger := len(slice)/n, len(slice)%n
+	start := 0
+	for i := range parts {
+		end := start + size
+		if i < bigger {
+			end++
+		}
+		parts[i] = slice[start:end]
+		start = end
+	}
+	return parts
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Sorting 100M integers takes a while: instead of waiting for the final duration, -progress shows how far the sort is,
+// on standard error (it is on by default when standard error is a terminal). Ctrl-C stops the sort cleanly.
+
+// progress is how far a sort is. The sorts report it as they go, through a progressFunc.
+type progress struct {
+	PartsDone   int   // Parts sorted by their goroutine, or runs written with -external.
+	Parts       int   // Parts to sort, 0 when it is not known in advance (with -external).
+	BytesMerged int64 // Bytes merged so far.
+	Bytes       int64 // Bytes all the merges will have merged at the end.
+}
+
+// progressFunc receives the progress of a sort. The recursive sort calls it from several goroutines at the same time,
+// so it must be safe for that.
+type progressFunc func(progress)
+
+// progressBar prints the progress of a sort on one line, which it redraws every interval.
+type progressBar struct {
+	w        io.Writer
+	mutex    sync.Mutex
+	current  progress
+	start    time.Time
+	stop     chan struct{}
+	finished chan struct{}
+}
+
+// progressInterval is how often the bar is redrawn.
+const progressInterval = 100 * time.Millisecond
+
+// progressWidth is the number of characters of the bar itself.
+const progressWidth = 30
+
+// newProgressBar starts drawing the bar on w, until Finish is called.
+func newProgressBar(w io.Writer) *progressBar {
+	b := &progressBar{w: w, start: time.Now(), stop: make(chan struct{}), finished: make(chan struct{})}
+	go func() {
+		defer close(b.finished)
+		ticker := time.NewTicker(progressInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				b.draw()
+			case <-b.stop:
+				return
+			}
+		}
+	}()
+	return b
+}
+
+// Update is the progressFunc of the bar.
+func (b *progressBar) Update(p progress) {
+	b.mutex.Lock()
+	b.current = p
+	b.mutex.Unlock()
+}
+
+// Finish draws the bar one last time, and goes to the next line.
+func (b *progressBar) Finish() {
+	close(b.stop)
+	<-b.finished
+	b.draw()
+	fmt.Fprintln(b.w)
+}
+
+func (b *progressBar) draw() {
+	b.mutex.Lock()
+	p := b.current
+	b.mutex.Unlock()
+	// \r goes back to the start of the line, so that the new bar is drawn over the old one.
+	fmt.Fprint(b.w, "\r"+formatProgress(p, time.Since(b.start)))
+}
+
+// formatProgress returns the line of the bar: the merge as a bar and a percentage, the parts, and the time spent.
+func formatProgress(p progress, elapsed time.Duration) string {
+	fraction := 0.0
+	if p.Bytes > 0 {
+		fraction = float64(p.BytesMerged) / float64(p.Bytes)
+	}
+	filled := int(fraction * progressWidth)
+	line := fmt.Sprintf("[%s%s] %3.0f%%", strings.Repeat("#", filled), strings.Repeat(".", progressWidth-filled), fraction*100)
+	switch {
+	case p.Parts > 0:
+		line += fmt.Sprintf("  parts %d/%d", p.PartsDone, p.Parts)
+	case p.PartsDone > 0:
+		line += fmt.Sprintf("  runs %d", p.PartsDone)
+	}
+	line += fmt.Sprintf("  merged %s/%s  %v", formatBytes(p.BytesMerged), formatBytes(p.Bytes), elapsed.Round(100*time.Millisecond))
+	return line
+}
+
+// formatBytes returns a number of bytes in KB, MB or GB, like 12.3MB.
+func formatBytes(n int64) string {
+	for _, unit := range []struct {
+		suffix string
+		size   int64
+	}{{"GB", 1 << 30}, {"MB", 1 << 20}, {"KB", 1 << 10}} {
+		if n >= unit.size {
+			return fmt.Sprintf("%.1f%s", float64(n)/float64(unit.size), unit.suffix)
+		}
+	}
+	return fmt.Sprintf("%dB", n)
+}
@@ -0,0 +1,481 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"coursera-go/m/source/cli"
+	"coursera-go/m/source/sorting"
+)
+
+// With -serve, the program sorts for other programs, in any language, over HTTP instead of the command line:
+// go run SortingGoroutines.go -serve -listen localhost:8080 -recursive
+//
+// Then POST the integers to /sort, either as a JSON array:
+// curl -d '[5, 3, 8, 1]' -H 'Content-Type: application/json' localhost:8080/sort
+// or as newline-delimited integers, which come back the same way, streamed one line after the other instead of built in memory first:
+// seq 1000000 | shuf | curl --data-binary @- -H 'Content-Type: application/x-ndjson' localhost:8080/sort
+//
+// The service only listens on localhost: it has no authentication, and anyone able to reach it could make it sort huge slices.
+// Even so, it protects itself: the requests can't be bigger than -max-body, at most -max-sorts sorts run at once (the other
+// requests wait for their turn), and a request that takes longer than -timeout is stopped (its sort keeps its slot until
+// the parts being sorted are done, so that the sorts stopped don't pile up).
+// GET /metrics gives the number of requests, and histograms of the sort durations and sizes, in the text format of Prometheus.
+
+// defaultServeTimeout is the timeout of each request when -timeout is not given: a service must not sort forever.
+const defaultServeTimeout = 30 * time.Second
+
+// The content types of the two formats of /sort. text/plain is accepted for the newline-delimited integers too, as it is
+// what files of numbers usually are. curl -d sends application/x-www-form-urlencoded, which is refused: give -H 'Content-Type: ...'.
+const (
+	jsonType   = "application/json"
+	ndjsonType = "application/x-ndjson"
+)
+
+// errBodyTooLarge is returned when reading a request bigger than -max-body.
+var errBodyTooLarge = errors.New("the request body is too large")
+
+// sortServer is the HTTP handler of the service. Use newSortServer to get one.
+type sortServer struct {
+	config  config
+	sorter  sorting.Sorter
+	timeout time.Duration
+	slots   chan struct{} // One token per sort running: the channel is full when -max-sorts sorts are running.
+	metrics *serverMetrics
+	mux     *http.ServeMux
+}
+
+// newSortServer returns the handler of the service, configured with the command line: the algorithm, the workers, and the limits.
+func newSortServer(c config, sorter sorting.Sorter) *sortServer {
+	s := &sortServer{
+		config:  c,
+		sorter:  sorter,
+		timeout: c.timeout,
+		slots:   make(chan struct{}, c.maxSorts),
+		metrics: newServerMetrics(),
+		mux:     http.NewServeMux(),
+	}
+	if s.timeout == 0 {
+		s.timeout = defaultServeTimeout
+	}
+	s.mux.HandleFunc("/sort", s.handleSort)
+	s.mux.HandleFunc("/metrics", s.handleMetrics)
+	return s
+}
+
+func (s *sortServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// handleSort sorts the integers of the request, and answers them sorted, in the same format.
+func (s *sortServer) handleSort(w http.ResponseWriter, r *http.Request) {
+	// We remember the status of the answer for the metrics.
+	recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+	defer func() { s.metrics.request(recorder.status) }()
+	w = recorder
+
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "POST the integers to sort", http.StatusMethodNotAllowed)
+		return
+	}
+	format, err := requestFormat(r.Header.Get("Content-Type"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnsupportedMediaType)
+		return
+	}
+	if r.ContentLength > s.config.maxBody {
+		http.Error(w, fmt.Sprintf("%v: %d bytes, the limit is %d", errBodyTooLarge, r.ContentLength, s.config.maxBody), http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), s.timeout)
+	defer cancel()
+
+	// The body is read before waiting for a slot: the slots are for the sorts, not for slow clients.
+	slice, err := readIntegers(&limitedReader{r: r.Body, remaining: s.config.maxBody}, format)
+	switch {
+	case errors.Is(err, errBodyTooLarge):
+		http.Error(w, fmt.Sprintf("%v: the limit is %d bytes", err, s.config.maxBody), http.StatusRequestEntityTooLarge)
+		return
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// We wait for one of the -max-sorts slots, but not longer than the timeout of the request.
+	select {
+	case s.slots <- struct{}{}:
+	case <-ctx.Done():
+		s.contextDone(recorder, ctx, "while waiting for the other sorts to finish")
+		return
+	}
+	// The sort runs in its own goroutine, which keeps the slot until the sort is really over: after the timeout, the parts
+	// being sorted are finished first, and they must still count against -max-sorts. The answer does not wait for them.
+	s.metrics.started()
+	done := make(chan sortResult, 1)
+	go func() {
+		defer func() { <-s.slots }()
+		elapsed, err := s.sort(ctx, slice)
+		s.metrics.finished(len(slice), elapsed, err == nil)
+		done <- sortResult{elapsed, err}
+	}()
+	var result sortResult
+	select {
+	case result = <-done:
+	case <-ctx.Done():
+		s.contextDone(recorder, ctx, "while sorting")
+		return
+	}
+	if result.err != nil {
+		if ctx.Err() != nil {
+			s.contextDone(recorder, ctx, "while sorting")
+		} else {
+			http.Error(w, result.err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", format)
+	w.Header().Set("X-Sort-Duration", result.elapsed.String())
+	// Once the answer has started, its status can't be changed anymore: if writing fails, the client went away, and
+	// only gets a truncated answer. The metrics count it as abandoned by the client.
+	if err := writeIntegers(w, slice, format); err != nil {
+		recorder.status = statusClientClosed
+	}
+}
+
+// sortResult is what the goroutine of a sort sends back to its request.
+type sortResult struct {
+	elapsed time.Duration
+	err     error
+}
+
+// sort sorts the slice like the command line does, and checks it with -verify.
+func (s *sortServer) sort(ctx context.Context, slice []int) (elapsed time.Duration, err error) {
+	var before uint64
+	if s.config.verify {
+		before = checksum(slice, s.config.workers)
+	}
+	if s.config.recursive {
+		elapsed, err = sortRecursively(ctx, s.config, slice, io.Discard, nil)
+	} else {
+		elapsed, err = sortInParts(ctx, s.config, s.sorter, slice, io.Discard, nil)
+	}
+	if err == nil && s.config.verify {
+		if v := verify(slice, before, s.config.workers); !v.ok() {
+			err = fmt.Errorf("verification failed: %v", v)
+		}
+	}
+	return elapsed, err
+}
+
+// statusClientClosed is the status counted in the metrics for the requests abandoned by their client, like nginx does.
+// It is never sent: there is nobody to send it to.
+const statusClientClosed = 499
+
+// contextDone answers a request whose context is done: with 503 after the timeout, and nothing when the client went away.
+func (s *sortServer) contextDone(w *statusRecorder, ctx context.Context, when string) {
+	if !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		w.status = statusClientClosed
+		return
+	}
+	w.Header().Set("Retry-After", "1")
+	http.Error(w, fmt.Sprintf("the request took longer than the timeout of %v %s", s.timeout, when), http.StatusServiceUnavailable)
+}
+
+// handleMetrics writes the metrics of the service.
+func (s *sortServer) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		http.Error(w, "GET the metrics", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	s.metrics.writeTo(w)
+}
+
+// requestFormat returns the format of the integers of a request from its content type: JSON when it is not given.
+func requestFormat(contentType string) (string, error) {
+	if contentType == "" {
+		return jsonType, nil
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return "", fmt.Errorf("invalid Content-Type %q", contentType)
+	}
+	switch mediaType {
+	case jsonType:
+		return jsonType, nil
+	case ndjsonType, "text/plain":
+		return ndjsonType, nil
+	}
+	return "", fmt.Errorf("unsupported Content-Type %q: send %s or %s", mediaType, jsonType, ndjsonType)
+}
+
+// readIntegers reads the integers of a request body in the given format.
+func readIntegers(body io.Reader, format string) ([]int, error) {
+	if format == jsonType {
+		slice := []int{}
+		decoder := json.NewDecoder(body)
+		if err := decoder.Decode(&slice); err != nil {
+			if errors.Is(err, errBodyTooLarge) {
+				return nil, err
+			}
+			return nil, fmt.Errorf("the body must be a JSON array of integers: %v", err)
+		}
+		// Decode stops at the end of the array: only spaces may come after it.
+		if _, err := decoder.Token(); err != io.EOF {
+			if errors.Is(err, errBodyTooLarge) {
+				return nil, err
+			}
+			return nil, errors.New("the body must be a JSON array of integers: there is something after the array")
+		}
+		return slice, nil
+	}
+
+	// The newline-delimited integers are read like the text files of -external (spaces are accepted too).
+	slice := []int{}
+	input := newValueReader(body, "body", "text")
+	for {
+		v, err := input.Read()
+		if err == io.EOF {
+			return slice, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		slice = append(slice, v)
+	}
+}
+
+// writeIntegers writes the sorted integers in the given format. The newline-delimited ones are written little by little,
+// so the client can start reading them before they are all written.
+func writeIntegers(w io.Writer, slice []int, format string) error {
+	if format == jsonType {
+		return json.NewEncoder(w).Encode(slice)
+	}
+	output := bufio.NewWriter(w)
+	line := make([]byte, 0, 24)
+	for _, v := range slice {
+		line = strconv.AppendInt(line[:0], int64(v), 10)
+		line = append(line, '\n')
+		if _, err := output.Write(line); err != nil {
+			return err
+		}
+	}
+	return output.Flush()
+}
+
+// limitedReader reads at most remaining bytes, and fails with errBodyTooLarge when there are more.
+// Unlike io.LimitReader, it tells a body that is too large from one that just ends there.
+type limitedReader struct {
+	r         io.Reader
+	remaining int64
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	if l.remaining < 0 {
+		return 0, errBodyTooLarge
+	}
+	// We read one byte more than allowed, to know if the body goes on.
+	if int64(len(p)) > l.remaining+1 {
+		p = p[:l.remaining+1]
+	}
+	n, err := l.r.Read(p)
+	l.remaining -= int64(n)
+	if l.remaining < 0 {
+		return n, errBodyTooLarge
+	}
+	return n, err
+}
+
+// statusRecorder remembers the status written by a handler.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (s *statusRecorder) WriteHeader(status int) {
+	s.status = status
+	s.ResponseWriter.WriteHeader(status)
+}
+
+// serverMetrics counts what the service did. All its methods may be called by several requests at the same time.
+type serverMetrics struct {
+	mutex    sync.Mutex
+	requests map[int]int64 // Requests by status code.
+	running  int
+	elements int64
+	failed   int64
+	duration *histogram // Seconds per sort.
+	sizes    *histogram // Integers per sort.
+}
+
+func newServerMetrics() *serverMetrics {
+	return &serverMetrics{
+		requests: map[int]int64{},
+		duration: newHistogram(0.001, 0.01, 0.1, 1, 10),
+		sizes:    newHistogram(100, 10_000, 1_000_000, 100_000_000),
+	}
+}
+
+func (m *serverMetrics) request(status int) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.requests[status]++
+}
+
+func (m *serverMetrics) started() {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.running++
+}
+
+func (m *serverMetrics) finished(elements int, elapsed time.Duration, ok bool) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.running--
+	if !ok {
+		m.failed++
+		return
+	}
+	m.elements += int64(elements)
+	m.duration.observe(elapsed.Seconds())
+	m.sizes.observe(float64(elements))
+}
+
+// writeTo writes the metrics in the text format of Prometheus, so that it can collect them, though they are easy to read too.
+func (m *serverMetrics) writeTo(w io.Writer) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	fmt.Fprintln(w, "# HELP sort_requests_total Requests to /sort, by status code.")
+	fmt.Fprintln(w, "# TYPE sort_requests_total counter")
+	codes := make([]int, 0, len(m.requests))
+	for code := range m.requests {
+		codes = append(codes, code)
+	}
+	sort.Ints(codes)
+	for _, code := range codes {
+		fmt.Fprintf(w, "sort_requests_total{code=\"%d\"} %d\n", code, m.requests[code])
+	}
+	fmt.Fprintln(w, "# HELP sort_running Sorts running now.")
+	fmt.Fprintln(w, "# TYPE sort_running gauge")
+	fmt.Fprintf(w, "sort_running %d\n", m.running)
+	fmt.Fprintln(w, "# HELP sort_failed_total Sorts stopped by their timeout, or that failed.")
+	fmt.Fprintln(w, "# TYPE sort_failed_total counter")
+	fmt.Fprintf(w, "sort_failed_total %d\n", m.failed)
+	fmt.Fprintln(w, "# HELP sort_elements_total Integers sorted.")
+	fmt.Fprintln(w, "# TYPE sort_elements_total counter")
+	fmt.Fprintf(w, "sort_elements_total %d\n", m.elements)
+	m.duration.writeTo(w, "sort_duration_seconds", "Time taken by each sort, reading and writing the integers not included.")
+	m.sizes.writeTo(w, "sort_elements", "Integers of each sort.")
+}
+
+// histogram counts the values below each of its bounds, like the histograms of Prometheus.
+type histogram struct {
+	bounds []float64
+	counts []int64 // counts[i] is the number of values <= bounds[i].
+	count  int64
+	sum    float64
+}
+
+func newHistogram(bounds ...float64) *histogram {
+	return &histogram{bounds: bounds, counts: make([]int64, len(bounds))}
+}
+
+func (h *histogram) observe(v float64) {
+	for i, bound := range h.bounds {
+		if v <= bound {
+			h.counts[i]++
+		}
+	}
+	h.count++
+	h.sum += v
+}
+
+func (h *histogram) writeTo(w io.Writer, name, help string) {
+	fmt.Fprintf(w, "# HELP %s %s\n", name, help)
+	fmt.Fprintf(w, "# TYPE %s histogram\n", name)
+	for i, bound := range h.bounds {
+		fmt.Fprintf(w, "%s_bucket{le=\"%g\"} %d\n", name, bound, h.counts[i])
+	}
+	fmt.Fprintf(w, "%s_bucket{le=\"+Inf\"} %d\n", name, h.count)
+	fmt.Fprintf(w, "%s_sum %g\n", name, h.sum)
+	fmt.Fprintf(w, "%s_count %d\n", name, h.count)
+}
+
+// serve is the -serve mode: it runs the service on -listen until the context is done (Ctrl-C), then lets the running
+// requests finish before returning.
+func serve(ctx context.Context, c config, sorter sorting.Sorter, stderr io.Writer) error {
+	if c.autoMode || c.report || c.externalMode || len(c.numbers) > 0 || c.Input != "" {
+		return cli.Usagef("-serve sorts the integers sent to it, it can't be used with -auto, -bench-report, -external, -in or numbers")
+	}
+	if c.maxSorts < 1 {
+		return cli.Usagef("-max-sorts must be 1 or more, got %d", c.maxSorts)
+	}
+	if c.maxBody < 1 {
+		return cli.Usagef("-max-body must be 1 byte or more, got %d", c.maxBody)
+	}
+	if !isLocalhost(c.listen) {
+		return cli.Usagef("-listen must be a localhost address, like localhost:8080: the service is not meant to be reached from other machines, got %q", c.listen)
+	}
+
+	listener, err := net.Listen("tcp", c.listen)
+	if err != nil {
+		return err
+	}
+	if c.Verbose() {
+		fmt.Fprintf(stderr, "Sorting on http://%s/sort, metrics on http://%s/metrics (Ctrl-C to stop)\n", listener.Addr(), listener.Addr())
+	}
+	return serveOn(ctx, listener, newSortServer(c, sorter))
+}
+
+// serveOn serves the sort server on the listener until the context is done.
+func serveOn(ctx context.Context, listener net.Listener, s *sortServer) error {
+	server := &http.Server{
+		Handler:           s,
+		ReadHeaderTimeout: 10 * time.Second,
+		// Reading a request can't take longer than the timeout of the request either, so slow clients are stopped too.
+		ReadTimeout: s.timeout,
+		IdleTimeout: time.Minute,
+	}
+	failed := make(chan error, 1)
+	go func() { failed <- server.Serve(listener) }()
+
+	select {
+	case err := <-failed:
+		return err
+	case <-ctx.Done():
+	}
+	// The running requests get a few seconds to finish.
+	shutdown, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return server.Shutdown(shutdown)
+}
+
+// isLocalhost tells if the address, like localhost:8080 or 127.0.0.1:0, is on this machine only.
+func isLocalhost(address string) bool {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return false
+	}
+	if strings.EqualFold(host, "localhost") {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
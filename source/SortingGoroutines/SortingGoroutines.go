@@ -2,14 +2,23 @@ package main
 
 import (
 	"bufio"
+	"context"
+	"errors"
 	"flag"
 	"fmt"
-	"math/rand"
+	"io"
 	"os"
+	"os/signal"
+	"runtime"
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
+
+	"coursera-go/m/source/cli"
+	"coursera-go/m/source/generate"
+	"coursera-go/m/source/sorting"
 )
 
 // ------------------------
@@ -23,164 +32,552 @@ import (
 // and change the default value of the "size" flag to a larger number 100000 for example.
 // For that, you can call the program with the following command:
 // go run SortingGoroutines.go - auto -size=100000
-// On my PC, things even out at about 1000 elements in the slice, and goroutines are faster for larger slices.
-// size=100000000 takes a few seconds to run, I dont recommend going higher than that.
+// The values are random, unless -dist asks for sorted ones, reversed ones, a few values only... (see the generate package),
+// and -seed gives the same slice again.
+// size=100000000 takes a few seconds to run, I dont recommend going higher than that: the whole slice must fit in memory.
+// For more integers than that, -external sorts a file with a fixed amount of memory (see External.go).
+// Big sorts show their progress on a terminal (see Progress.go), Ctrl-C stops them cleanly, and -timeout 30s stops them after 30 seconds.
+// Only the first 50 values are printed: -verify checks all of them, in order and the same as before sorting (see Verify.go).
+// Other programs can use the sort too, over HTTP: -serve turns the program into a small sorting service (see Serve.go).
+// The slice is cut in as many parts as there are CPUs, each sorted by its own goroutine. Try -workers=1 to see what the goroutines bring.
+// With -recursive, it uses a true parallel merge sort instead: each half is sorted by its own goroutine, down to -cutoff integers,
+// with never more than -workers goroutines at once.
+//
+// Rather than guessing from timings by hand, the benchmarks of SortingGoroutines_test.go compare sort.Ints, the slice cut in parts,
+// and the recursive sort, on the same slices, with several numbers of workers: go test -bench . -benchmem
+// -bench-report does the same from the program, several times, with statistics: go run SortingGoroutines.go -bench-report -size 100000
+// Here are the results on random slices, on a machine with a single CPU, where the goroutines can't run at the same time (times per sort):
+//
+//	size        sort.Ints    4 parts     recursive
+//	1000        0.029 ms     0.076 ms    0.090 ms
+//	10000       1.1 ms       1.5 ms      1.6 ms
+//	100000      14 ms        17 ms       22 ms
+//	1000000     156 ms       178 ms      222 ms
+//
+// So with one CPU, sort.Ints always wins: the goroutines only add their cost, and the merges theirs. The 4 parts and the recursive
+// sort only allocate one buffer of the size of the slice, for the merges (see Merge.go). Run the benchmarks on your own machine
+// with several CPUs to see the goroutines win on big slices.
 
 func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
 
-	fmt.Println("Sorting a slice using goroutines and merge sort algorithm")
-	fmt.Println("---------------------------------------------------------")
-	fmt.Println()
+// config holds everything given on the command line.
+type config struct {
+	cli.Options
+	autoMode     bool
+	size         int
+	algorithm    string
+	workers      int             // The number of parts, each sorted by its own goroutine.
+	recursive    bool            // Use the recursive parallel merge sort instead of cutting the slice in parts.
+	cutoff       int             // With recursive, the size under which the insertion sort is used.
+	report       bool            // Print the benchmark report instead of sorting.
+	runs         int             // The number of runs of the benchmark report.
+	generated    generate.Flags  // -dist, -seed, -min and -max, used by -auto and -bench-report.
+	set          map[string]bool // The flags given on the command line.
+	externalMode bool            // Sort a file with the external merge sort instead.
+	external     externalOptions // The limits of the external sort, with -external.
+	output       string          // Where -external writes the sorted values.
+	progress     bool            // Show the progress bar even when standard error is not a terminal.
+	timeout      time.Duration   // Stop the sort after this long, 0 for never.
+	verify       bool            // Check that the result is a sorted permutation of the input (see Verify.go).
+	serveMode    bool            // Sort over HTTP instead (see Serve.go).
+	listen       string          // The address of the service, with -serve.
+	maxBody      int64           // The biggest request of the service, in bytes.
+	maxSorts     int             // The most sorts the service runs at once.
+	numbers      []string        // The numbers given as arguments, if any.
+}
 
-	autoMode := flag.Bool("auto", false, "generate random slice automatically")
-	size := flag.Int("size", 100, "size of the slice to be sorted (only used with -auto flag)")
-	flag.Parse()
+// run is the whole program. It gets the command line arguments and the standard streams, and returns the exit code.
+// Besides the prompt, numbers can be given as arguments or in a file, for example:
+// go run SortingGoroutines.go --quiet 5 3 8 1
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	var c config
+	flags := cli.NewFlagSet("SortingGoroutines", stderr)
+	c.Options.Register(flags)
+	flags.BoolVar(&c.autoMode, "auto", false, "generate random slice automatically")
+	flags.IntVar(&c.size, "size", 100, "size of the slice to be sorted (only used with the -auto and -bench-report flags)")
+	flags.StringVar(&c.algorithm, "algo", "std", "sorting algorithm used by each goroutine ("+strings.Join(sorting.Names(), ", ")+")")
+	flags.IntVar(&c.workers, "workers", runtime.NumCPU(), "number of parts the slice is cut in, each one sorted by its own goroutine (default: the number of CPUs)")
+	flags.BoolVar(&c.recursive, "recursive", false, "sort with a recursive parallel merge sort, with at most -workers goroutines at once, instead of cutting the slice in parts")
+	flags.IntVar(&c.cutoff, "cutoff", 0, "with -recursive, the size under which parts are sorted with the insertion sort (0: the one of the merge sort)")
+	flags.BoolVar(&c.report, "bench-report", false, "compare sort.Ints and the goroutines on slices of -size integers, several times, and print statistics instead of sorting")
+	flags.IntVar(&c.runs, "runs", 10, "number of runs of -bench-report")
+	c.generated.Register(flags)
+	flags.BoolVar(&c.externalMode, "external", false, "sort the -in file (or standard input) with an external merge sort, for more integers than the memory can hold")
+	flags.StringVar(&c.output, "out", "-", "with -external, the file to write the sorted integers to (- for standard output)")
+	flags.StringVar(&c.external.Format, "format", "text", "with -external, the format of the files: text, or binary for 64 bits little-endian integers")
+	c.external.Memory = 64 << 20
+	flags.Var((*byteSize)(&c.external.Memory), "memory", "with -external, the memory used to sort, like 512K, 64M or 2G")
+	flags.IntVar(&c.external.MaxOpen, "max-open", 64, "with -external, the most files open at once while merging")
+	flags.StringVar(&c.external.TempDir, "temp", "", "with -external, where to write the temporary files (default: the temporary directory of the system)")
+	flags.BoolVar(&c.progress, "progress", false, "show the progress of the sort on standard error (default: when it is a terminal, without --quiet or --json)")
+	flags.DurationVar(&c.timeout, "timeout", 0, "stop the sort if it takes longer than this, like 30s or 5m (default: no limit)")
+	flags.BoolVar(&c.verify, "verify", false, "check that the sorted values are in order and the same as before sorting, and fail if they are not")
+	flags.BoolVar(&c.serveMode, "serve", false, "sort the integers POSTed to /sort over HTTP, as a JSON array or one per line, until Ctrl-C")
+	flags.StringVar(&c.listen, "listen", "localhost:8080", "with -serve, the address to listen on, which must be on localhost")
+	c.maxBody = 64 << 20
+	flags.Var((*byteSize)(&c.maxBody), "max-body", "with -serve, the biggest request accepted, like 512K or 64M")
+	flags.IntVar(&c.maxSorts, "max-sorts", runtime.NumCPU(), "with -serve, the most sorts running at once, the other requests wait (default: the number of CPUs)")
+	if ok, code := cli.Parse(flags, args); !ok {
+		return code
+	}
+	c.numbers = flags.Args()
+	c.set = map[string]bool{}
+	flags.Visit(func(f *flag.Flag) { c.set[f.Name] = true })
 
-	var slice []int
+	if c.timeout < 0 {
+		return c.Report(stderr, "SortingGoroutines", cli.Usagef("-timeout can't be negative, got %v", c.timeout))
+	}
 
-	if !*autoMode {
-		// Prompt user for input - This is the standard case for this program, as required by the assignement
-		fmt.Println("Please enter integers to sort, separated by spaces:")
-		reader := bufio.NewReader(os.Stdin)
-		input, _ := reader.ReadString('\n')
-		input = strings.TrimSpace(input)
-		inputs := strings.Split(input, " ")
+	// Ctrl-C cancels the context: the sort stops, cleans up behind it (the temporary files of -external), and we exit with an error.
+	// A second Ctrl-C kills the program right away, as usual, in case stopping takes too long.
+	interrupted, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	go func() {
+		<-interrupted.Done()
+		stop()
+	}()
+	ctx := interrupted
+	// With -serve, the timeout is the one of each request, not of the whole service.
+	if c.timeout > 0 && !c.serveMode {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+	}
 
-		for _, s := range inputs {
-			num, err := strconv.Atoi(s)
-			if err == nil {
-				slice = append(slice, num)
-			} else {
-				fmt.Printf("'%s' is not a valid integer and will be skipped.\n", s)
+	err := sortSlice(ctx, c, stdin, stdout, stderr)
+	switch {
+	case errors.Is(err, context.Canceled):
+		err = fmt.Errorf("interrupted before the end of the sort: %w", err)
+	case errors.Is(err, context.DeadlineExceeded):
+		err = fmt.Errorf("the sort took longer than the -timeout of %v: %w", c.timeout, err)
+	}
+	return c.Report(stderr, "SortingGoroutines", err)
+}
+
+// showProgress tells if the progress bar must be drawn on stderr: when asked with -progress, or when a person is watching it.
+func showProgress(c config, stderr io.Writer) bool {
+	if c.progress {
+		return true
+	}
+	// IsTerminal takes a reader, but only looks at the file behind it.
+	f, ok := stderr.(*os.File)
+	return ok && c.Verbose() && cli.IsTerminal(f)
+}
+
+// startProgress starts the progress bar on stderr if it must be shown. It returns the function the sort reports its progress to
+// (nil without a bar), and the one to call once the sort is over.
+func startProgress(c config, stderr io.Writer) (progressFunc, func()) {
+	if !showProgress(c, stderr) {
+		return nil, func() {}
+	}
+	bar := newProgressBar(stderr)
+	return bar.Update, bar.Finish
+}
+
+// sortSlice gets the slice, sorts it with goroutines and prints the result, as configured on the command line.
+// The sort stops and returns ctx.Err() when the context is done.
+func sortSlice(ctx context.Context, c config, stdin io.Reader, stdout, stderr io.Writer) error {
+
+	sorter, err := sorting.Lookup(c.algorithm)
+	if err != nil {
+		return &cli.UsageError{Err: err}
+	}
+	if (c.autoMode || c.report) && c.size < 0 {
+		return cli.Usagef("-size must be 0 or more, got %d", c.size)
+	}
+	if c.workers < 1 {
+		return cli.Usagef("-workers must be 1 or more, got %d", c.workers)
+	}
+	if c.cutoff < 0 {
+		return cli.Usagef("-cutoff can't be negative, got %d", c.cutoff)
+	}
+	generating := c.set["dist"] || c.set["seed"] || c.set["min"] || c.set["max"]
+	if generating && !c.autoMode && !c.report {
+		return cli.Usagef("-dist, -seed, -min and -max are only used with -auto and -bench-report")
+	}
+	if c.serveMode {
+		return serve(ctx, c, sorter, stderr)
+	}
+	if c.externalMode {
+		return sortExternally(ctx, c, sorter, stdin, stdout, stderr)
+	}
+	if c.report {
+		if c.autoMode || len(c.numbers) > 0 || c.Input != "" {
+			return cli.Usagef("-bench-report sorts generated slices of -size integers, it can't be given numbers or -auto")
+		}
+		// Without -dist, the report compares the contenders on several distributions.
+		generators := benchGenerators(c.size)
+		if generating {
+			generator, err := c.generated.Generator()
+			if err != nil {
+				return &cli.UsageError{Err: err}
 			}
+			generators = []generate.Generator{generator}
+		}
+		return benchReport(ctx, c, sorter, generators, stdout)
+	}
+
+	// messages is where we print everything that is not the result: it is thrown away with --quiet or --json.
+	messages := io.Discard
+	if c.Verbose() {
+		messages = stdout
+	}
+
+	fmt.Fprintln(messages, "Sorting a slice using goroutines and merge sort algorithm")
+	fmt.Fprintln(messages, "---------------------------------------------------------")
+	fmt.Fprintln(messages)
+
+	var slice []int
+
+	if !c.autoMode {
+		// Get the numbers from the user - This is the standard case for this program, as required by the assignement
+		slice, err = getNumbers(c, stdin, stdout)
+		if err != nil {
+			return err
 		}
 	} else {
-		// Here we are in auto mode, we'll generate a random slice of integers
-		rand.Seed(time.Now().UnixNano())
-		// Generate random numbers
-		slice = make([]int, *size)
-		randomise(slice)
+		// Here we are in auto mode, we'll generate a slice of integers (random ones unless -dist says otherwise)
+		generator, err := c.generated.Generator()
+		if err != nil {
+			return &cli.UsageError{Err: err}
+		}
+		slice = generator.Generate(c.size)
+		fmt.Fprintf(messages, "Generated %d integers, distribution %s, seed %d (use -seed %d to get the same slice again)\n",
+			c.size, generator, generator.Seed, generator.Seed)
+		fmt.Fprintln(messages)
+	}
+
+	printSlice(messages, "Unsorted slice", slice)
 
+	// The slice is sorted in place by some algorithms: we keep the unsorted values to sort them again with sort.Ints in auto mode.
+	var unsorted []int
+	if c.autoMode && c.Verbose() {
+		unsorted = append([]int{}, slice...)
 	}
 
-	printSlice("Unsorted slice", slice)
+	// The checksum must be taken before sorting: the parts and the recursive sort sort the slice in place.
+	var before uint64
+	if c.verify {
+		before = checksum(slice, c.workers)
+	}
 
-	// well divide the slice in 4 parts (about the same size, 4th part might be a bit bigger or smaller if the slice size is not a multiple of 4)
+	report, finish := startProgress(c, stderr)
+	var elapsed time.Duration
+	if c.recursive {
+		elapsed, err = sortRecursively(ctx, c, slice, messages, report)
+	} else {
+		elapsed, err = sortInParts(ctx, c, sorter, slice, messages, report)
+	}
+	finish()
+	if err != nil {
+		return err
+	}
 
-	fmt.Println("Dividing the slice in 4 parts")
-	fmt.Println()
+	printSlice(messages, "Sorted slice", slice)
 
-	quarter := len(slice) / 4
+	var verified *verification
+	if c.verify {
+		v := verify(slice, before, c.workers)
+		if !v.ok() {
+			return fmt.Errorf("verification failed: %v", v)
+		}
+		fmt.Fprintf(messages, "Verified: %v\n", v)
+		fmt.Fprintln(messages)
+		verified = &v
+	}
 
-	slice1, slice2, slice3, slice4 := slice[:quarter], slice[quarter:quarter*2], slice[quarter*2:quarter*3], slice[quarter*3:]
+	switch {
+	case c.JSON:
+		return cli.WriteJSON(stdout, struct {
+			Sorted             []int         `json:"sorted"`
+			ElapsedNanoseconds int64         `json:"elapsedNanoseconds"`
+			Verification       *verification `json:"verification,omitempty"`
+		}{slice, elapsed.Nanoseconds(), verified})
+	case c.Quiet:
+		fmt.Fprintln(stdout, strings.Trim(fmt.Sprint(slice), "[]"))
+	}
 
-	// we'll create 4 channels to send the sorted slices
-	chans := make([]chan []int, 4)
-	for i := range chans {
-		chans[i] = make(chan []int)
+	if c.autoMode && c.Verbose() {
+		//Now, just for fun, let's sort the same slice using the regular sort function
+		// This is only done in auto mode as the results would not be relevant if the user entered the numbers themselves
+		// One run says little about which one is faster: -bench-report runs them many times and gives proper statistics.
+
+		noGoroutinesSlice := unsorted
+
+		// Start timer for regular functions
+		start := time.Now()
+
+		// sort the slice using the regular sort function
+
+		sort.Ints(noGoroutinesSlice)
+		elapsedStandard := time.Since(start)
+
+		printSlice(messages, "Sorted slice using the regular sort function", noGoroutinesSlice)
+
+		fmt.Fprintln(messages, "Time taken to sort the slice using the regular sort function :", elapsedStandard)
+		fmt.Fprintln(messages)
+
+		comparePerformance(messages, elapsed, elapsedStandard)
+	}
+	return nil
+}
+
+// sortRecursively sorts the slice in place with the recursive parallel merge sort, and returns the time it took.
+func sortRecursively(ctx context.Context, c config, slice []int, messages io.Writer, report progressFunc) (time.Duration, error) {
+	fmt.Fprintf(messages, "Sorting with a recursive merge sort, with up to %d goroutines at once\n", c.workers)
+	fmt.Fprintln(messages)
+
+	// The merges of the recursive sort are all reported as bytes merged: there are no parts.
+	var merged func(n, total int)
+	if report != nil {
+		merged = func(n, total int) { report(progress{BytesMerged: 8 * int64(n), Bytes: 8 * int64(total)}) }
 	}
 
-	// Start timer for regular functions
 	start := time.Now()
+	err := sorting.ParallelMergeSortContext(ctx, slice, c.cutoff, c.workers, merged)
+	elapsed := time.Since(start)
+	if err != nil {
+		return elapsed, err
+	}
+
+	fmt.Fprintln(messages, "Time taken to sort the slice :", elapsed)
+	fmt.Fprintln(messages)
+	return elapsed, nil
+}
 
-	// we'll create 4 goroutines to sort each slice
-	go Sort(slice1, chans[0])
-	go Sort(slice2, chans[1])
-	go Sort(slice3, chans[2])
-	go Sort(slice4, chans[3])
+// sortInParts cuts the slice in parts, sorts each one in its own goroutine and merges them back in the slice.
+// It returns the time it took.
+func sortInParts(ctx context.Context, c config, sorter sorting.Sorter, slice []int, messages io.Writer, report progressFunc) (time.Duration, error) {
+	// we'll divide the slice in as many parts as there are workers (about the same size, the first ones might have one more value),
+	// but never in more parts than there are values: an empty part would only cost a goroutine for nothing.
 
-	// we'll merge the 4 sorted slices into a new slice
+	parts := partition(slice, c.workers)
+	fmt.Fprintf(messages, "Dividing the slice in %d parts\n", len(parts))
+	fmt.Fprintln(messages)
 
-	slice = merge(merge(<-chans[0], <-chans[1]),
-		merge(<-chans[2], <-chans[3]))
+	for i, part := range parts {
+		printSlice(messages, fmt.Sprintf("Part %d, to be sorted in a GOROUTINE - Unsorted part of the slice", i+1), part)
+	}
 
+	// Start timer for regular functions
+	start := time.Now()
+	err := splitSortContext(ctx, sorter, parts, &merger{}, report)
 	elapsed := time.Since(start)
-	fmt.Println("Time taken to sort the 4 parts and merge them :", elapsed)
-	fmt.Println()
+	if err != nil {
+		return elapsed, err
+	}
 
-	printSlice("Sorted slice", slice)
+	fmt.Fprintf(messages, "Time taken to sort the %d parts and merge them : %v\n", len(parts), elapsed)
+	fmt.Fprintln(messages)
+	return elapsed, nil
+}
 
-	if *autoMode {
-		//Now, just for fun, let's sort the slice using the regular sort function
-		// This is only done in auto mode as the results would not be relevant if the user entered the numbers themselves
+// splitSort sorts each part in its own goroutine, with the given algorithm, and merges them with the merger.
+// The parts must be the consecutive parts of a slice, as partition gives them: the slice is sorted in place.
+func splitSort(sorter sorting.Sorter, parts [][]int, m *merger) {
+	splitSortContext(context.Background(), sorter, parts, m, nil)
+}
 
-		noGoroutinesSlice := make([]int, *size)
-		randomise(noGoroutinesSlice)
+// splitSortContext is splitSort, which stops and returns ctx.Err() when the context is done, and reports its progress if report is not nil.
+// A part being sorted can't be stopped halfway: it waits for the goroutines to finish their part before returning, so that
+// nothing writes in the slice anymore once it has returned. Stopping takes at most the time to sort one part.
+func splitSortContext(ctx context.Context, sorter sorting.Sorter, parts [][]int, m *merger, report progressFunc) error {
+	// ends are where the parts end in the slice they were cut from, and slice is that whole slice.
+	ends := make([]int, len(parts))
+	total := 0
+	for i, part := range parts {
+		total += len(part)
+		ends[i] = total
+	}
+	var slice []int
+	if len(parts) > 0 {
+		slice = parts[0][:total]
+	}
+	// Every value is merged once per level of merges.
+	current := progress{Parts: len(parts), Bytes: 8 * int64(total) * int64(mergeLevels(len(parts)))}
+	if report != nil {
+		report(current)
+	}
 
-		printSlice("Unsorted randomised slice for the process without Goroutines", noGoroutinesSlice)
+	// we'll create one channel per part to send the sorted parts
+	chans := make([]chan []int, len(parts))
+	for i := range chans {
+		chans[i] = make(chan []int)
+	}
 
-		// Start timer for regular functions
-		start = time.Now()
+	// we'll create one goroutine to sort each part
+	var wg sync.WaitGroup
+	for i, part := range parts {
+		wg.Add(1)
+		go func(part []int, c chan<- []int) {
+			defer wg.Done()
+			SortWithContext(ctx, sorter, part, c)
+		}(part, chans[i])
+	}
 
-		// sort the slice using the regular sort function
+	// we'll wait for all the sorted parts, unless the context is done first: then we wait for the goroutines to end
+	for i := range chans {
+		select {
+		case <-chans[i]:
+		case <-ctx.Done():
+			wg.Wait()
+			return ctx.Err()
+		}
+		current.PartsDone++
+		if report != nil {
+			report(current)
+		}
+	}
 
-		sort.Ints(noGoroutinesSlice)
-		elapsedStandard := time.Since(start)
+	// and merge them back in the slice, two by two (see Merge.go)
+	var merged func(n int)
+	if report != nil {
+		merged = func(n int) {
+			current.BytesMerged += 8 * int64(n)
+			report(current)
+		}
+	}
+	return m.mergeParts(ctx, slice, ends, merged)
+}
 
-		printSlice("Sorted slice using the regular sort function", noGoroutinesSlice)
+// getNumbers gets the numbers to sort from the arguments, the -in file, or standard input.
+// When standard input is the terminal (and we are not in batch mode), we prompt the user for one line and skip what is not a number.
+// Otherwise, something that is not a number is an error, as nobody is there to see that it was skipped.
+func getNumbers(c config, stdin io.Reader, stdout io.Writer) (slice []int, err error) {
+	if len(c.numbers) > 0 {
+		return parseNumbers(c.numbers, "argument")
+	}
 
-		fmt.Println("Time taken to sort the slice using the regular sort function :", elapsedStandard)
-		fmt.Println()
+	if c.Input == "" && !c.Batch && cli.IsTerminal(stdin) {
+		fmt.Fprintln(stdout, "Please enter integers to sort, separated by spaces:")
+		reader := bufio.NewReader(stdin)
+		input, _ := reader.ReadString('\n')
+		input = strings.TrimSpace(input)
+		inputs := strings.Split(input, " ")
 
-		comparePerformance(elapsed, elapsedStandard)
+		for _, s := range inputs {
+			num, err := strconv.Atoi(s)
+			if err == nil {
+				slice = append(slice, num)
+			} else {
+				fmt.Fprintf(stdout, "'%s' is not a valid integer and will be skipped.\n", s)
+			}
+		}
+		return slice, nil
 	}
 
+	input, name, close, err := cli.OpenInput(c.Input, stdin)
+	if err != nil {
+		return nil, err
+	}
+	defer close()
+
+	scanner := bufio.NewScanner(input)
+	line := 0
+	for scanner.Scan() {
+		line++
+		numbers, err := parseNumbers(strings.Fields(scanner.Text()), fmt.Sprintf("%s:%d", name, line))
+		if err != nil {
+			return nil, err
+		}
+		slice = append(slice, numbers...)
+	}
+	return slice, scanner.Err()
 }
 
-func randomise(slice []int) {
-	for i := 0; i < len(slice); i++ {
-		slice[i] = rand.Intn(1000)
+// parseNumbers converts strings to integers. The error says where the bad one comes from.
+func parseNumbers(strs []string, where string) ([]int, error) {
+	numbers := make([]int, len(strs))
+	for i, s := range strs {
+		num, err := strconv.Atoi(s)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %q is not a valid integer", where, s)
+		}
+		numbers[i] = num
 	}
+	return numbers, nil
 }
 
-func printSlice(message string, slice []int) {
+func printSlice(w io.Writer, message string, slice []int) {
 	if len(slice) > 50 {
-		fmt.Printf("%s (%d integers in total, just showin the 50 first): %v\n", message, len(slice), slice[:50])
+		fmt.Fprintf(w, "%s (%d integers in total, just showin the 50 first): %v\n", message, len(slice), slice[:50])
 	} else {
-		fmt.Printf("%s (%d integers): %v\n", message, len(slice), slice)
+		fmt.Fprintf(w, "%s (%d integers): %v\n", message, len(slice), slice)
 	}
-	fmt.Println()
+	fmt.Fprintln(w)
 }
 
+// Sort sorts the slice with sort.Ints and sends it on the channel once it is sorted.
+// Like the rest of this program, it only sorts integers: sorting.ParallelSort sorts slices of any type in parallel,
+// and sorting.SortByKey sorts records (structs read from a CSV file, for example) by one or more of their fields.
 func Sort(slice []int, c chan []int) {
 
 	// "My" Sort function just calls the regular sort function implemented in the sort package, and puts the result in the channel
 	// This is just to show how to use channels to send data between goroutines, and so that the timing is not polluted by the time taken to sort the slice (which would not be optimised if I do it myself)
 
-	printSlice("In a GOROUTINE - Unsorted part of the slice", slice)
+	SortWith(sorting.Standard{}, slice, c)
+}
+
+// SortWith does the same as Sort, but with any algorithm of the sorting package (chosen with the -algo flag).
+// It does not print the part it sorts anymore: the parts are printed by the caller before the goroutines start,
+// so that nothing is printed in --quiet mode and the messages don't get mixed up.
+func SortWith(sorter sorting.Sorter, slice []int, c chan []int) {
 
-	sort.Ints(slice)
+	sorter.Sort(slice)
 
 	c <- slice
 }
 
+// SortWithContext does the same as SortWith, but gives up when the context is done: it does not start sorting,
+// or does not wait for someone to take the sorted slice from the channel anymore, so that its goroutine always ends.
+func SortWithContext(ctx context.Context, sorter sorting.Sorter, slice []int, c chan<- []int) {
+	if ctx.Err() != nil {
+		return
+	}
+
+	sorter.Sort(slice)
+
+	select {
+	case c <- slice:
+	case <-ctx.Done():
+	}
+}
+
 // merge merges two slices of integers into a new slice
 func merge(s1 []int, s2 []int) (s []int) {
 
 	s = make([]int, len(s1)+len(s2))
+	mergeTo(s, s1, s2)
+	return s
+}
+
+// mergeTo merges two sorted slices of integers into dst, which must be exactly as long as both of them, without allocating anything.
+// When two values are equal, the one of s1 goes first.
+func mergeTo(dst, s1, s2 []int) {
 
 	i, j := 0, 0
 
 	for i < len(s1) && j < len(s2) {
 
-		if s1[i] < s2[j] {
-			s[i+j] = s1[i]
-			i++
-		} else {
-			s[i+j] = s2[j]
+		if s2[j] < s1[i] {
+			dst[i+j] = s2[j]
 			j++
+		} else {
+			dst[i+j] = s1[i]
+			i++
 		}
 	}
 	if i == len(s1) {
-		copy(s[i+j:], s2[j:])
+		copy(dst[i+j:], s2[j:])
 	} else {
-		copy(s[i+j:], s1[i:])
+		copy(dst[i+j:], s1[i:])
 	}
-	return s
 }
 
 // comparePerformance compares the performance of the two algorithms and prints a message
-func comparePerformance(elapsed1, elapsed2 time.Duration) {
+func comparePerformance(w io.Writer, elapsed1, elapsed2 time.Duration) {
 	elapsed1Millis := float64(elapsed1) / float64(time.Millisecond)
 	elapsed2Millis := float64(elapsed2) / float64(time.Millisecond)
 
@@ -195,5 +592,5 @@ func comparePerformance(elapsed1, elapsed2 time.Duration) {
 		message = fmt.Sprintf("Simple algorithm was faster by %.2f%%", difference)
 	}
 
-	fmt.Println(message)
+	fmt.Fprintln(w, message)
 }
@@ -1,8 +1,33 @@
 package main
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"reflect"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
+
+	"coursera-go/m/source/cli"
+	"coursera-go/m/source/cli/golden"
+	"coursera-go/m/source/sorting"
 )
 
 func TestSort(t *testing.T) {
@@ -52,3 +77,1168 @@ func TestMerge(t *testing.T) {
 		})
 	}
 }
+
+func TestPartition(t *testing.T) {
+	tests := []struct {
+		name      string
+		size, n   int
+		wantSizes []int
+	}{
+		{name: "multiple of n", size: 8, n: 4, wantSizes: []int{2, 2, 2, 2}},
+		{name: "not a multiple of n", size: 10, n: 4, wantSizes: []int{3, 3, 2, 2}},
+		{name: "one part", size: 5, n: 1, wantSizes: []int{5}},
+		{name: "shorter than n", size: 3, n: 8, wantSizes: []int{1, 1, 1}},
+		{name: "empty", size: 0, n: 8, wantSizes: []int{0}},
+		{name: "n of 0", size: 3, n: 0, wantSizes: []int{3}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			slice := make([]int, tt.size)
+			for i := range slice {
+				slice[i] = i
+			}
+			parts := partition(slice, tt.n)
+
+			sizes := make([]int, len(parts))
+			var joined []int
+			for i, part := range parts {
+				sizes[i] = len(part)
+				joined = append(joined, part...)
+			}
+			if !reflect.DeepEqual(sizes, tt.wantSizes) {
+				t.Errorf("partition of %d values in %d: sizes %v; want %v", tt.size, tt.n, sizes, tt.wantSizes)
+			}
+			// The parts, one after the other, are the slice.
+			if len(joined) != len(slice) || (len(slice) > 0 && !reflect.DeepEqual(joined, slice)) {
+				t.Errorf("the parts %v don't cover the slice %v", parts, slice)
+			}
+		})
+	}
+}
+
+func TestMergeParts(t *testing.T) {
+	tests := []struct {
+		name     string
+		parts    [][]int
+		expected []int
+	}{
+		{name: "no part", parts: nil, expected: []int{}},
+		{name: "one part", parts: [][]int{{1, 2, 3}}, expected: []int{1, 2, 3}},
+		{name: "two parts", parts: [][]int{{1, 3}, {2, 4}}, expected: []int{1, 2, 3, 4}},
+		{name: "many parts", parts: [][]int{{5, 9}, {1, 2, 8}, {3}, {4, 6, 7}, {0}}, expected: []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}},
+		{name: "empty parts", parts: [][]int{{}, {2}, {}, {1, 3}, {}}, expected: []int{1, 2, 3}},
+		{name: "all empty", parts: [][]int{{}, {}, {}}, expected: []int{}},
+		{name: "duplicates", parts: [][]int{{1, 1, 2}, {1, 2}, {2, 2}}, expected: []int{1, 1, 1, 2, 2, 2, 2}},
+	}
+
+	// All the cases share the merger, so its buffer is reused for slices of different sizes.
+	var m merger
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The parts are put one after the other in a slice, where mergeParts merges them.
+			slice := []int{}
+			var ends []int
+			for _, part := range tt.parts {
+				slice = append(slice, part...)
+				ends = append(ends, len(slice))
+			}
+			if err := m.mergeParts(context.Background(), slice, ends, nil); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(slice, tt.expected) {
+				t.Errorf("Merging %v: expected %v, but got %v", tt.parts, tt.expected, slice)
+			}
+		})
+	}
+}
+
+func TestMergePartsContext(t *testing.T) {
+	random := rand.New(rand.NewSource(2))
+	slice := make([]int, 10_000)
+	for i := range slice {
+		slice[i] = random.Intn(1000)
+	}
+	parts := partition(slice, 5)
+	ends := make([]int, len(parts))
+	for i, part := range parts {
+		sort.Ints(part)
+		ends[i] = len(part)
+		if i > 0 {
+			ends[i] += ends[i-1]
+		}
+	}
+	unsorted := append([]int{}, slice...)
+
+	// Once the context is cancelled, nothing is merged, and the slice keeps all its values.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	var m merger
+	if err := m.mergeParts(ctx, slice, ends, nil); !errors.Is(err, context.Canceled) {
+		t.Errorf("cancelled: got error %v; want %v", err, context.Canceled)
+	}
+	if !reflect.DeepEqual(slice, unsorted) {
+		t.Error("the cancelled merge changed the slice")
+	}
+
+	// Every value is merged once per level: 5 parts take 3 levels.
+	total := 0
+	if err := m.mergeParts(context.Background(), slice, ends, func(n int) { total += n }); err != nil {
+		t.Fatal(err)
+	}
+	if !sort.IntsAreSorted(slice) {
+		t.Error("the slice is not sorted")
+	}
+	if want := 3 * len(slice); total != want || mergeLevels(5) != 3 {
+		t.Errorf("got %d values merged and %d levels; want %d and 3", total, mergeLevels(5), want)
+	}
+
+	// The next merges reuse the buffer, as long as the slices are not bigger.
+	buffer := &m.buffer[0]
+	copy(slice, unsorted)
+	m.mergeParts(context.Background(), slice[:ends[1]], ends[:2], nil)
+	if &m.buffer[0] != buffer {
+		t.Error("the buffer was allocated again")
+	}
+}
+
+func TestMergeLevels(t *testing.T) {
+	for parts, want := range map[int]int{0: 0, 1: 0, 2: 1, 3: 2, 4: 2, 5: 3, 8: 3, 9: 4, 64: 6} {
+		if got := mergeLevels(parts); got != want {
+			t.Errorf("mergeLevels(%d) = %d; want %d", parts, got, want)
+		}
+	}
+}
+
+func TestSplitSortContext(t *testing.T) {
+	slice := []int{3, 1, 2, 9, 7, 8, 5, 4, 6}
+	var last progress
+	err := splitSortContext(context.Background(), sorting.Standard{}, partition(slice, 3), &merger{}, func(p progress) { last = p })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []int{1, 2, 3, 4, 5, 6, 7, 8, 9}; !reflect.DeepEqual(slice, want) {
+		t.Errorf("got %v; want %v", slice, want)
+	}
+	// 3 parts take 2 levels of merges.
+	if want := (progress{PartsDone: 3, Parts: 3, BytesMerged: 144, Bytes: 144}); last != want {
+		t.Errorf("got the progress %+v at the end; want %+v", last, want)
+	}
+
+	// Once the context is cancelled, the sort returns right away, and the goroutines don't wait for anyone to take their part.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	before := runtime.NumGoroutine()
+	if err := splitSortContext(ctx, sorting.Standard{}, partition(slice, 3), &merger{}, nil); !errors.Is(err, context.Canceled) {
+		t.Errorf("cancelled: got error %v; want %v", err, context.Canceled)
+	}
+	for i := 0; i < 100 && runtime.NumGoroutine() > before; i++ {
+		time.Sleep(time.Millisecond)
+	}
+	if after := runtime.NumGoroutine(); after > before {
+		t.Errorf("%d goroutines were left running", after-before)
+	}
+
+	// Cancelled while the parts are being sorted: it waits for them, so nothing writes in the slice once it has returned.
+	ctx, cancel = context.WithCancel(context.Background())
+	sorter := &slowSorter{Sorter: sorting.Standard{}, started: make(chan struct{}, 3), release: make(chan struct{})}
+	stopped := make(chan error, 1)
+	go func() { stopped <- splitSortContext(ctx, sorter, partition(slice, 3), &merger{}, nil) }()
+	for i := 0; i < 3; i++ {
+		<-sorter.started
+	}
+	cancel()
+	select {
+	case err := <-stopped:
+		t.Fatalf("returned %v while a part was still being sorted", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(sorter.release)
+	if err := <-stopped; !errors.Is(err, context.Canceled) {
+		t.Errorf("cancelled while sorting: got error %v; want %v", err, context.Canceled)
+	}
+	if sorted := atomic.LoadInt32(&sorter.sorted); sorted != 3 {
+		t.Errorf("returned after %d parts out of 3 were sorted", sorted)
+	}
+}
+
+// slowSorter tells when it starts sorting a part, and does not sort it before release is closed.
+type slowSorter struct {
+	sorting.Sorter
+	started chan struct{}
+	release chan struct{}
+	sorted  int32 // Parts sorted, updated atomically.
+}
+
+func (s *slowSorter) Sort(slice []int) {
+	s.started <- struct{}{}
+	<-s.release
+	s.Sorter.Sort(slice)
+	atomic.AddInt32(&s.sorted, 1)
+}
+
+func TestFormatProgress(t *testing.T) {
+	tests := []struct {
+		p    progress
+		want string
+	}{
+		{progress{}, "[..............................]   0%  merged 0B/0B  0s"},
+		{progress{PartsDone: 2, Parts: 4, BytesMerged: 0, Bytes: 8 << 20}, "[..............................]   0%  parts 2/4  merged 0B/8.0MB  0s"},
+		{progress{PartsDone: 4, Parts: 4, BytesMerged: 4 << 20, Bytes: 8 << 20}, "[###############...............]  50%  parts 4/4  merged 4.0MB/8.0MB  0s"},
+		{progress{PartsDone: 12, BytesMerged: 3 << 30, Bytes: 3 << 30}, "[##############################] 100%  runs 12  merged 3.0GB/3.0GB  0s"},
+		{progress{BytesMerged: 1536, Bytes: 2048}, "[######################........]  75%  merged 1.5KB/2.0KB  0s"},
+	}
+	for _, tt := range tests {
+		if got := formatProgress(tt.p, 0); got != tt.want {
+			t.Errorf("formatProgress(%+v):\ngot  %q\nwant %q", tt.p, got, tt.want)
+		}
+	}
+	if got := formatProgress(progress{}, 1234*time.Millisecond); !strings.HasSuffix(got, "  1.2s") {
+		t.Errorf("the elapsed time is not rounded to a tenth of a second: %q", got)
+	}
+}
+
+// TestSortSliceWorkers sorts random slices with every number of workers from 1 to more than there are values.
+func TestSortSliceWorkers(t *testing.T) {
+	random := rand.New(rand.NewSource(1))
+	for _, size := range []int{0, 1, 5, 100} {
+		numbers := make([]string, size)
+		want := make([]int, size)
+		for i := range numbers {
+			want[i] = random.Intn(50) - 25
+			numbers[i] = strconv.Itoa(want[i])
+		}
+		sort.Ints(want)
+
+		for _, recursive := range []bool{false, true} {
+			for workers := 1; workers <= 12; workers++ {
+				var stdout bytes.Buffer
+				c := config{Options: cli.Options{JSON: true}, algorithm: "std", workers: workers, recursive: recursive, numbers: numbers}
+				if err := sortSlice(context.Background(), c, strings.NewReader(""), &stdout, io.Discard); err != nil {
+					t.Fatalf("%d values, %d workers, recursive %v: %v", size, workers, recursive, err)
+				}
+				var result struct {
+					Sorted []int `json:"sorted"`
+				}
+				if err := json.Unmarshal(stdout.Bytes(), &result); err != nil {
+					t.Fatal(err)
+				}
+				if len(result.Sorted) != size || (size > 0 && !reflect.DeepEqual(result.Sorted, want)) {
+					t.Errorf("%d values, %d workers, recursive %v: got %v; want %v", size, workers, recursive, result.Sorted, want)
+				}
+			}
+		}
+	}
+}
+
+func TestSortWith(t *testing.T) {
+	for _, sorter := range sorting.Sorters() {
+		t.Run(sorter.Name(), func(t *testing.T) {
+			c := make(chan []int)
+			go SortWith(sorter, []int{3, 1, 4, 1, 5, 9, 2}, c)
+
+			result := <-c
+			if !reflect.DeepEqual(result, []int{1, 1, 2, 3, 4, 5, 9}) {
+				t.Errorf("SortWith(%s): expected %v, but got %v", sorter.Name(), []int{1, 1, 2, 3, 4, 5, 9}, result)
+			}
+		})
+	}
+}
+
+func TestRun(t *testing.T) {
+	// The timings change at each run, so the golden files are only used with --quiet, which does not print them.
+	testCases := []struct {
+		name     string
+		args     []string
+		stdin    string
+		wantCode int
+	}{
+		{name: "quiet arguments", args: []string{"--quiet", "5", "3", "8", "1", "0", "-2"}, wantCode: cli.ExitOK},
+		{name: "quiet stdin", args: []string{"--batch", "--quiet"}, stdin: "9 8 7\n6 5\n\n4 3 2 1\n", wantCode: cli.ExitOK},
+		{name: "quiet empty input", args: []string{"--batch", "--quiet"}, stdin: "", wantCode: cli.ExitOK},
+		{name: "invalid number", args: []string{"--batch", "--quiet"}, stdin: "1 2\n3 x\n", wantCode: cli.ExitFailure},
+		{name: "unknown algorithm", args: []string{"--quiet", "-algo", "bogo", "1"}, wantCode: cli.ExitUsage},
+		{name: "more workers than numbers", args: []string{"--quiet", "-workers", "16", "3", "1", "2"}, wantCode: cli.ExitOK},
+		{name: "one worker", args: []string{"--quiet", "-workers", "1", "3", "1", "2"}, wantCode: cli.ExitOK},
+		{name: "many workers empty input", args: []string{"--batch", "--quiet", "-workers", "64"}, stdin: "", wantCode: cli.ExitOK},
+		{name: "no worker", args: []string{"--quiet", "-workers", "0", "1"}, wantCode: cli.ExitUsage},
+		{name: "recursive", args: []string{"--quiet", "-recursive", "5", "3", "8", "1", "0", "-2"}, wantCode: cli.ExitOK},
+		{name: "recursive with a cutoff", args: []string{"--quiet", "-recursive", "-cutoff", "2", "-workers", "4", "5", "3", "8", "1", "0", "-2"}, wantCode: cli.ExitOK},
+		{name: "negative cutoff", args: []string{"--quiet", "-recursive", "-cutoff", "-1", "1"}, wantCode: cli.ExitUsage},
+		{name: "auto with a seed", args: []string{"--quiet", "-auto", "-size", "12", "-seed", "3", "-max", "100"}, wantCode: cli.ExitOK},
+		{name: "auto sawtooth", args: []string{"--quiet", "-auto", "-size", "12", "-dist", "sawtooth:3", "-max", "12"}, wantCode: cli.ExitOK},
+		{name: "auto zipf", args: []string{"--quiet", "-auto", "-size", "12", "-dist", "zipf:2", "-seed", "5", "-min", "-3", "-max", "20"}, wantCode: cli.ExitOK},
+		{name: "dist without auto", args: []string{"--quiet", "-dist", "sorted", "1", "2"}, wantCode: cli.ExitUsage},
+		{name: "unknown dist", args: []string{"--quiet", "-auto", "-dist", "random"}, wantCode: cli.ExitUsage},
+		{name: "external", args: []string{"-external", "-memory", "24", "-workers", "1"}, stdin: "5 3 8\n1 0 -2\n9\n", wantCode: cli.ExitOK},
+		{name: "external quiet", args: []string{"--quiet", "-external", "-memory", "24", "-workers", "1"}, stdin: "5 3 8\n1 0 -2\n9\n", wantCode: cli.ExitOK},
+		{name: "external invalid number", args: []string{"-external"}, stdin: "5 3 x\n", wantCode: cli.ExitFailure},
+		{name: "external unknown format", args: []string{"-external", "-format", "csv"}, wantCode: cli.ExitUsage},
+		{name: "external with numbers", args: []string{"-external", "1", "2"}, wantCode: cli.ExitUsage},
+		{name: "external bad memory", args: []string{"-external", "-memory", "lots"}, wantCode: cli.ExitUsage},
+		{name: "bench report with numbers", args: []string{"--quiet", "-bench-report", "1", "2"}, wantCode: cli.ExitUsage},
+		{name: "bench report with one run", args: []string{"--quiet", "-bench-report", "-runs", "1"}, wantCode: cli.ExitUsage},
+		{name: "negative timeout", args: []string{"--quiet", "-timeout", "-1s", "1"}, wantCode: cli.ExitUsage},
+		{name: "serve not on localhost", args: []string{"-serve", "-listen", "0.0.0.0:8080"}, wantCode: cli.ExitUsage},
+		{name: "serve with numbers", args: []string{"-serve", "1", "2"}, wantCode: cli.ExitUsage},
+		{name: "serve without sorts", args: []string{"-serve", "-max-sorts", "0"}, wantCode: cli.ExitUsage},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			code := run(tc.args, strings.NewReader(tc.stdin), &stdout, &stderr)
+			if code != tc.wantCode {
+				t.Errorf("run(%v) exited with %d; want %d (stderr: %s)", tc.args, code, tc.wantCode, stderr.String())
+			}
+			golden.Assert(t, "run-"+strings.ReplaceAll(tc.name, " ", "-"), stdout.String())
+			if code != cli.ExitOK && stderr.Len() == 0 {
+				t.Errorf("run(%v) failed without an error message", tc.args)
+			}
+		})
+	}
+}
+
+func TestRunJSON(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"--json", "3", "1", "2"}, strings.NewReader(""), &stdout, &stderr); code != cli.ExitOK {
+		t.Fatalf("run exited with %d (stderr: %s)", code, stderr.String())
+	}
+
+	var result struct {
+		Sorted []int `json:"sorted"`
+	}
+	if err := json.Unmarshal(stdout.Bytes(), &result); err != nil {
+		t.Fatalf("the output is not valid JSON: %v\n%s", err, stdout.String())
+	}
+	if !reflect.DeepEqual(result.Sorted, []int{1, 2, 3}) {
+		t.Errorf("sorted = %v; want %v", result.Sorted, []int{1, 2, 3})
+	}
+}
+
+func TestRunVerify(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{name: "parts", args: []string{"-verify", "-auto", "-size", "1000", "-workers", "3"}, want: "Verified: the 1000 values are sorted, and the same as before sorting"},
+		{name: "recursive", args: []string{"-verify", "-auto", "-size", "1000", "-recursive"}, want: "Verified: the 1000 values are sorted, and the same as before sorting"},
+		{name: "json", args: []string{"--json", "-verify", "3", "1", "2"}, want: `"verification": {`},
+		{name: "external", args: []string{"-verify", "-external", "-memory", "24", "-workers", "1", "-out", filepath.Join(t.TempDir(), "sorted.txt")}, want: "Verified: the 7 values are sorted"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			if code := run(tt.args, strings.NewReader("5 3 8 1 0 -2 9"), &stdout, &stderr); code != cli.ExitOK {
+				t.Fatalf("run(%v) exited with %d (stderr: %s)", tt.args, code, stderr.String())
+			}
+			if !strings.Contains(stdout.String(), tt.want) {
+				t.Errorf("run(%v) did not print %q:\n%s", tt.args, tt.want, stdout.String())
+			}
+		})
+	}
+}
+
+func TestVerify(t *testing.T) {
+	sorted := []int{-5, 0, 0, 1, 2, 3, 7, 7, 8, 12}
+	before := checksum(sorted, 1)
+	tests := []struct {
+		name  string
+		slice []int
+		want  verification
+	}{
+		{name: "sorted", slice: sorted, want: verification{Values: 10, Sorted: true}},
+		{name: "empty", slice: []int{}, want: verification{Sorted: true}},
+		// With 2 workers, index 5 is the first value of the second part: it is compared with the last value of the first one.
+		{name: "not sorted", slice: []int{-5, 0, 0, 1, 3, 2, 7, 7, 8, 12}, want: verification{Values: 10, FirstUnsorted: 5}},
+		{name: "first of several", slice: []int{-5, 0, 0, 1, 2, 3, 7, 7, 12, 8}, want: verification{Values: 10, FirstUnsorted: 9}},
+		{name: "value changed", slice: []int{-5, 0, 0, 1, 2, 3, 7, 7, 8, 13}, want: verification{Values: 10, Sorted: true}},
+		{name: "value lost", slice: []int{-5, 0, 1, 2, 3, 7, 7, 8, 12}, want: verification{Values: 9, Sorted: true}},
+	}
+	for _, tt := range tests {
+		for _, workers := range []int{1, 2, 3, 16} {
+			got := verify(tt.slice, before, workers)
+			if got.Values != tt.want.Values || got.Sorted != tt.want.Sorted || got.FirstUnsorted != tt.want.FirstUnsorted {
+				t.Errorf("%s, %d workers: got %+v; want %+v", tt.name, workers, got, tt.want)
+			}
+			// Only the sorted slice with the same values passes.
+			if wantOK := tt.name == "sorted"; got.ok() != wantOK {
+				t.Errorf("%s, %d workers: ok() = %v; want %v (%v)", tt.name, workers, got.ok(), wantOK, got)
+			}
+		}
+	}
+}
+
+func TestChecksum(t *testing.T) {
+	random := rand.New(rand.NewSource(4))
+	slice := make([]int, 10_000)
+	for i := range slice {
+		slice[i] = random.Intn(100) - 50
+	}
+	want := checksum(slice, 1)
+
+	// The order and the number of workers don't matter.
+	random.Shuffle(len(slice), func(i, j int) { slice[i], slice[j] = slice[j], slice[i] })
+	for _, workers := range []int{1, 2, 7, 64} {
+		if got := checksum(slice, workers); got != want {
+			t.Errorf("%d workers, shuffled: got %016x; want %016x", workers, got, want)
+		}
+	}
+
+	// But the values do, even when their sum is the same.
+	for _, pair := range [][2][]int{{{1, 3}, {2, 2}}, {{1, 1}, {1}}, {{0}, {}}, {{5, -5}, {0, 0}}, {{1, 2}, {2, 1, 0}}} {
+		if checksum(pair[0], 1) == checksum(pair[1], 1) {
+			t.Errorf("%v and %v have the same checksum", pair[0], pair[1])
+		}
+	}
+}
+
+func TestVerifyingWriter(t *testing.T) {
+	for _, tt := range []struct {
+		values []int
+		want   verification
+	}{
+		{values: []int{1, 2, 2, 5}, want: verification{Values: 4, Sorted: true}},
+		{values: []int{1, 2, 5, 2}, want: verification{Values: 4, FirstUnsorted: 3}},
+		{values: nil, want: verification{Sorted: true}},
+	} {
+		var out bytes.Buffer
+		w := &verifyingWriter{valueWriter: newValueWriter(&out, "text", 64), firstUnsorted: -1}
+		for _, v := range tt.values {
+			w.Write(v)
+		}
+		w.Flush()
+		got := w.verification(checksum(tt.values, 1))
+		tt.want.ChecksumBefore, tt.want.ChecksumAfter = checksum(tt.values, 1), checksum(tt.values, 1)
+		if got != tt.want {
+			t.Errorf("%v: got %+v; want %+v", tt.values, got, tt.want)
+		}
+		if lines := strings.Count(out.String(), "\n"); lines != len(tt.values) {
+			t.Errorf("%v: %d values were written", tt.values, lines)
+		}
+	}
+}
+
+// FuzzSort sorts random slices with the goroutines, with random algorithms and numbers of workers, and checks the result
+// with the checks of -verify, and against sort.Ints. Run it with: go test -fuzz FuzzSort
+func FuzzSort(f *testing.F) {
+	f.Add([]byte{}, uint8(1), uint8(0), false)
+	f.Add([]byte{5, 3, 8, 1, 0, 254, 9}, uint8(3), uint8(9), false)
+	f.Add([]byte{1, 1, 1, 1, 0, 0, 0, 0, 1, 1}, uint8(4), uint8(12), true)
+	f.Add(bytes.Repeat([]byte{9, 8, 7, 6, 5, 4, 3, 2, 1, 0}, 50), uint8(7), uint8(3), true)
+
+	sorters := sorting.Sorters()
+	f.Fuzz(func(t *testing.T, data []byte, workers, algorithm uint8, recursive bool) {
+		// Each byte is a value, between -128 and 127: small values give a lot of equal ones.
+		slice := make([]int, len(data))
+		for i, b := range data {
+			slice[i] = int(int8(b))
+		}
+		want := append([]int{}, slice...)
+		sort.Ints(want)
+		n := int(workers%16) + 1
+		before := checksum(slice, n)
+
+		if recursive {
+			sorting.ParallelMergeSort(slice, int(algorithm%16), n)
+		} else {
+			splitSort(sorters[int(algorithm)%len(sorters)], partition(slice, n), &merger{})
+		}
+
+		if v := verify(slice, before, n); !v.ok() {
+			t.Fatalf("%v", v)
+		}
+		if !reflect.DeepEqual(slice, want) {
+			t.Fatalf("the checks passed, but got %v; want %v", slice, want)
+		}
+	})
+}
+
+// newTestServer starts the sorting service on a local port, configured like the command line with the options set by configure.
+func newTestServer(t *testing.T, configure func(c *config)) (*httptest.Server, *sortServer) {
+	c := config{algorithm: "std", workers: 3, maxBody: 1 << 20, maxSorts: 2}
+	if configure != nil {
+		configure(&c)
+	}
+	sorter, err := sorting.Lookup(c.algorithm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := newSortServer(c, sorter)
+	server := httptest.NewServer(s)
+	t.Cleanup(server.Close)
+	return server, s
+}
+
+// post sends the body to /sort, and returns the status and the body of the answer.
+func post(t *testing.T, server *httptest.Server, contentType string, body io.Reader) (int, string) {
+	t.Helper()
+	request, err := http.NewRequest(http.MethodPost, server.URL+"/sort", body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if contentType != "" {
+		request.Header.Set("Content-Type", contentType)
+	}
+	response, err := server.Client().Do(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer response.Body.Close()
+	answer, err := io.ReadAll(response.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return response.StatusCode, string(answer)
+}
+
+func TestServe(t *testing.T) {
+	tests := []struct {
+		name        string
+		configure   func(c *config)
+		contentType string
+		body        string
+		wantStatus  int
+		want        string // The answer, or a part of the error message.
+	}{
+		{name: "json", contentType: "application/json", body: "[5, 3, 8, 1, 0, -2]", wantStatus: http.StatusOK, want: "[-2,0,1,3,5,8]\n"},
+		{name: "json by default", body: "[2, 1]", wantStatus: http.StatusOK, want: "[1,2]\n"},
+		{name: "json with a charset", contentType: "application/json; charset=utf-8", body: "[2, 1]", wantStatus: http.StatusOK, want: "[1,2]\n"},
+		{name: "empty json", contentType: "application/json", body: "[]", wantStatus: http.StatusOK, want: "[]\n"},
+		{name: "ndjson", contentType: "application/x-ndjson", body: "5\n3\n8\n\n1\n", wantStatus: http.StatusOK, want: "1\n3\n5\n8\n"},
+		{name: "text", contentType: "text/plain", body: "9 -4\n7", wantStatus: http.StatusOK, want: "-4\n7\n9\n"},
+		{name: "empty ndjson", contentType: "application/x-ndjson", body: "", wantStatus: http.StatusOK, want: ""},
+		{name: "recursive", configure: func(c *config) { c.recursive = true }, body: "[3, 1, 2]", wantStatus: http.StatusOK, want: "[1,2,3]\n"},
+		{name: "verified", configure: func(c *config) { c.verify = true }, body: "[3, 1, 2]", wantStatus: http.StatusOK, want: "[1,2,3]\n"},
+		{name: "another algorithm", configure: func(c *config) { c.algorithm = "heap" }, body: "[3, 1, 2]", wantStatus: http.StatusOK, want: "[1,2,3]\n"},
+		{name: "not json", contentType: "application/json", body: "5 3 8", wantStatus: http.StatusBadRequest, want: "must be a JSON array of integers"},
+		{name: "something after the array", contentType: "application/json", body: "[1,2]garbage", wantStatus: http.StatusBadRequest, want: "there is something after the array"},
+		{name: "two arrays", contentType: "application/json", body: "[1,2] [3]", wantStatus: http.StatusBadRequest, want: "there is something after the array"},
+		{name: "spaces after the array", contentType: "application/json", body: "[2,1]\n\n", wantStatus: http.StatusOK, want: "[1,2]\n"},
+		{name: "not integers", contentType: "application/json", body: "[1.5, 2]", wantStatus: http.StatusBadRequest, want: "must be a JSON array of integers"},
+		{name: "not an integer", contentType: "application/x-ndjson", body: "1\n2\nthree\n", wantStatus: http.StatusBadRequest, want: `body: value 3: "three" is not a valid integer`},
+		{name: "unsupported type", contentType: "application/xml", body: "<int>1</int>", wantStatus: http.StatusUnsupportedMediaType, want: "unsupported Content-Type"},
+		{name: "too large", body: "[" + strings.Repeat("1,", 600_000) + "1]", wantStatus: http.StatusRequestEntityTooLarge, want: "the request body is too large"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, _ := newTestServer(t, tt.configure)
+			status, answer := post(t, server, tt.contentType, strings.NewReader(tt.body))
+			if status != tt.wantStatus {
+				t.Errorf("got the status %d; want %d (%s)", status, tt.wantStatus, answer)
+			}
+			if (status == http.StatusOK && answer != tt.want) || !strings.Contains(answer, tt.want) {
+				t.Errorf("got the answer %q; want %q", answer, tt.want)
+			}
+		})
+	}
+}
+
+func TestServeLimits(t *testing.T) {
+	t.Run("too large without a length", func(t *testing.T) {
+		// Without a Content-Length, the body is sent in chunks: the limit is found while reading it.
+		server, _ := newTestServer(t, func(c *config) { c.maxBody = 100 })
+		body := struct{ io.Reader }{strings.NewReader(strings.Repeat("1\n", 51))} // Hides the length from the client.
+		if status, answer := post(t, server, "text/plain", body); status != http.StatusRequestEntityTooLarge {
+			t.Errorf("got the status %d; want %d (%s)", status, http.StatusRequestEntityTooLarge, answer)
+		}
+		body = struct{ io.Reader }{strings.NewReader(strings.Repeat("1\n", 50))} // Exactly the limit.
+		if status, answer := post(t, server, "text/plain", body); status != http.StatusOK {
+			t.Errorf("at the limit: got the status %d; want %d (%s)", status, http.StatusOK, answer)
+		}
+	})
+
+	t.Run("all the sorts running", func(t *testing.T) {
+		// Both slots are taken: the request waits for one until its timeout.
+		server, s := newTestServer(t, func(c *config) { c.timeout = 50 * time.Millisecond })
+		s.slots <- struct{}{}
+		s.slots <- struct{}{}
+		status, answer := post(t, server, "", strings.NewReader("[2, 1]"))
+		if status != http.StatusServiceUnavailable || !strings.Contains(answer, "while waiting for the other sorts to finish") {
+			t.Errorf("got the status %d and %q; want %d", status, answer, http.StatusServiceUnavailable)
+		}
+
+		// Once a slot is free, the next request goes through.
+		<-s.slots
+		if status, answer := post(t, server, "", strings.NewReader("[2, 1]")); status != http.StatusOK {
+			t.Errorf("with a free slot: got the status %d; want %d (%s)", status, http.StatusOK, answer)
+		}
+	})
+
+	t.Run("slot kept after a timeout", func(t *testing.T) {
+		// The sort times out while its parts are being sorted: until they are done, it still takes the only slot.
+		sorter := &slowSorter{Sorter: sorting.Standard{}, started: make(chan struct{}, 10), release: make(chan struct{})}
+		s := newSortServer(config{algorithm: "std", workers: 1, maxBody: 1024, maxSorts: 1, timeout: 50 * time.Millisecond}, sorter)
+		server := httptest.NewServer(s)
+		defer server.Close()
+
+		if status, answer := post(t, server, "", strings.NewReader("[2, 1]")); status != http.StatusServiceUnavailable || !strings.Contains(answer, "while sorting") {
+			t.Errorf("first sort: got the status %d and %q; want %d", status, answer, http.StatusServiceUnavailable)
+		}
+		if status, answer := post(t, server, "", strings.NewReader("[4, 3]")); status != http.StatusServiceUnavailable || !strings.Contains(answer, "while waiting for the other sorts to finish") {
+			t.Errorf("second sort: got the status %d and %q; want %d while waiting", status, answer, http.StatusServiceUnavailable)
+		}
+
+		// Once the first sort is really over, the slot is free again.
+		close(sorter.release)
+		status, answer := 0, ""
+		for i := 0; i < 100 && status != http.StatusOK; i++ {
+			status, answer = post(t, server, "", strings.NewReader("[6, 5]"))
+		}
+		if status != http.StatusOK || answer != "[5,6]\n" {
+			t.Errorf("after the first sort: got the status %d and %q; want [5,6]", status, answer)
+		}
+	})
+
+	t.Run("client gone while answering", func(t *testing.T) {
+		_, s := newTestServer(t, nil)
+		w := failingWriter{httptest.NewRecorder()}
+		s.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/sort", strings.NewReader("[2, 1]")))
+		var metrics bytes.Buffer
+		s.metrics.writeTo(&metrics)
+		if want := `sort_requests_total{code="499"} 1`; !strings.Contains(metrics.String(), want+"\n") {
+			t.Errorf("the metrics don't have %q:\n%s", want, metrics.String())
+		}
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		server, _ := newTestServer(t, func(c *config) { c.timeout = time.Nanosecond })
+		status, answer := post(t, server, "", strings.NewReader("[2, 1]"))
+		if status != http.StatusServiceUnavailable || !strings.Contains(answer, "took longer than the timeout of 1ns") {
+			t.Errorf("got the status %d and %q; want %d", status, answer, http.StatusServiceUnavailable)
+		}
+	})
+
+	t.Run("wrong method", func(t *testing.T) {
+		server, _ := newTestServer(t, nil)
+		response, err := server.Client().Get(server.URL + "/sort")
+		if err != nil {
+			t.Fatal(err)
+		}
+		response.Body.Close()
+		if response.StatusCode != http.StatusMethodNotAllowed || response.Header.Get("Allow") != http.MethodPost {
+			t.Errorf("got the status %d and Allow %q; want %d and POST", response.StatusCode, response.Header.Get("Allow"), http.StatusMethodNotAllowed)
+		}
+	})
+}
+
+// failingWriter is a response that can't be written, like when the client has gone away.
+type failingWriter struct {
+	*httptest.ResponseRecorder
+}
+
+func (failingWriter) Write([]byte) (int, error) {
+	return 0, errors.New("the connection is closed")
+}
+
+func TestServeConcurrently(t *testing.T) {
+	// More requests than slots at once: they all get their own slice sorted.
+	server, _ := newTestServer(t, func(c *config) { c.maxSorts = 2 })
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			body := fmt.Sprintf("[%d, %d, %d]", i+2, i, i+1)
+			status, answer := post(t, server, "", strings.NewReader(body))
+			if want := fmt.Sprintf("[%d,%d,%d]\n", i, i+1, i+2); status != http.StatusOK || answer != want {
+				t.Errorf("%s: got the status %d and %q; want %q", body, status, answer, want)
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+func TestServeMetrics(t *testing.T) {
+	server, _ := newTestServer(t, nil)
+	post(t, server, "", strings.NewReader("[3, 1, 2]"))
+	post(t, server, "text/plain", strings.NewReader(strings.Repeat("7\n", 500)))
+	post(t, server, "", strings.NewReader("[oops]"))
+
+	response, err := server.Client().Get(server.URL + "/metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer response.Body.Close()
+	metrics, _ := io.ReadAll(response.Body)
+	for _, want := range []string{
+		`sort_requests_total{code="200"} 2`,
+		`sort_requests_total{code="400"} 1`,
+		"sort_running 0",
+		"sort_elements_total 503",
+		`sort_elements_bucket{le="100"} 1`,
+		`sort_elements_bucket{le="10000"} 2`,
+		`sort_elements_bucket{le="+Inf"} 2`,
+		"sort_elements_sum 503",
+		"sort_duration_seconds_count 2",
+	} {
+		if !strings.Contains(string(metrics), want+"\n") {
+			t.Errorf("the metrics don't have %q:\n%s", want, metrics)
+		}
+	}
+}
+
+func TestServeOn(t *testing.T) {
+	// The service runs until the context is done, then stops cleanly.
+	listener, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan error, 1)
+	go func() {
+		stopped <- serveOn(ctx, listener, newSortServer(config{algorithm: "std", workers: 2, maxBody: 1024, maxSorts: 1}, sorting.Standard{}))
+	}()
+
+	response, err := http.Post("http://"+listener.Addr().String()+"/sort", "application/json", strings.NewReader("[2, 3, 1]"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	answer, _ := io.ReadAll(response.Body)
+	response.Body.Close()
+	if string(answer) != "[1,2,3]\n" {
+		t.Errorf("got %q", answer)
+	}
+
+	cancel()
+	select {
+	case err := <-stopped:
+		if err != nil {
+			t.Errorf("got the error %v when stopping", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the service did not stop")
+	}
+}
+
+func TestIsLocalhost(t *testing.T) {
+	for address, want := range map[string]bool{
+		"localhost:8080": true, "LOCALHOST:0": true, "127.0.0.1:80": true, "[::1]:8080": true, "127.1.2.3:80": true,
+		"0.0.0.0:8080": false, ":8080": false, "example.com:80": false, "192.168.1.2:80": false, "localhost": false,
+	} {
+		if got := isLocalhost(address); got != want {
+			t.Errorf("isLocalhost(%q) = %v; want %v", address, got, want)
+		}
+	}
+}
+
+func TestRunTimeout(t *testing.T) {
+	// A timeout of 1ns is over before the sort starts, whatever the mode.
+	for _, args := range [][]string{
+		{"--quiet", "-timeout", "1ns", "-auto", "-size", "100000"},
+		{"--quiet", "-timeout", "1ns", "-auto", "-size", "100000", "-recursive"},
+		{"--quiet", "-timeout", "1ns", "-external"},
+		{"--quiet", "-timeout", "1ns", "-bench-report", "-size", "1000", "-runs", "2"},
+	} {
+		var stdout, stderr bytes.Buffer
+		code := run(args, strings.NewReader("3 1 2"), &stdout, &stderr)
+		if code != cli.ExitFailure || !strings.Contains(stderr.String(), "took longer than the -timeout of 1ns") {
+			t.Errorf("run(%v) exited with %d and the error %q; want %d and a timeout", args, code, stderr.String(), cli.ExitFailure)
+		}
+		if stdout.Len() != 0 {
+			t.Errorf("run(%v) printed %q after the timeout", args, stdout.String())
+		}
+	}
+}
+
+func TestRunProgress(t *testing.T) {
+	// The bar is drawn on stderr, and ends at 100% on its own line.
+	for _, args := range [][]string{
+		{"--quiet", "-progress", "-auto", "-size", "100000", "-workers", "4"},
+		{"--quiet", "-progress", "-auto", "-size", "100000", "-recursive"},
+		{"--quiet", "-progress", "-external", "-memory", "24"},
+	} {
+		var stdout, stderr bytes.Buffer
+		if code := run(args, strings.NewReader("5 3 8 1 0 -2 9"), &stdout, &stderr); code != cli.ExitOK {
+			t.Fatalf("run(%v) exited with %d (stderr: %s)", args, code, stderr.String())
+		}
+		lines := strings.Split(stderr.String(), "\r")
+		if last := lines[len(lines)-1]; !strings.Contains(last, "] 100%") || !strings.HasSuffix(last, "\n") {
+			t.Errorf("run(%v): the bar does not end at 100%%: %q", args, last)
+		}
+	}
+
+	// Without -progress, nothing is drawn when stderr is not a terminal.
+	var stdout, stderr bytes.Buffer
+	run([]string{"-auto", "-size", "1000"}, strings.NewReader(""), &stdout, &stderr)
+	if stderr.Len() != 0 {
+		t.Errorf("a bar was drawn on a buffer: %q", stderr.String())
+	}
+}
+
+func TestExternalSort(t *testing.T) {
+	input := benchGenerators(5000)[0].Generate(5000)
+	for i := range input {
+		input[i] -= 1 << 30 // Negative values too.
+	}
+	want := append([]int{}, input...)
+	sort.Ints(want)
+
+	tests := []struct {
+		name       string
+		memory     int64
+		maxOpen    int
+		workers    int
+		format     string
+		wantRuns   int
+		wantPasses int
+	}{
+		// 800 bytes for 2 workers are 50 integers per chunk: 100 runs. Merging 2 runs at a time takes 7 passes to get 1.
+		{name: "text, many passes", memory: 800, maxOpen: 3, workers: 2, format: "text", wantRuns: 100, wantPasses: 7},
+		{name: "binary, many passes", memory: 800, maxOpen: 3, workers: 2, format: "binary", wantRuns: 100, wantPasses: 7},
+		// 9 runs at a time: 100 runs, then 12, then 2, then the output.
+		{name: "more open files", memory: 800, maxOpen: 10, workers: 2, format: "text", wantRuns: 100, wantPasses: 3},
+		{name: "one worker", memory: 8000, maxOpen: 4, workers: 1, format: "binary", wantRuns: 5, wantPasses: 2},
+		{name: "one run", memory: 1 << 20, maxOpen: 3, workers: 4, format: "text", wantRuns: 1, wantPasses: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var in, out bytes.Buffer
+			writer := newValueWriter(&in, tt.format, 4096)
+			for _, v := range input {
+				writer.Write(v)
+			}
+			writer.Flush()
+
+			temp := t.TempDir()
+			o := externalOptions{Memory: tt.memory, MaxOpen: tt.maxOpen, Workers: tt.workers, TempDir: temp, Format: tt.format, Sorter: sorting.Standard{}}
+			var last progress
+			closed, mergedBeforeClose := false, false
+			o.CloseInput = func() error { closed = true; return nil }
+			o.Progress = func(p progress) {
+				last = p
+				if p.BytesMerged > 0 && !closed {
+					mergedBeforeClose = true
+				}
+			}
+			stats, err := externalSort(context.Background(), &in, "test", &out, o)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// The input is closed before merging, so that it does not count against MaxOpen.
+			if !closed || mergedBeforeClose {
+				t.Errorf("the input was closed: %t, before merging: %t", closed, !mergedBeforeClose)
+			}
+			if stats.Values != int64(len(input)) || stats.Runs != tt.wantRuns || stats.Passes != tt.wantPasses {
+				t.Errorf("got %+v; want %d values, %d runs and %d passes", stats, len(input), tt.wantRuns, tt.wantPasses)
+			}
+			// Every pass merged all the values.
+			if want := (progress{PartsDone: tt.wantRuns, BytesMerged: 8 * 5000 * int64(tt.wantPasses), Bytes: 8 * 5000 * int64(tt.wantPasses)}); last != want {
+				t.Errorf("got the progress %+v at the end; want %+v", last, want)
+			}
+
+			var got []int
+			reader := newValueReader(&out, "output", tt.format)
+			for {
+				v, err := reader.Read()
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					t.Fatal(err)
+				}
+				got = append(got, v)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("the output is not the sorted input (%d values)", len(got))
+			}
+
+			// The temporary files are all gone.
+			if files, _ := os.ReadDir(temp); len(files) != 0 {
+				t.Errorf("%d temporary files were left behind", len(files))
+			}
+		})
+	}
+}
+
+func TestExternalSortErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		format  string
+		memory  int64
+		maxOpen int
+		want    string
+	}{
+		{name: "not an integer", input: "3 1\n2 x 5", format: "text", memory: 800, maxOpen: 3, want: `test: value 4: "x" is not a valid integer`},
+		{name: "half an integer", input: "12345678abc", format: "binary", memory: 800, maxOpen: 3, want: "test: the file ends in the middle of an integer (3 bytes out of 8)"},
+		{name: "memory too small", input: "1", format: "text", memory: 15, maxOpen: 3, want: "too small for 2 workers"},
+		{name: "too few open files", input: "1", format: "text", memory: 800, maxOpen: 2, want: "at least 3 files"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			temp := t.TempDir()
+			o := externalOptions{Memory: tt.memory, MaxOpen: tt.maxOpen, Workers: 2, TempDir: temp, Format: tt.format, Sorter: sorting.Standard{}}
+			_, err := externalSort(context.Background(), strings.NewReader(tt.input), "test", io.Discard, o)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("got error %v; want one with %q", err, tt.want)
+			}
+			if files, _ := os.ReadDir(temp); len(files) != 0 {
+				t.Errorf("%d temporary files were left behind", len(files))
+			}
+		})
+	}
+}
+
+func TestExternalSortCancelled(t *testing.T) {
+	// The context is cancelled as soon as the first run is written: the sort stops, and leaves no file behind.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	temp := t.TempDir()
+	o := externalOptions{Memory: 800, MaxOpen: 3, Workers: 1, TempDir: temp, Format: "text", Sorter: sorting.Standard{},
+		Progress: func(progress) { cancel() }}
+	input := strings.Repeat("3 1 2\n", 1000)
+
+	_, err := externalSort(ctx, strings.NewReader(input), "test", io.Discard, o)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("got error %v; want %v", err, context.Canceled)
+	}
+	if files, _ := os.ReadDir(temp); len(files) != 0 {
+		t.Errorf("%d temporary files were left behind", len(files))
+	}
+}
+
+func TestMergePasses(t *testing.T) {
+	for _, tt := range []struct{ runs, group, want int }{{0, 2, 1}, {1, 2, 1}, {2, 2, 1}, {3, 2, 2}, {100, 2, 7}, {100, 9, 3}, {5, 3, 2}} {
+		if got := mergePasses(tt.runs, tt.group); got != tt.want {
+			t.Errorf("mergePasses(%d, %d) = %d; want %d", tt.runs, tt.group, got, tt.want)
+		}
+	}
+}
+
+func TestExternalSortInPlace(t *testing.T) {
+	// The output can be the input: it is only replaced once it is sorted.
+	path := filepath.Join(t.TempDir(), "numbers.txt")
+	if err := os.WriteFile(path, []byte("5 3 -8\n1 0\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	var stdout, stderr bytes.Buffer
+	args := []string{"-external", "-in", path, "-out", path, "-memory", "16", "-workers", "1", "-max-open", "3"}
+	if code := run(args, strings.NewReader(""), &stdout, &stderr); code != cli.ExitOK {
+		t.Fatalf("exit code %d, stderr: %s", code, stderr.String())
+	}
+	sorted, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(sorted) != "-8\n0\n1\n3\n5\n" {
+		t.Errorf("got %q", sorted)
+	}
+	if want := "Sorted 5 integers: 3 sorted runs, merged in 2 passes\n"; stdout.String() != want {
+		t.Errorf("got the summary %q; want %q", stdout.String(), want)
+	}
+	if files, _ := os.ReadDir(filepath.Dir(path)); len(files) != 1 {
+		t.Errorf("got %d files next to the output; want only the output", len(files))
+	}
+}
+
+func TestByteSize(t *testing.T) {
+	for value, want := range map[string]int64{"4096": 4096, "512K": 512 << 10, "64m": 64 << 20, "2G": 2 << 30} {
+		var b byteSize
+		if err := b.Set(value); err != nil || int64(b) != want {
+			t.Errorf("Set(%q): got %d, %v; want %d", value, b, err, want)
+		}
+	}
+	for _, value := range []string{"", "K", "-1", "12T", "1.5M"} {
+		var b byteSize
+		if err := b.Set(value); err == nil {
+			t.Errorf("Set(%q): want an error", value)
+		}
+	}
+}
+
+// The benchmarks compare the ways of sorting of the program with sort.Ints, on the same slices, for every distribution, size and
+// number of workers (see contenders), for example: go test -bench . -benchmem
+// Or only some of them: go test -bench 'Sort/uniform/^100000$/' -benchmem
+// The -bench-report flag of the program runs them several times and prints statistics.
+var (
+	benchmarkSizes   = []int{1_000, 10_000, 100_000, 1_000_000}
+	benchmarkWorkers = []int{1, 2, 4, 8}
+)
+
+func BenchmarkSort(b *testing.B) {
+	sorter, _ := sorting.Lookup("std")
+	for i, distribution := range distributions {
+		b.Run(distribution, func(b *testing.B) {
+			for _, size := range benchmarkSizes {
+				input := benchGenerators(size)[i].Generate(size)
+				b.Run(strconv.Itoa(size), func(b *testing.B) {
+					// sort.Ints does not depend on the number of workers, so it only runs once.
+					all := contenders(sorter, benchmarkWorkers[0], 0)
+					for _, workers := range benchmarkWorkers[1:] {
+						all = append(all, contenders(sorter, workers, 0)[1:]...)
+					}
+					for _, contender := range all {
+						b.Run(contender.name, func(b *testing.B) {
+							benchmarkContender(b, contender, input)
+						})
+					}
+				})
+			}
+		})
+	}
+}
+
+// benchmarkContender sorts a fresh copy of the input at each iteration. The copies are not timed.
+func benchmarkContender(b *testing.B, c contender, input []int) {
+	slice := make([]int, len(input))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		copy(slice, input)
+		b.StartTimer()
+		c.sort(slice)
+	}
+}
+
+// BenchmarkMerge compares two ways of merging 4 sorted parts: two levels of merge, which allocate a new slice at every merge,
+// and a merger, which reuses its buffer: go test -bench Merge -benchmem
+// The 100M integers need about 4GB of memory, so they are only merged with -bench-huge: go test -bench Merge -benchmem -bench-huge
+// GCs/op is the number of garbage collections per merge. On a machine with a single CPU:
+//
+//	size         way         time/op    B/op       allocs/op    GCs/op
+//	10000000     tree        0.21 s     160 MB     3            1
+//	10000000     ping-pong   0.20 s     424 B      14           0
+//	100000000    tree        2.2-3.3 s  1.6 GB     3            1
+//	100000000    ping-pong   1.9 s      424 B      14           0
+//
+// The merger allocates nothing but a few bytes for its goroutines, so the garbage collector has nothing to do,
+// and the time of the big merges does not depend on when it runs anymore.
+func BenchmarkMerge(b *testing.B) {
+	for _, size := range []int{10_000_000, 100_000_000} {
+		size := size
+		b.Run(strconv.Itoa(size), func(b *testing.B) {
+			// The slices are only made for the sizes that run.
+			if size > 10_000_000 && !*benchHuge {
+				b.Skip("needs about 4GB of memory, run it with -bench-huge")
+			}
+			input := benchGenerators(size)[0].Generate(size)
+			parts := partition(input, 4)
+			ends := make([]int, len(parts))
+			for i, part := range parts {
+				sort.Ints(part)
+				ends[i] = len(part)
+				if i > 0 {
+					ends[i] += ends[i-1]
+				}
+			}
+
+			b.Run("tree", func(b *testing.B) {
+				benchmarkMerge(b, func() {}, func() {
+					merge(merge(parts[0], parts[1]), merge(parts[2], parts[3]))
+				})
+			})
+			b.Run("ping-pong", func(b *testing.B) {
+				var m merger
+				slice := make([]int, size)
+				m.mergeParts(context.Background(), slice, ends, nil) // The first merge allocates the buffer.
+				benchmarkMerge(b, func() { copy(slice, input) }, func() {
+					m.mergeParts(context.Background(), slice, ends, nil)
+				})
+			})
+		})
+	}
+}
+
+// benchHuge runs BenchmarkMerge on 100M integers too.
+var benchHuge = flag.Bool("bench-huge", false, "also run BenchmarkMerge on 100M integers, which needs about 4GB of memory")
+
+// benchmarkMerge runs the merge b.N times, after prepare, which is not timed, and reports the garbage collections it caused.
+func benchmarkMerge(b *testing.B, prepare, merge func()) {
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		prepare()
+		b.StartTimer()
+		merge()
+	}
+	b.StopTimer()
+	runtime.ReadMemStats(&after)
+	b.ReportMetric(float64(after.NumGC-before.NumGC)/float64(b.N), "GCs/op")
+}
+
+func TestContenders(t *testing.T) {
+	sorter, _ := sorting.Lookup("std")
+	for _, generator := range benchGenerators(1000) {
+		input := generator.Generate(1000)
+		want := append([]int{}, input...)
+		sort.Ints(want)
+		for _, workers := range []int{1, 3, 8} {
+			for _, contender := range contenders(sorter, workers, 0) {
+				got := append([]int{}, input...)
+				contender.sort(got)
+				if !reflect.DeepEqual(got, want) {
+					t.Errorf("%s on %s: the slice is not sorted", contender.name, generator)
+				}
+			}
+		}
+	}
+}
+
+func TestComputeStatistics(t *testing.T) {
+	s := computeStatistics([]float64{2, 4, 4, 4, 5, 5, 7, 9})
+	// The mean is 5, the standard deviation (with n-1) √(32/7), and the margin t(7) × σ / √8.
+	sigma := math.Sqrt(32.0 / 7)
+	margin := 2.365 * sigma / math.Sqrt(8)
+	if math.Abs(s.Mean-5) > 1e-12 || math.Abs(s.StandardDeviation-sigma) > 1e-12 ||
+		math.Abs(s.Low-(5-margin)) > 1e-12 || math.Abs(s.High-(5+margin)) > 1e-12 {
+		t.Errorf("computeStatistics: got %+v; want mean 5, standard deviation %v, interval ±%v", s, sigma, margin)
+	}
+}
+
+func TestBenchReport(t *testing.T) {
+	defer func(saved time.Duration) { benchTime = saved }(benchTime)
+	benchTime = time.Millisecond
+
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"--json", "-bench-report", "-size", "500", "-runs", "3", "-workers", "2"}, strings.NewReader(""), &stdout, &stderr)
+	if code != cli.ExitOK {
+		t.Fatalf("exit code %d, stderr: %s", code, stderr.String())
+	}
+	var report struct {
+		Runs          int                  `json:"runs"`
+		Distributions []distributionReport `json:"distributions"`
+	}
+	if err := json.Unmarshal(stdout.Bytes(), &report); err != nil {
+		t.Fatal(err)
+	}
+	if report.Runs != 3 || len(report.Distributions) != len(distributions) {
+		t.Fatalf("got %d runs and %d distributions; want 3 and %d", report.Runs, len(report.Distributions), len(distributions))
+	}
+	for _, d := range report.Distributions {
+		names := []string{}
+		for _, c := range d.Contenders {
+			names = append(names, c.Name)
+			if !(c.Nanoseconds.Mean > 0) || c.Nanoseconds.Low > c.Nanoseconds.Mean || c.Speedup.High < c.Speedup.Mean {
+				t.Errorf("%s, %s: the statistics make no sense: %+v", d.Distribution, c.Name, c)
+			}
+		}
+		if want := []string{"sort.Ints", "parts-2", "recursive-2"}; !reflect.DeepEqual(names, want) {
+			t.Errorf("%s: got contenders %v; want %v", d.Distribution, names, want)
+		}
+		// sort.Ints is the baseline: it is exactly as fast as itself, in every run.
+		if baseline := d.Contenders[0].Speedup; baseline.Mean != 1 || baseline.StandardDeviation != 0 {
+			t.Errorf("%s: the speedup of sort.Ints is %+v; want exactly 1", d.Distribution, baseline)
+		}
+	}
+
+	// With -dist, only that distribution is used.
+	stdout.Reset()
+	code = run([]string{"--json", "-bench-report", "-size", "100", "-runs", "2", "-dist", "nearly-sorted:5", "-seed", "1"}, strings.NewReader(""), &stdout, &stderr)
+	if code != cli.ExitOK {
+		t.Fatalf("exit code %d, stderr: %s", code, stderr.String())
+	}
+	if err := json.Unmarshal(stdout.Bytes(), &report); err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Distributions) != 1 || report.Distributions[0].Distribution != "nearly-sorted:5" {
+		t.Errorf("-dist nearly-sorted:5: got the distributions %+v", report.Distributions)
+	}
+}
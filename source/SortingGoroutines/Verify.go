@@ -0,0 +1,128 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+)
+
+// Printing the first 50 values of 100M sorted integers proves very little. With -verify, the program checks the whole result:
+//   - the values are in order: each goroutine checks one part of the slice, and the first value of the next part;
+//   - they are the same values as before sorting, none lost, changed or repeated. Comparing them with a sorted copy would
+//     need a second slice, and a second sort. Instead, we take a checksum of the values before and after sorting, which
+//     does not depend on their order: each value is mixed into a big random-looking number, and the numbers are added up.
+//     Adding does not care about the order, but a value lost or changed changes the sum (the odds that another change
+//     gives the same sum are about 1 in 2^64).
+//
+// Together, they prove the output is a sorted permutation of the input.
+
+// verification is the result of the checks of -verify.
+type verification struct {
+	Values         int    `json:"values"`
+	Sorted         bool   `json:"sorted"`
+	FirstUnsorted  int    `json:"firstUnsorted,omitempty"` // The index of the first value smaller than the one before it, when not sorted.
+	ChecksumBefore uint64 `json:"checksumBefore"`
+	ChecksumAfter  uint64 `json:"checksumAfter"`
+}
+
+// ok tells if the sort passed the checks.
+func (v verification) ok() bool {
+	return v.Sorted && v.ChecksumBefore == v.ChecksumAfter
+}
+
+// String explains the result of the checks.
+func (v verification) String() string {
+	switch {
+	case !v.Sorted:
+		return fmt.Sprintf("the values are not sorted: the value at index %d is smaller than the one before it", v.FirstUnsorted)
+	case v.ChecksumBefore != v.ChecksumAfter:
+		return fmt.Sprintf("the values changed while sorting: the checksum was %016x before, and is %016x after", v.ChecksumBefore, v.ChecksumAfter)
+	default:
+		return fmt.Sprintf("the %d values are sorted, and the same as before sorting (checksum %016x)", v.Values, v.ChecksumAfter)
+	}
+}
+
+// verify checks the sorted slice with workers goroutines. before is the checksum of the slice before it was sorted.
+func verify(slice []int, before uint64, workers int) verification {
+	v := verification{Values: len(slice), ChecksumBefore: before, ChecksumAfter: checksum(slice, workers)}
+	v.FirstUnsorted = firstUnsorted(slice, workers)
+	v.Sorted = v.FirstUnsorted < 0
+	if v.Sorted {
+		v.FirstUnsorted = 0
+	}
+	return v
+}
+
+// firstUnsorted returns the index of the first value smaller than the one before it, or -1 when the slice is sorted.
+// Each of the workers goroutines checks one part of the slice.
+func firstUnsorted(slice []int, workers int) int {
+	parts := partition(slice, workers)
+	found := make([]int, len(parts))
+	var wg sync.WaitGroup
+	start := 0
+	for i, part := range parts {
+		wg.Add(1)
+		go func(i, start, end int) {
+			defer wg.Done()
+			found[i] = -1
+			// Each value is compared with the one before it, which is the last one of the part before for the first value of a part.
+			if start == 0 {
+				start = 1
+			}
+			for j := start; j < end; j++ {
+				if slice[j] < slice[j-1] {
+					found[i] = j
+					return
+				}
+			}
+		}(i, start, start+len(part))
+		start += len(part)
+	}
+	wg.Wait()
+
+	for _, index := range found {
+		if index >= 0 {
+			return index
+		}
+	}
+	return -1
+}
+
+// checksum returns the multiset checksum of the slice: the sum of the values once mixed. It is the same whatever the order
+// of the values. Each of the workers goroutines adds up one part of the slice.
+func checksum(slice []int, workers int) uint64 {
+	parts := partition(slice, workers)
+	sums := make([]uint64, len(parts))
+	var wg sync.WaitGroup
+	for i, part := range parts {
+		wg.Add(1)
+		go func(i int, part []int) {
+			defer wg.Done()
+			sums[i] = sumMixed(part)
+		}(i, part)
+	}
+	wg.Wait()
+
+	var sum uint64
+	for _, s := range sums {
+		sum += s
+	}
+	return sum
+}
+
+// sumMixed adds up the mixed values of a slice. The sum wraps around at 2^64, which does not change the order independence.
+func sumMixed(slice []int) uint64 {
+	var sum uint64
+	for _, v := range slice {
+		sum += mix(v)
+	}
+	return sum
+}
+
+// mix turns a value into a random-looking 64 bits number, so that values close to each other give very different numbers:
+// adding the values themselves would not see 1 and 3 becoming 2 and 2. It is the finalizer of SplitMix64.
+func mix(v int) uint64 {
+	x := uint64(v) + 0x9e3779b97f4a7c15
+	x = (x ^ (x >> 30)) * 0xbf58476d1ce4e5b9
+	x = (x ^ (x >> 27)) * 0x94d049bb133111eb
+	return x ^ (x >> 31)
+}
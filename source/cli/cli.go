@@ -0,0 +1,147 @@
+// Package cli holds the command line conventions shared by all the programs of this repository,
+// so that they can all be used the same way in shell scripts and pipelines:
+//
+//	--batch  never prompt, read the input from the arguments, the -in file or standard input
+//	--quiet  only print the results, without titles or explanations
+//	--json   print the results (and the errors) as JSON
+//	-in      file to read the input from
+//
+// and the same exit codes (see ExitOK, ExitFailure and ExitUsage).
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+)
+
+// Exit codes shared by all the programs.
+const (
+	ExitOK      = 0 // Everything went fine.
+	ExitFailure = 1 // The command line was fine, but something went wrong: invalid input, unreadable file, unknown animal...
+	ExitUsage   = 2 // The command line itself is wrong: unknown flag, missing value... This is also what the flag package uses.
+)
+
+// Options are the flags every program has.
+type Options struct {
+	Batch bool
+	Quiet bool
+	JSON  bool
+	Input string
+}
+
+// Register adds the common flags to a flag set.
+func (o *Options) Register(flags *flag.FlagSet) {
+	flags.BoolVar(&o.Batch, "batch", false, "never prompt: read the input from the arguments, the -in file or standard input")
+	flags.BoolVar(&o.Quiet, "quiet", false, "only print the results, without titles or explanations")
+	flags.BoolVar(&o.JSON, "json", false, "print the results as JSON")
+	flags.StringVar(&o.Input, "in", "", "file to read the input from (- for standard input)")
+}
+
+// Verbose tells if titles, explanations and progress messages should be printed.
+func (o Options) Verbose() bool {
+	return !o.Quiet && !o.JSON
+}
+
+// NewFlagSet returns a flag set that reports its errors instead of exiting, so that the programs can be tested.
+func NewFlagSet(name string, stderr io.Writer) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	return flags
+}
+
+// Parse parses the command line. When it returns false, the program must stop and exit with the returned code
+// (ExitOK for -help, as the help has been printed, ExitUsage for anything else).
+func Parse(flags *flag.FlagSet, args []string) (ok bool, exitCode int) {
+	err := flags.Parse(args)
+	if err == nil {
+		return true, ExitOK
+	}
+	if errors.Is(err, flag.ErrHelp) {
+		return false, ExitOK
+	}
+	// The flag package already printed the error and the usage.
+	return false, ExitUsage
+}
+
+// UsageError is an error caused by the command line. Programs exit with ExitUsage when they get one.
+type UsageError struct {
+	Err error
+}
+
+func (e *UsageError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *UsageError) Unwrap() error {
+	return e.Err
+}
+
+// Usagef returns a UsageError with a formatted message.
+func Usagef(format string, args ...interface{}) error {
+	return &UsageError{Err: fmt.Errorf(format, args...)}
+}
+
+// ExitCode returns the exit code for an error: ExitOK for nil, ExitUsage for a UsageError, ExitFailure for anything else.
+func ExitCode(err error) int {
+	if err == nil {
+		return ExitOK
+	}
+	var usageError *UsageError
+	if errors.As(err, &usageError) {
+		return ExitUsage
+	}
+	return ExitFailure
+}
+
+// Report prints the error on stderr, as {"error": "..."} in JSON mode, and returns the exit code the program must use.
+func (o Options) Report(stderr io.Writer, program string, err error) int {
+	if err == nil {
+		return ExitOK
+	}
+	if o.JSON {
+		WriteJSON(stderr, struct {
+			Error string `json:"error"`
+		}{err.Error()})
+	} else {
+		fmt.Fprintf(stderr, "%s: %v\n", program, err)
+	}
+	return ExitCode(err)
+}
+
+// WriteJSON writes v as indented JSON, followed by a new line.
+func WriteJSON(w io.Writer, v interface{}) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+// OpenInput opens the file given with -in, or returns stdin if there is none (or if it is "-").
+// The returned name is the one to use in error messages. The caller must call close when done.
+func OpenInput(path string, stdin io.Reader) (input io.Reader, name string, close func() error, err error) {
+	if path == "" || path == "-" {
+		return stdin, "stdin", func() error { return nil }, nil
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	return file, path, file.Close, nil
+}
+
+// IsTerminal tells if the reader is a terminal (and not a pipe, a file or a buffer).
+// It is how programs decide to prompt the user when they are not in batch mode.
+func IsTerminal(r io.Reader) bool {
+	file, ok := r.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := file.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
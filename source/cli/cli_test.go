@@ -0,0 +1,78 @@
+package cli
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestExitCode(t *testing.T) {
+	testCases := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{name: "no error", err: nil, want: ExitOK},
+		{name: "usage error", err: Usagef("missing %s", "-a"), want: ExitUsage},
+		{name: "wrapped usage error", err: errorWrapper{Usagef("missing -a")}, want: ExitUsage},
+		{name: "other error", err: errors.New("file not found"), want: ExitFailure},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := ExitCode(tc.err); got != tc.want {
+				t.Errorf("ExitCode(%v) = %d; want %d", tc.err, got, tc.want)
+			}
+		})
+	}
+}
+
+// errorWrapper wraps an error, to check that ExitCode looks through wrapped errors.
+type errorWrapper struct{ err error }
+
+func (e errorWrapper) Error() string { return "wrapped: " + e.err.Error() }
+func (e errorWrapper) Unwrap() error { return e.err }
+
+func TestReport(t *testing.T) {
+	var text, json bytes.Buffer
+
+	if code := (Options{}).Report(&text, "prog", errors.New("boom")); code != ExitFailure {
+		t.Errorf("Report returned %d; want %d", code, ExitFailure)
+	}
+	if text.String() != "prog: boom\n" {
+		t.Errorf("Report printed %q; want %q", text.String(), "prog: boom\n")
+	}
+
+	if code := (Options{JSON: true}).Report(&json, "prog", Usagef("bad flag")); code != ExitUsage {
+		t.Errorf("Report returned %d; want %d", code, ExitUsage)
+	}
+	if json.String() != "{\n  \"error\": \"bad flag\"\n}\n" {
+		t.Errorf("Report printed %q in JSON mode", json.String())
+	}
+}
+
+func TestParse(t *testing.T) {
+	testCases := []struct {
+		name     string
+		args     []string
+		wantOK   bool
+		wantCode int
+	}{
+		{name: "valid flags", args: []string{"--batch", "--quiet", "-in", "file.txt"}, wantOK: true, wantCode: ExitOK},
+		{name: "help", args: []string{"-help"}, wantOK: false, wantCode: ExitOK},
+		{name: "unknown flag", args: []string{"--bogus"}, wantOK: false, wantCode: ExitUsage},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var options Options
+			flags := NewFlagSet("prog", &bytes.Buffer{})
+			options.Register(flags)
+
+			ok, code := Parse(flags, tc.args)
+			if ok != tc.wantOK || code != tc.wantCode {
+				t.Errorf("Parse(%v) = %t, %d; want %t, %d", tc.args, ok, code, tc.wantOK, tc.wantCode)
+			}
+		})
+	}
+}
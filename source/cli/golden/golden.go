@@ -0,0 +1,40 @@
+// Package golden compares the output of a program with the expected output saved in a file, under testdata/.
+// When the output changes on purpose, the files of a package are updated with:
+//
+//	go test ./source/BubbleSort -update
+//
+// Only the packages whose tests use golden know the -update flag, so it can't be given to go test ./...
+package golden
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "update the golden files with the current output")
+
+// Assert checks that got is the content of testdata/<name>.golden.
+func Assert(t *testing.T, name string, got string) {
+	t.Helper()
+
+	path := filepath.Join("testdata", name+".golden")
+	if *update {
+		if err := os.MkdirAll("testdata", 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(got), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("reading golden file (run the test with -update to create it): %v", err)
+	}
+	if got != string(want) {
+		t.Errorf("output does not match %s:\ngot:\n%s\nwant:\n%s", path, got, want)
+	}
+}
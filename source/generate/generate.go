@@ -0,0 +1,289 @@
+// Package generate makes slices of integers to sort, with different distributions of values: random, already sorted,
+// nearly sorted, with only a few different values... Sorting algorithms behave very differently on each of them,
+// so trying only random values would not tell the whole story.
+//
+// Every slice comes from a seed: the same seed, distribution and size always give the same slice, so that a run can be repeated.
+package generate
+
+import (
+	"flag"
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Generator makes slices of one distribution. Use New to get one: it checks the values and sets the default parameter.
+type Generator struct {
+	Distribution string  // The name of the distribution, like "nearly-sorted".
+	Parameter    float64 // The parameter of the distribution, like the number of swaps of nearly-sorted (see Names).
+	Min, Max     int     // The values are between Min (included) and Max (excluded).
+	Seed         int64   // The seed of the random values.
+}
+
+// distribution is one of the distributions of the package.
+type distribution struct {
+	name string
+	// parameter is what the parameter means, empty when the distribution has none, and defaultParameter its value when it is not given.
+	parameter        string
+	defaultParameter float64
+	// valid tells if a parameter is allowed.
+	valid func(parameter float64) bool
+	fill  func(s []int, g Generator, random *rand.Rand)
+}
+
+// distributions is the list of all the distributions, in the order they are shown to the user.
+var distributions = []distribution{
+	{name: "uniform", fill: fillUniform},
+	{name: "sorted", fill: fillSorted},
+	{name: "reversed", fill: fillReversed},
+	{name: "nearly-sorted", parameter: "swaps", defaultParameter: 10, valid: isCount(0), fill: fillNearlySorted},
+	{name: "organ-pipe", fill: fillOrganPipe},
+	{name: "sawtooth", parameter: "teeth", defaultParameter: 4, valid: isCount(1), fill: fillSawtooth},
+	{name: "few-unique", parameter: "values", defaultParameter: 8, valid: isCount(1), fill: fillFewUnique},
+	{name: "gaussian", parameter: "deviation", defaultParameter: 1.0 / 6, valid: isPositive, fill: fillGaussian},
+	{name: "zipf", parameter: "exponent", defaultParameter: 1.1, valid: isAboveOne, fill: fillZipf},
+}
+
+// Names returns the distributions, with their parameter if they have one, to be shown in help messages.
+// For example "nearly-sorted[:swaps]": the number of swaps can be given after a colon, like nearly-sorted:100.
+func Names() []string {
+	names := make([]string, len(distributions))
+	for i, d := range distributions {
+		names[i] = d.name
+		if d.parameter != "" {
+			names[i] += "[:" + d.parameter + "]"
+		}
+	}
+	return names
+}
+
+// New returns a generator of the distribution given like "uniform" or "nearly-sorted:100", with values between min (included) and max (excluded).
+//
+// The parameters are:
+//   - nearly-sorted: the number of pairs of values swapped in a sorted slice (10 by default)
+//   - sawtooth: the number of ascending runs (4 by default)
+//   - few-unique: the number of different values (8 by default)
+//   - gaussian: the standard deviation, as a part of max-min (1/6 by default, so that almost all the values are in the range)
+//   - zipf: the exponent, above 1 (1.1 by default). The bigger it is, the more the small values are frequent.
+func New(spec string, min, max int, seed int64) (Generator, error) {
+	name, parameter, hasParameter := strings.Cut(spec, ":")
+	var d *distribution
+	for i := range distributions {
+		if distributions[i].name == name {
+			d = &distributions[i]
+		}
+	}
+	if d == nil {
+		return Generator{}, fmt.Errorf("unknown distribution %q (available: %s)", name, strings.Join(Names(), ", "))
+	}
+	if max <= min {
+		return Generator{}, fmt.Errorf("the maximum value must be above the minimum, got %d and %d", max, min)
+	}
+
+	g := Generator{Distribution: d.name, Parameter: d.defaultParameter, Min: min, Max: max, Seed: seed}
+	if hasParameter {
+		if d.parameter == "" {
+			return Generator{}, fmt.Errorf("the %s distribution has no parameter, got %q", d.name, spec)
+		}
+		value, err := strconv.ParseFloat(parameter, 64)
+		if err != nil || !d.valid(value) {
+			return Generator{}, fmt.Errorf("invalid %s for the %s distribution: %q", d.parameter, d.name, parameter)
+		}
+		g.Parameter = value
+	}
+	return g, nil
+}
+
+// String returns the distribution as given to New, with its parameter if it has one.
+func (g Generator) String() string {
+	for _, d := range distributions {
+		if d.name == g.Distribution && d.parameter != "" {
+			return fmt.Sprintf("%s:%g", g.Distribution, g.Parameter)
+		}
+	}
+	return g.Distribution
+}
+
+// Generate returns a new slice of size values. It is always the same one for the same generator and size.
+func (g Generator) Generate(size int) []int {
+	s := make([]int, size)
+	g.Fill(s)
+	return s
+}
+
+// Fill fills the slice with values of the distribution.
+func (g Generator) Fill(s []int) {
+	random := rand.New(rand.NewSource(g.Seed))
+	for _, d := range distributions {
+		if d.name == g.Distribution {
+			d.fill(s, g, random)
+			return
+		}
+	}
+	panic("generate: unknown distribution " + g.Distribution + ", use New to get a generator")
+}
+
+func isCount(min float64) func(float64) bool {
+	return func(p float64) bool {
+		return p >= min && p == math.Trunc(p) && p <= math.MaxInt32
+	}
+}
+
+func isPositive(p float64) bool { return p > 0 && !math.IsInf(p, 1) }
+func isAboveOne(p float64) bool { return p > 1 && !math.IsInf(p, 1) }
+
+// uniform returns a random value between min (included) and max (excluded).
+// The width of the range is computed on unsigned numbers, as max - min overflows an int when the range is wider than MaxInt64.
+func uniform(random *rand.Rand, min, max int) int {
+	width := uint64(max) - uint64(min)
+	if width <= math.MaxInt64 {
+		return min + int(random.Int63n(int64(width)))
+	}
+	// Int63n can't draw that many values: we draw any 64 bits value until it is in the range, which at least one in two is.
+	for {
+		if v := random.Uint64(); v < width {
+			return int(uint64(min) + v)
+		}
+	}
+}
+
+// scale turns i, between 0 and steps, into a value between min and max: the patterns go over the whole range whatever the size of the slice.
+// Like in uniform, the offset from min is unsigned, so that it can go over MaxInt64.
+func scale(i, steps int, g Generator) int {
+	width := float64(uint64(g.Max) - uint64(g.Min))
+	return int(uint64(g.Min) + uint64(float64(i)/float64(steps)*width))
+}
+
+func fillUniform(s []int, g Generator, random *rand.Rand) {
+	for i := range s {
+		s[i] = uniform(random, g.Min, g.Max)
+	}
+}
+
+func fillSorted(s []int, g Generator, random *rand.Rand) {
+	fillUniform(s, g, random)
+	sort.Ints(s)
+}
+
+func fillReversed(s []int, g Generator, random *rand.Rand) {
+	fillSorted(s, g, random)
+	for i, j := 0, len(s)-1; i < j; i, j = i+1, j-1 {
+		s[i], s[j] = s[j], s[i]
+	}
+}
+
+// fillNearlySorted swaps random pairs of a sorted slice, like a sorted list where a few values were added at the wrong place.
+func fillNearlySorted(s []int, g Generator, random *rand.Rand) {
+	fillSorted(s, g, random)
+	if len(s) < 2 {
+		return
+	}
+	for k := 0; k < int(g.Parameter); k++ {
+		i, j := random.Intn(len(s)), random.Intn(len(s))
+		s[i], s[j] = s[j], s[i]
+	}
+}
+
+// fillOrganPipe goes up then down, like the pipes of an organ: 0 1 2 3 3 2 1 0.
+func fillOrganPipe(s []int, g Generator, random *rand.Rand) {
+	half := (len(s) + 1) / 2
+	for i := range s {
+		if i < half {
+			s[i] = scale(i, half, g)
+		} else {
+			s[i] = scale(len(s)-1-i, half, g)
+		}
+	}
+}
+
+// fillSawtooth goes up again and again, like the teeth of a saw: 0 1 2 0 1 2 0 1 2.
+func fillSawtooth(s []int, g Generator, random *rand.Rand) {
+	teeth := int(g.Parameter)
+	length := (len(s) + teeth - 1) / teeth
+	for i := range s {
+		s[i] = scale(i%length, length, g)
+	}
+}
+
+// fillFewUnique only uses a few values, spread over the range, in a random order.
+func fillFewUnique(s []int, g Generator, random *rand.Rand) {
+	values := int(g.Parameter)
+	for i := range s {
+		s[i] = scale(random.Intn(values), values, g)
+	}
+}
+
+// fillGaussian gives values around the middle of the range, fewer and fewer away from it (a bell curve).
+// The few values that would be out of the range are moved to its ends.
+func fillGaussian(s []int, g Generator, random *rand.Rand) {
+	width := float64(g.Max) - float64(g.Min)
+	mean := float64(g.Min) + width/2
+	for i := range s {
+		v := math.Round(mean + random.NormFloat64()*g.Parameter*width)
+		// We compare before converting: a float64 too big for an int does not convert to the biggest int.
+		switch {
+		case v < float64(g.Min):
+			s[i] = g.Min
+		case v >= float64(g.Max):
+			s[i] = g.Max - 1
+		default:
+			s[i] = int(v)
+		}
+	}
+}
+
+// fillZipf gives a lot of small values and a few big ones, like the frequencies of the words of a language:
+// Min is the most frequent value, then Min+1, and so on.
+func fillZipf(s []int, g Generator, random *rand.Rand) {
+	zipf := rand.NewZipf(random, g.Parameter, 1, uint64(g.Max)-uint64(g.Min)-1)
+	for i := range s {
+		s[i] = int(uint64(g.Min) + zipf.Uint64())
+	}
+}
+
+// Flags are the command line flags of the programs that generate their input: -dist, -seed, -min and -max.
+type Flags struct {
+	Distribution string
+	Seed         int64
+	SeedGiven    bool // Without -seed, the seed comes from the clock, and programs print it so that the run can be repeated.
+	Min, Max     int
+}
+
+// Register adds the flags to a flag set.
+func (f *Flags) Register(flags *flag.FlagSet) {
+	flags.StringVar(&f.Distribution, "dist", "uniform", "distribution of the generated values: "+strings.Join(Names(), ", "))
+	flags.Var((*seedFlag)(f), "seed", "seed of the generated values: the same seed gives the same values (default: from the clock)")
+	flags.IntVar(&f.Min, "min", 0, "smallest generated value")
+	flags.IntVar(&f.Max, "max", 1_000_000, "generated values are below this one")
+}
+
+// Generator returns the generator chosen with the flags.
+func (f *Flags) Generator() (Generator, error) {
+	if !f.SeedGiven {
+		f.Seed = time.Now().UnixNano()
+	}
+	return New(f.Distribution, f.Min, f.Max, f.Seed)
+}
+
+// seedFlag is the -seed flag: it remembers it was given.
+type seedFlag Flags
+
+func (s *seedFlag) String() string {
+	if s == nil || !s.SeedGiven {
+		return ""
+	}
+	return strconv.FormatInt(s.Seed, 10)
+}
+
+func (s *seedFlag) Set(value string) error {
+	seed, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return fmt.Errorf("the seed must be an integer")
+	}
+	s.Seed, s.SeedGiven = seed, true
+	return nil
+}
@@ -0,0 +1,218 @@
+package generate
+
+import (
+	"flag"
+	"io"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestGenerate(t *testing.T) {
+	tests := []struct {
+		spec  string
+		check func(s []int) bool // What the slice must look like, besides having values in the range.
+	}{
+		{"uniform", func(s []int) bool { return distinct(s) > 900 }},
+		{"sorted", sort.IntsAreSorted},
+		{"reversed", func(s []int) bool { return sort.IsSorted(sort.Reverse(sort.IntSlice(s))) }},
+		{"nearly-sorted", func(s []int) bool { return !sort.IntsAreSorted(s) && outOfOrder(s) <= 2*10 }},
+		{"nearly-sorted:0", sort.IntsAreSorted},
+		{"organ-pipe", func(s []int) bool {
+			return sort.IntsAreSorted(s[:500]) && sort.IsSorted(sort.Reverse(sort.IntSlice(s[500:]))) && s[0] == s[len(s)-1]
+		}},
+		{"sawtooth", func(s []int) bool { return outOfOrder(s) == 3 }},
+		{"sawtooth:10", func(s []int) bool { return outOfOrder(s) == 9 }},
+		{"few-unique", func(s []int) bool { return distinct(s) == 8 }},
+		{"few-unique:3", func(s []int) bool { return distinct(s) == 3 }},
+		{"gaussian", func(s []int) bool {
+			// About two thirds of the values are within one standard deviation of the middle.
+			near := 0
+			for _, v := range s {
+				if math.Abs(float64(v)-5000) <= 10000.0/6 {
+					near++
+				}
+			}
+			return near > 600 && near < 750
+		}},
+		{"zipf", func(s []int) bool {
+			// The smallest value is by far the most frequent.
+			counts := map[int]int{}
+			for _, v := range s {
+				counts[v]++
+			}
+			return counts[0] > counts[1] && counts[1] > counts[100] && counts[0] > 100
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			g, err := New(tt.spec, 0, 10_000, 42)
+			if err != nil {
+				t.Fatal(err)
+			}
+			s := g.Generate(1000)
+			if len(s) != 1000 {
+				t.Fatalf("got %d values; want 1000", len(s))
+			}
+			for _, v := range s {
+				if v < 0 || v >= 10_000 {
+					t.Fatalf("%d is out of the range [0, 10000)", v)
+				}
+			}
+			if !tt.check(s) {
+				t.Errorf("the values don't look like %s: %v", tt.spec, s[:20])
+			}
+
+			// The same generator always gives the same values.
+			if again := g.Generate(1000); !reflect.DeepEqual(s, again) {
+				t.Error("the same generator gave different values")
+			}
+			for _, size := range []int{0, 1, 2, 3} {
+				if got := g.Generate(size); len(got) != size {
+					t.Errorf("Generate(%d): got %d values", size, len(got))
+				}
+			}
+		})
+	}
+}
+
+func TestGenerateWholeRange(t *testing.T) {
+	// From the smallest int to the biggest one, max - min does not fit in an int: every distribution must still stay in the range.
+	tests := map[string]func(s []int) bool{
+		"uniform":       func(s []int) bool { return distinct(s) == len(s) && s[0] != s[1] },
+		"sorted":        sort.IntsAreSorted,
+		"reversed":      func(s []int) bool { return sort.IsSorted(sort.Reverse(sort.IntSlice(s))) },
+		"nearly-sorted": func(s []int) bool { return outOfOrder(s) <= 2*10 },
+		"organ-pipe": func(s []int) bool {
+			return sort.IntsAreSorted(s[:500]) && sort.IsSorted(sort.Reverse(sort.IntSlice(s[500:]))) && s[0] == math.MinInt64 && s[499] > 0
+		},
+		"sawtooth":   func(s []int) bool { return outOfOrder(s) == 3 },
+		"few-unique": func(s []int) bool { return distinct(s) == 8 },
+		"gaussian":   func(s []int) bool { return distinct(s) > 900 },
+		"zipf": func(s []int) bool {
+			// The smallest value is still the most frequent, and most values are small.
+			counts := map[int]int{}
+			small := 0
+			for _, v := range s {
+				counts[v]++
+				if v < math.MinInt64+1_000_000 {
+					small++
+				}
+			}
+			for _, count := range counts {
+				if count > counts[math.MinInt64] {
+					return false
+				}
+			}
+			return small > len(s)/2
+		},
+	}
+	for _, d := range distributions {
+		check, ok := tests[d.name]
+		if !ok {
+			t.Fatalf("no check for the %s distribution", d.name)
+		}
+		t.Run(d.name, func(t *testing.T) {
+			g, err := New(d.name, math.MinInt64, math.MaxInt64, 5)
+			if err != nil {
+				t.Fatal(err)
+			}
+			s := g.Generate(1000)
+			for _, v := range s {
+				if v == math.MaxInt64 {
+					t.Fatalf("got %d, which is the maximum: it is excluded", v)
+				}
+			}
+			if !check(s) {
+				t.Errorf("the values don't look like %s: %v", d.name, s[:20])
+			}
+		})
+	}
+}
+
+func TestSeed(t *testing.T) {
+	a, _ := New("uniform", -50, 50, 1)
+	b, _ := New("uniform", -50, 50, 2)
+	if reflect.DeepEqual(a.Generate(100), b.Generate(100)) {
+		t.Error("two seeds gave the same values")
+	}
+}
+
+func TestNewErrors(t *testing.T) {
+	tests := []struct {
+		spec     string
+		min, max int
+		want     string
+	}{
+		{"random", 0, 10, "unknown distribution"},
+		{"uniform", 10, 10, "must be above the minimum"},
+		{"uniform:3", 0, 10, "has no parameter"},
+		{"nearly-sorted:-1", 0, 10, "invalid swaps"},
+		{"sawtooth:0", 0, 10, "invalid teeth"},
+		{"few-unique:2.5", 0, 10, "invalid values"},
+		{"gaussian:0", 0, 10, "invalid deviation"},
+		{"zipf:1", 0, 10, "invalid exponent"},
+		{"zipf:x", 0, 10, "invalid exponent"},
+	}
+	for _, tt := range tests {
+		if _, err := New(tt.spec, tt.min, tt.max, 0); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("New(%q, %d, %d): got error %v; want one with %q", tt.spec, tt.min, tt.max, err, tt.want)
+		}
+	}
+}
+
+func TestString(t *testing.T) {
+	for spec, want := range map[string]string{"uniform": "uniform", "sawtooth": "sawtooth:4", "zipf:1.5": "zipf:1.5"} {
+		g, err := New(spec, 0, 10, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if g.String() != want {
+			t.Errorf("New(%q).String(): got %q; want %q", spec, g.String(), want)
+		}
+	}
+}
+
+func TestFlags(t *testing.T) {
+	var f Flags
+	flags := flag.NewFlagSet("test", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	f.Register(flags)
+	if err := flags.Parse([]string{"-dist", "few-unique:2", "-seed", "7", "-min", "5", "-max", "9"}); err != nil {
+		t.Fatal(err)
+	}
+	g, err := f.Generator()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (Generator{Distribution: "few-unique", Parameter: 2, Min: 5, Max: 9, Seed: 7}); g != want {
+		t.Errorf("got %+v; want %+v", g, want)
+	}
+
+	if err := flags.Parse([]string{"-seed", "abc"}); err == nil {
+		t.Error("-seed abc: want an error")
+	}
+}
+
+// distinct returns the number of different values.
+func distinct(s []int) int {
+	seen := map[int]bool{}
+	for _, v := range s {
+		seen[v] = true
+	}
+	return len(seen)
+}
+
+// outOfOrder returns the number of values smaller than the one before them.
+func outOfOrder(s []int) int {
+	n := 0
+	for i := 1; i < len(s); i++ {
+		if s[i] < s[i-1] {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,297 @@
+package kinematics
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+// Fitting is the other way around: we have measured positions at some times, and we want the acceleration, initial velocity
+// and initial displacement of the motion. We pick the ones that make the sum of the squares of the errors the smallest (least squares).
+
+// Sample is a measured position S at the time T.
+type Sample struct {
+	T float64
+	S float64
+}
+
+// Estimate is a fitted value, with its standard error and its confidence interval: the true value is between Low and High,
+// with the confidence of the fit (95% of the time by default).
+type Estimate struct {
+	Value         float64
+	StandardError float64
+	Low           float64
+	High          float64
+}
+
+// Fit is the result of FitSamples.
+type Fit struct {
+	Motion              Kinematics // The fitted motion, ready to use.
+	Acceleration        Estimate
+	InitialVelocity     Estimate
+	InitialDisplacement Estimate
+	Confidence          float64   // The confidence of the intervals, like 0.95.
+	Residuals           []float64 // For each sample, the measured position minus the fitted one.
+	RSquared            float64   // How much of the variation of the positions the motion explains, 1 being all of it.
+}
+
+// DisplaceFn returns the displacement function of the fitted motion, like GenDisplaceFn would.
+func (f *Fit) DisplaceFn() func(t float64) float64 {
+	return f.Motion.Displacement
+}
+
+// ErrNotEnoughSamples is returned by FitSamples when the samples can't give the three parameters and their uncertainty.
+var ErrNotEnoughSamples = errors.New("at least 4 samples, at 3 different times or more, are needed to fit a motion")
+
+// FitSamples finds the motion with a constant acceleration that is the closest to the samples, and how sure we can be of it.
+// confidence is the confidence of the intervals, between 0 and 1 (0.95 is the usual choice).
+//
+// Three samples would be enough to find the motion, but then it goes through all of them and we can't tell how precise it is:
+// FitSamples needs at least four.
+func FitSamples(samples []Sample, confidence float64) (*Fit, error) {
+	if !(confidence > 0 && confidence < 1) {
+		return nil, fmt.Errorf("the confidence must be between 0 and 1, got %v", confidence)
+	}
+	n := len(samples)
+	if n < 4 {
+		return nil, ErrNotEnoughSamples
+	}
+
+	// The motion is s = c0 + c1 t + c2 t², with c2 = a/2. With times like 1700000000 (a clock in seconds), t² is so big
+	// that the equations lose all their precision. So we first fit s = g0 + g1 u + g2 u², with u = (t - mean) / scale,
+	// which goes from -1 to 1, and then go back to t.
+	mean, scale := 0.0, 0.0
+	for _, sample := range samples {
+		mean += sample.T
+	}
+	mean /= float64(n)
+	for _, sample := range samples {
+		scale = math.Max(scale, math.Abs(sample.T-mean))
+	}
+	if scale == 0 {
+		return nil, ErrNotEnoughSamples
+	}
+
+	// The normal equations: (XᵀX) g = Xᵀs, where each row of X is 1, u, u².
+	var xtx [3][3]float64
+	var xts [3]float64
+	for _, sample := range samples {
+		u := (sample.T - mean) / scale
+		row := [3]float64{1, u, u * u}
+		for i := range row {
+			for j := range row {
+				xtx[i][j] += row[i] * row[j]
+			}
+			xts[i] += row[i] * sample.S
+		}
+	}
+	inverse, ok := invert3(xtx)
+	if !ok {
+		// All the samples are at one or two different times.
+		return nil, ErrNotEnoughSamples
+	}
+	var g [3]float64
+	for i := range g {
+		for j := range xts {
+			g[i] += inverse[i][j] * xts[j]
+		}
+	}
+
+	// Back to t: expanding g0 + g1 (t - m)/d + g2 (t - m)²/d² gives c = M g, with the matrix M below.
+	m, d := mean, scale
+	transform := [3][3]float64{
+		{1, -m / d, m * m / (d * d)},
+		{0, 1 / d, -2 * m / (d * d)},
+		{0, 0, 1 / (d * d)},
+	}
+	c := multiplyVector(transform, g)
+	motion := New(2*c[2], c[1], c[0])
+
+	// How far the samples are from the fitted motion.
+	residuals := make([]float64, n)
+	sumOfSquares, average := 0.0, 0.0
+	for i, sample := range samples {
+		residuals[i] = sample.S - motion.Displacement(sample.T)
+		sumOfSquares += residuals[i] * residuals[i]
+		average += sample.S
+	}
+	average /= float64(n)
+	total := 0.0
+	for _, sample := range samples {
+		total += (sample.S - average) * (sample.S - average)
+	}
+	rSquared := 1.0
+	if total > 0 {
+		rSquared = 1 - sumOfSquares/total
+	}
+
+	// The covariance of g is σ² (XᵀX)⁻¹, where σ² is estimated from the residuals, with 3 degrees of freedom used by the fit.
+	// The covariance of c = M g is M cov(g) Mᵀ, and we only need its diagonal.
+	dof := n - 3
+	variance := sumOfSquares / float64(dof)
+	covariance := multiply(multiply(transform, inverse), transpose(transform))
+	quantile := studentQuantile(1-(1-confidence)/2, float64(dof))
+	estimate := func(value, scaleOfValue float64, i int) Estimate {
+		standardError := math.Abs(scaleOfValue) * math.Sqrt(math.Max(0, variance*covariance[i][i]))
+		return Estimate{Value: value, StandardError: standardError, Low: value - quantile*standardError, High: value + quantile*standardError}
+	}
+
+	return &Fit{
+		Motion:              motion,
+		Acceleration:        estimate(motion.A, 2, 2),
+		InitialVelocity:     estimate(motion.V0, 1, 1),
+		InitialDisplacement: estimate(motion.S0, 1, 0),
+		Confidence:          confidence,
+		Residuals:           residuals,
+		RSquared:            rSquared,
+	}, nil
+}
+
+// invert3 inverts a 3×3 matrix with the Gauss-Jordan elimination. It returns false when the matrix can't be inverted.
+func invert3(matrix [3][3]float64) (inverse [3][3]float64, ok bool) {
+	// We turn matrix into the identity, doing the same operations on inverse, which starts as the identity.
+	largest := 0.0
+	for i := range inverse {
+		inverse[i][i] = 1
+		for j := range matrix[i] {
+			largest = math.Max(largest, math.Abs(matrix[i][j]))
+		}
+	}
+
+	for column := 0; column < 3; column++ {
+		// Use the row with the biggest value in this column, to divide by the biggest number we can (partial pivoting).
+		pivot := column
+		for row := column + 1; row < 3; row++ {
+			if math.Abs(matrix[row][column]) > math.Abs(matrix[pivot][column]) {
+				pivot = row
+			}
+		}
+		if math.Abs(matrix[pivot][column]) <= 1e-12*largest {
+			return inverse, false
+		}
+		matrix[column], matrix[pivot] = matrix[pivot], matrix[column]
+		inverse[column], inverse[pivot] = inverse[pivot], inverse[column]
+
+		p := matrix[column][column]
+		for j := 0; j < 3; j++ {
+			matrix[column][j] /= p
+			inverse[column][j] /= p
+		}
+		for row := 0; row < 3; row++ {
+			if row == column {
+				continue
+			}
+			factor := matrix[row][column]
+			for j := 0; j < 3; j++ {
+				matrix[row][j] -= factor * matrix[column][j]
+				inverse[row][j] -= factor * inverse[column][j]
+			}
+		}
+	}
+	return inverse, true
+}
+
+func multiply(a, b [3][3]float64) (product [3][3]float64) {
+	for i := range a {
+		for j := range b[0] {
+			for k := range b {
+				product[i][j] += a[i][k] * b[k][j]
+			}
+		}
+	}
+	return product
+}
+
+func multiplyVector(a [3][3]float64, v [3]float64) (product [3]float64) {
+	for i := range a {
+		for j := range v {
+			product[i] += a[i][j] * v[j]
+		}
+	}
+	return product
+}
+
+func transpose(a [3][3]float64) (t [3][3]float64) {
+	for i := range a {
+		for j := range a[i] {
+			t[j][i] = a[i][j]
+		}
+	}
+	return t
+}
+
+// studentQuantile returns the value t such that a Student's t-distribution with dof degrees of freedom is below t with probability p (p ≥ 0.5).
+// With few samples, the estimated variance is itself uncertain, and the intervals must be wider than with the normal distribution: this is what t does.
+// There is no formula, so we search for t by bisection, the distribution function being an incomplete beta function.
+func studentQuantile(p, dof float64) float64 {
+	cdf := func(t float64) float64 {
+		return 1 - 0.5*incompleteBeta(dof/(dof+t*t), dof/2, 0.5)
+	}
+
+	low, high := 0.0, 1.0
+	for cdf(high) < p {
+		high *= 2
+	}
+	for i := 0; i < 200 && high-low > 1e-12*high; i++ {
+		middle := (low + high) / 2
+		if cdf(middle) < p {
+			low = middle
+		} else {
+			high = middle
+		}
+	}
+	return (low + high) / 2
+}
+
+// incompleteBeta returns the regularized incomplete beta function I_x(a, b), with its continued fraction (the modified Lentz's method).
+func incompleteBeta(x, a, b float64) float64 {
+	switch {
+	case x <= 0:
+		return 0
+	case x >= 1:
+		return 1
+	}
+	// The continued fraction converges quickly only for x < (a+1)/(a+b+2). Otherwise we use I_x(a, b) = 1 - I_(1-x)(b, a).
+	if x > (a+1)/(a+b+2) {
+		return 1 - incompleteBeta(1-x, b, a)
+	}
+
+	lbeta := func(a, b float64) float64 {
+		la, _ := math.Lgamma(a)
+		lb, _ := math.Lgamma(b)
+		lab, _ := math.Lgamma(a + b)
+		return la + lb - lab
+	}
+	front := math.Exp(a*math.Log(x)+b*math.Log(1-x)-lbeta(a, b)) / a
+
+	const tiny = 1e-300
+	f, c, d := 1.0, 1.0, 0.0
+	for i := 0; i <= 300; i++ {
+		// The terms of the fraction alternate between the odd ones and the even ones.
+		m := float64(i / 2)
+		var numerator float64
+		switch {
+		case i == 0:
+			numerator = 1
+		case i%2 == 0:
+			numerator = m * (b - m) * x / ((a + 2*m - 1) * (a + 2*m))
+		default:
+			numerator = -(a + m) * (a + b + m) * x / ((a + 2*m) * (a + 2*m + 1))
+		}
+
+		d = 1 + numerator*d
+		if math.Abs(d) < tiny {
+			d = tiny
+		}
+		d = 1 / d
+		c = 1 + numerator/c
+		if math.Abs(c) < tiny {
+			c = tiny
+		}
+		f *= c * d
+		if math.Abs(1-c*d) < 1e-15 {
+			break
+		}
+	}
+	return front * (f - 1)
+}
@@ -0,0 +1,156 @@
+package kinematics
+
+import (
+	"fmt"
+	"math"
+)
+
+// GenDisplaceFn and Kinematics assume a constant acceleration, which has an exact formula.
+// When the acceleration changes with the time, the position or the velocity (drag, springs, thrust...), there is usually no formula:
+// we compute the motion step by step instead, with one of the methods below.
+
+// AccelerationFn gives the acceleration at the time t, for the position s and the velocity v.
+// For example, a spring pulling back to 0 is func(t, s, v float64) float64 { return -k * s }.
+type AccelerationFn func(t, s, v float64) float64
+
+// Method is a way to compute the next step of a motion from the current one.
+type Method int
+
+const (
+	// Euler moves with the velocity and the acceleration of the start of the step. It is the simplest, and the least precise.
+	Euler Method = iota
+	// SemiImplicitEuler updates the velocity first, then moves with the new velocity. It is as cheap as Euler,
+	// but keeps the energy of oscillations (springs, orbits) instead of making it grow.
+	SemiImplicitEuler
+	// Verlet (velocity Verlet) uses the average of the accelerations at the start and at the end of the step.
+	// It is exact for a constant acceleration.
+	Verlet
+	// RK4 is the classic Runge-Kutta method: it tries four slopes in the step and averages them. It is very precise.
+	RK4
+)
+
+// Methods are all the integration methods, from the least to the most precise.
+var Methods = []Method{Euler, SemiImplicitEuler, Verlet, RK4}
+
+func (m Method) String() string {
+	switch m {
+	case Euler:
+		return "euler"
+	case SemiImplicitEuler:
+		return "semi-implicit-euler"
+	case Verlet:
+		return "verlet"
+	case RK4:
+		return "rk4"
+	}
+	return fmt.Sprintf("Method(%d)", int(m))
+}
+
+// Order is how fast the error goes down when the step gets smaller: with order p, dividing the step by 2 divides the error by 2^p.
+func (m Method) Order() int {
+	switch m {
+	case Verlet:
+		return 2
+	case RK4:
+		return 4
+	}
+	return 1
+}
+
+// State is where a body is, and how fast it goes, at a given time.
+type State struct {
+	T float64 // Time.
+	S float64 // Position.
+	V float64 // Velocity.
+}
+
+// Step returns the state h later (h can be negative, to go back in time).
+func (m Method) Step(a AccelerationFn, state State, h float64) State {
+	t, s, v := state.T, state.S, state.V
+
+	switch m {
+	case Euler:
+		return State{T: t + h, S: s + v*h, V: v + a(t, s, v)*h}
+
+	case SemiImplicitEuler:
+		v1 := v + a(t, s, v)*h
+		return State{T: t + h, S: s + v1*h, V: v1}
+
+	case Verlet:
+		a0 := a(t, s, v)
+		s1 := s + v*h + 0.5*a0*h*h
+		// The acceleration at the end of the step may depend on the velocity at the end of the step, which we don't know yet:
+		// we use the one Euler would give.
+		a1 := a(t+h, s1, v+a0*h)
+		return State{T: t + h, S: s1, V: v + 0.5*(a0+a1)*h}
+
+	case RK4:
+		// Each k is a slope of the position (a velocity) and of the velocity (an acceleration).
+		ks1, kv1 := v, a(t, s, v)
+		ks2, kv2 := v+0.5*h*kv1, a(t+0.5*h, s+0.5*h*ks1, v+0.5*h*kv1)
+		ks3, kv3 := v+0.5*h*kv2, a(t+0.5*h, s+0.5*h*ks2, v+0.5*h*kv2)
+		ks4, kv4 := v+h*kv3, a(t+h, s+h*ks3, v+h*kv3)
+		return State{
+			T: t + h,
+			S: s + h/6*(ks1+2*ks2+2*ks3+ks4),
+			V: v + h/6*(kv1+2*kv2+2*kv3+kv4),
+		}
+	}
+	panic(fmt.Sprintf("kinematics: unknown integration method %d", int(m)))
+}
+
+// GenIntegratedDisplaceFn is GenDisplaceFn for any acceleration: it returns a function which computes the position at time t,
+// starting from the position s0 with the velocity v0 at time 0, by taking steps of the given size with the given method.
+// The last step is shortened so that it ends exactly at t.
+//
+// The function remembers where it stopped, so asking for increasing times (like a range) does not start again from 0 each time.
+// That makes it unsafe to call from several goroutines at once.
+// It returns NaN for an infinite time or NaN: no number of steps reaches them.
+func GenIntegratedDisplaceFn(a AccelerationFn, v0, s0 float64, method Method, step float64) (func(t float64) float64, error) {
+	if !(step > 0) || math.IsInf(step, 0) {
+		return nil, fmt.Errorf("the step must be a positive number, got %v", step)
+	}
+	if method < Euler || method > RK4 {
+		return nil, fmt.Errorf("unknown integration method %v", method)
+	}
+
+	start := State{S: s0, V: v0}
+	// last is the last state on the grid of steps (0, step, 2 step...) we reached, before a shortened step, and lastIndex is its number on the grid.
+	// Going on from it gives exactly the same result as starting again from 0.
+	last, lastIndex := start, 0.0
+
+	return func(t float64) float64 {
+		if math.IsInf(t, 0) || math.IsNaN(t) {
+			return math.NaN()
+		}
+		from, index, h := start, 0.0, step
+		switch {
+		case t < 0:
+			// Going back in time is less common: we start from 0 every time, with negative steps.
+			h = -step
+		case t >= last.T:
+			from, index = last, lastIndex
+		}
+
+		grid, gridIndex := walkGrid(a, method, from, index, h, t)
+		if t >= 0 {
+			last, lastIndex = grid, gridIndex
+		}
+		if grid.T == t {
+			return grid.S
+		}
+		return method.Step(a, grid, t-grid.T).S
+	}, nil
+}
+
+// walkGrid takes steps of size h from state, the index-th state of the grid, as long as they don't go past t.
+// It returns the last state reached and its index.
+// The time of a state is its index times h, instead of the sum of the steps, so that rounding errors don't pile up.
+func walkGrid(a AccelerationFn, method Method, state State, index, h, t float64) (State, float64) {
+	for n := math.Floor(t / h); index < n; {
+		state = method.Step(a, state, h)
+		index++
+		state.T = index * h
+	}
+	return state, index
+}
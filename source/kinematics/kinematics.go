@@ -0,0 +1,132 @@
+// Package kinematics describes the motion of a body along a line with a constant acceleration,
+// the way the ComputeDisplacement program does with GenDisplaceFn, and a lot more:
+// velocity, time to reach a position, stopping time and distance, motions made of several phases,
+// projectiles in 2-D or 3-D (see Motion), and motions with any acceleration, computed step by step (see GenIntegratedDisplaceFn).
+//
+// All the values are in consistent units (for example metres, seconds, m/s and m/s²).
+package kinematics
+
+import (
+	"errors"
+	"math"
+)
+
+// Kinematics is a motion with a constant acceleration A, starting at the position S0 with the velocity V0 at time 0.
+type Kinematics struct {
+	A  float64 // Acceleration.
+	V0 float64 // Initial velocity.
+	S0 float64 // Initial displacement (position at time 0).
+}
+
+// New returns the motion with acceleration a, initial velocity v0 and initial displacement s0, the same parameters as GenDisplaceFn.
+func New(a, v0, s0 float64) Kinematics {
+	return Kinematics{A: a, V0: v0, S0: s0}
+}
+
+// Displacement returns the position at time t: s = ½ a t² + v0 t + s0
+func (k Kinematics) Displacement(t float64) float64 {
+	return 0.5*k.A*t*t + k.V0*t + k.S0
+}
+
+// Velocity returns the velocity at time t: v = a t + v0
+func (k Kinematics) Velocity(t float64) float64 {
+	return k.A*t + k.V0
+}
+
+// ErrAlwaysThere is returned by TimesAt when the body never moves and is always at the position asked for:
+// every time is a solution, so there is no list of times to return.
+var ErrAlwaysThere = errors.New("the body never moves and is always at this position")
+
+// TimesAt returns the times at which the body is at the position s, from the earliest to the latest.
+// We solve ½ a t² + v0 t + (s0 - s) = 0, so there can be zero, one or two times.
+// Times before 0 are solutions too (the motion is the same before time 0), it is up to the caller to ignore them if needed.
+func (k Kinematics) TimesAt(s float64) ([]float64, error) {
+	a, b, c := 0.5*k.A, k.V0, k.S0-s
+
+	// Without acceleration, the equation is linear: v0 t + c = 0.
+	if a == 0 {
+		if b == 0 {
+			if c == 0 {
+				return nil, ErrAlwaysThere
+			}
+			return nil, nil
+		}
+		return []float64{-c / b}, nil
+	}
+
+	discriminant := b*b - 4*a*c
+	switch {
+	case discriminant < 0:
+		// The body turns back before reaching s.
+		return nil, nil
+	case discriminant == 0:
+		// The body just touches s when it turns back.
+		return []float64{-b / (2 * a)}, nil
+	}
+
+	// We don't use the (-b ± √Δ) / 2a formula for both roots: when b² is much bigger than 4ac, -b + √Δ subtracts two nearly equal numbers
+	// and loses most of its precision. Instead, we compute the root that adds numbers of the same sign, and get the other one from
+	// the product of the roots, which is c / a.
+	q := -0.5 * (b + math.Copysign(math.Sqrt(discriminant), b))
+	t1, t2 := q/a, c/q
+	if t1 > t2 {
+		t1, t2 = t2, t1
+	}
+	return []float64{t1, t2}, nil
+}
+
+// StoppingTime returns the time at which the velocity becomes 0, if it ever does after time 0.
+// A body that does not move at time 0 stops at time 0. A body that speeds up, or that keeps a constant non-zero velocity, never stops.
+func (k Kinematics) StoppingTime() (t float64, ok bool) {
+	if k.V0 == 0 {
+		return 0, true
+	}
+	// The acceleration must be against the velocity.
+	if k.A == 0 || math.Signbit(k.A) == math.Signbit(k.V0) {
+		return 0, false
+	}
+	return -k.V0 / k.A, true
+}
+
+// StoppingDistance returns the distance travelled from time 0 until the body stops, if it ever does (see StoppingTime).
+// It is v0² / 2|a|, counted in the direction of the motion, so it is never negative.
+func (k Kinematics) StoppingDistance() (d float64, ok bool) {
+	t, ok := k.StoppingTime()
+	if !ok {
+		return 0, false
+	}
+	return math.Abs(k.Displacement(t) - k.S0), true
+}
+
+// Extremum is a time at which the displacement is the highest or the lowest, and that displacement.
+type Extremum struct {
+	Time         float64
+	Displacement float64
+}
+
+// Extremes returns the lowest and the highest displacements between the times from and to (both included), and when they happen.
+// They are exact: the displacement is a parabola, so they are either at one end of the range or where the body turns back.
+// When the same displacement happens several times, we return the earliest one.
+func (k Kinematics) Extremes(from, to float64) (min, max Extremum) {
+	candidates := []float64{from}
+	// The body turns back when its velocity is 0.
+	if k.A != 0 {
+		if t := -k.V0 / k.A; t > from && t < to {
+			candidates = append(candidates, t)
+		}
+	}
+	candidates = append(candidates, to)
+
+	min = Extremum{Time: from, Displacement: k.Displacement(from)}
+	max = min
+	for _, t := range candidates[1:] {
+		s := k.Displacement(t)
+		if s < min.Displacement {
+			min = Extremum{Time: t, Displacement: s}
+		}
+		if s > max.Displacement {
+			max = Extremum{Time: t, Displacement: s}
+		}
+	}
+	return min, max
+}
@@ -0,0 +1,616 @@
+package kinematics
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+// closeTo tells if two floats are equal, give or take the rounding errors.
+func closeTo(a, b float64) bool {
+	return math.Abs(a-b) <= 1e-9*math.Max(1, math.Max(math.Abs(a), math.Abs(b)))
+}
+
+func TestDisplacementAndVelocity(t *testing.T) {
+	tests := []struct {
+		name             string
+		k                Kinematics
+		t                float64
+		wantDisplacement float64
+		wantVelocity     float64
+	}{
+		{name: "at rest", k: New(0, 0, 5), t: 10, wantDisplacement: 5, wantVelocity: 0},
+		{name: "constant velocity", k: New(0, 3, 1), t: 2, wantDisplacement: 7, wantVelocity: 3},
+		{name: "free fall", k: New(-9.81, 0, 100), t: 2, wantDisplacement: 100 - 19.62, wantVelocity: -19.62},
+		{name: "thrown up", k: New(-10, 20, 0), t: 2, wantDisplacement: 20, wantVelocity: 0},
+		{name: "before time 0", k: New(2, 1, 0), t: -1, wantDisplacement: 0, wantVelocity: -1},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tc.k.Displacement(tc.t); !closeTo(got, tc.wantDisplacement) {
+				t.Errorf("%+v.Displacement(%v) = %v; want %v", tc.k, tc.t, got, tc.wantDisplacement)
+			}
+			if got := tc.k.Velocity(tc.t); !closeTo(got, tc.wantVelocity) {
+				t.Errorf("%+v.Velocity(%v) = %v; want %v", tc.k, tc.t, got, tc.wantVelocity)
+			}
+		})
+	}
+}
+
+func TestTimesAt(t *testing.T) {
+	tests := []struct {
+		name    string
+		k       Kinematics
+		s       float64
+		want    []float64
+		wantErr error
+	}{
+		{name: "two roots", k: New(-10, 20, 0), s: 15, want: []float64{1, 3}},
+		{name: "one root at the top", k: New(-10, 20, 0), s: 20, want: []float64{2}},
+		{name: "never that high", k: New(-10, 20, 0), s: 21, want: nil},
+		{name: "one root before time 0", k: New(2, 0, 0), s: 4, want: []float64{-2, 2}},
+		{name: "constant velocity", k: New(0, 4, 2), s: 10, want: []float64{2}},
+		{name: "not moving, elsewhere", k: New(0, 0, 2), s: 10, want: nil},
+		{name: "not moving, there", k: New(0, 0, 2), s: 2, wantErr: ErrAlwaysThere},
+		// With the usual formula, the small root of this one loses almost all its digits: 1e8 t + 0.5 t² = 1 gives t ≈ 1e-8.
+		{name: "badly conditioned", k: New(1, 1e8, 0), s: 1, want: []float64{-2e8 - 1e-8, 1e-8}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := tc.k.TimesAt(tc.s)
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("%+v.TimesAt(%v) returned the error %v; want %v", tc.k, tc.s, err, tc.wantErr)
+			}
+			if len(got) != len(tc.want) {
+				t.Fatalf("%+v.TimesAt(%v) = %v; want %v", tc.k, tc.s, got, tc.want)
+			}
+			for i := range got {
+				if !closeTo(got[i], tc.want[i]) {
+					t.Fatalf("%+v.TimesAt(%v) = %v; want %v", tc.k, tc.s, got, tc.want)
+				}
+				// Whatever the formula, the body must be at s at the times found.
+				// Computing the displacement adds terms that can be much bigger than s, so we allow for their rounding errors.
+				tt := got[i]
+				scale := math.Abs(0.5*tc.k.A*tt*tt) + math.Abs(tc.k.V0*tt) + math.Abs(tc.k.S0) + math.Abs(tc.s)
+				if s := tc.k.Displacement(tt); math.Abs(s-tc.s) > 1e-9*math.Max(1, scale) {
+					t.Errorf("at t=%v, the displacement is %v; want %v", got[i], s, tc.s)
+				}
+			}
+		})
+	}
+}
+
+func TestStopping(t *testing.T) {
+	tests := []struct {
+		name         string
+		k            Kinematics
+		wantOK       bool
+		wantTime     float64
+		wantDistance float64
+	}{
+		// A car at 20 m/s braking at 5 m/s² stops after 4 s and 40 m.
+		{name: "braking", k: New(-5, 20, 0), wantOK: true, wantTime: 4, wantDistance: 40},
+		{name: "braking backwards", k: New(5, -20, 3), wantOK: true, wantTime: 4, wantDistance: 40},
+		{name: "already stopped", k: New(-5, 0, 0), wantOK: true, wantTime: 0, wantDistance: 0},
+		{name: "speeding up", k: New(5, 20, 0), wantOK: false},
+		{name: "constant velocity", k: New(0, 20, 0), wantOK: false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			gotTime, ok := tc.k.StoppingTime()
+			if ok != tc.wantOK || !closeTo(gotTime, tc.wantTime) {
+				t.Errorf("%+v.StoppingTime() = %v, %t; want %v, %t", tc.k, gotTime, ok, tc.wantTime, tc.wantOK)
+			}
+			gotDistance, ok := tc.k.StoppingDistance()
+			if ok != tc.wantOK || !closeTo(gotDistance, tc.wantDistance) {
+				t.Errorf("%+v.StoppingDistance() = %v, %t; want %v, %t", tc.k, gotDistance, ok, tc.wantDistance, tc.wantOK)
+			}
+			// The stopping distance is v0² / 2|a|.
+			if ok && tc.k.A != 0 && !closeTo(gotDistance, tc.k.V0*tc.k.V0/(2*math.Abs(tc.k.A))) {
+				t.Errorf("%+v.StoppingDistance() = %v; want v0²/2|a|", tc.k, gotDistance)
+			}
+		})
+	}
+}
+
+func TestProfile(t *testing.T) {
+	// A car speeds up at 2 m/s² for 5 s (to 10 m/s, after 25 m), keeps its speed for 10 s (100 m more), then brakes at 5 m/s² for 2 s (10 m more, and it stops).
+	profile, err := NewProfile(0, 0, Phase{Duration: 5, Acceleration: 2}, Phase{Duration: 10}, Phase{Duration: 2, Acceleration: -5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := profile.Duration(); got != 17 {
+		t.Errorf("Duration() = %v; want 17", got)
+	}
+
+	tests := []struct {
+		t                float64
+		wantDisplacement float64
+		wantVelocity     float64
+		wantAcceleration float64
+	}{
+		{t: -1, wantDisplacement: 1, wantVelocity: -2, wantAcceleration: 2},
+		{t: 0, wantDisplacement: 0, wantVelocity: 0, wantAcceleration: 2},
+		{t: 2, wantDisplacement: 4, wantVelocity: 4, wantAcceleration: 2},
+		{t: 5, wantDisplacement: 25, wantVelocity: 10, wantAcceleration: 0},
+		{t: 10, wantDisplacement: 75, wantVelocity: 10, wantAcceleration: 0},
+		{t: 16, wantDisplacement: 132.5, wantVelocity: 5, wantAcceleration: -5},
+		{t: 17, wantDisplacement: 135, wantVelocity: 0, wantAcceleration: 0},
+		{t: 100, wantDisplacement: 135, wantVelocity: 0, wantAcceleration: 0},
+	}
+	for _, tc := range tests {
+		if got := profile.Displacement(tc.t); !closeTo(got, tc.wantDisplacement) {
+			t.Errorf("Displacement(%v) = %v; want %v", tc.t, got, tc.wantDisplacement)
+		}
+		if got := profile.Velocity(tc.t); !closeTo(got, tc.wantVelocity) {
+			t.Errorf("Velocity(%v) = %v; want %v", tc.t, got, tc.wantVelocity)
+		}
+		if got := profile.Acceleration(tc.t); got != tc.wantAcceleration {
+			t.Errorf("Acceleration(%v) = %v; want %v", tc.t, got, tc.wantAcceleration)
+		}
+	}
+}
+
+func TestNewProfileErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		phases []Phase
+	}{
+		{name: "no phase", phases: nil},
+		{name: "zero duration", phases: []Phase{{Duration: 1}, {Duration: 0}}},
+		{name: "negative duration", phases: []Phase{{Duration: -1}}},
+		{name: "not a number", phases: []Phase{{Duration: math.NaN()}}},
+		{name: "infinite", phases: []Phase{{Duration: math.Inf(1)}}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := NewProfile(0, 0, tc.phases...); err == nil {
+				t.Errorf("NewProfile(%v) did not return an error", tc.phases)
+			}
+		})
+	}
+}
+
+func TestParseQuantity(t *testing.T) {
+	tests := []struct {
+		input     string
+		dimension Dimension
+		want      float64
+		wantErr   bool
+	}{
+		{input: "9.81", dimension: Acceleration, want: 9.81},
+		{input: "9.81m/s^2", dimension: Acceleration, want: 9.81},
+		{input: "9.81m/s²", dimension: Acceleration, want: 9.81},
+		{input: "9.81 m/s/s", dimension: Acceleration, want: 9.81},
+		{input: "32.174ft*s^-2", dimension: Acceleration, want: 32.174 * 0.3048},
+		{input: "1g", dimension: Acceleration, want: 9.80665},
+		{input: "36km/h", dimension: Velocity, want: 10},
+		{input: "-36kph", dimension: Velocity, want: -10},
+		{input: "60mph", dimension: Velocity, want: 26.8224},
+		{input: "2min", dimension: Duration, want: 120},
+		{input: "1.5e3ms", dimension: Duration, want: 1.5},
+		{input: "100ft", dimension: Length, want: 30.48},
+		{input: "1e3m", dimension: Length, want: 1000},
+		{input: "2em", dimension: Length, wantErr: true},
+		{input: "36km/h", dimension: Acceleration, wantErr: true},
+		{input: "2min", dimension: Length, wantErr: true},
+		{input: "m", dimension: Length, wantErr: true},
+		{input: "10parsec", dimension: Length, wantErr: true},
+		{input: "10m/s^x", dimension: Velocity, wantErr: true},
+		{input: "10m/", dimension: Velocity, wantErr: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.input, func(t *testing.T) {
+			got, err := ParseQuantity(tc.input, tc.dimension)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("ParseQuantity(%q, %v) returned the error %v; want an error: %t", tc.input, tc.dimension, err, tc.wantErr)
+			}
+			if !closeTo(got, tc.want) {
+				t.Errorf("ParseQuantity(%q, %v) = %v; want %v", tc.input, tc.dimension, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestDimensionError(t *testing.T) {
+	_, err := ParseQuantity("36km/h", Acceleration)
+	var dimensionError *DimensionError
+	if !errors.As(err, &dimensionError) {
+		t.Fatalf("ParseQuantity returned %v; want a *DimensionError", err)
+	}
+	if want := `"36km/h" is a velocity, not an acceleration`; err.Error() != want {
+		t.Errorf("the error is %q; want %q", err, want)
+	}
+}
+
+func TestUnitConversion(t *testing.T) {
+	unit, err := ParseUnit("ft")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := unit.FromSI(unit.ToSI(42)); !closeTo(got, 42) {
+		t.Errorf("converting 42 ft to metres and back gives %v", got)
+	}
+	if got := unit.FromSI(30.48); !closeTo(got, 100) {
+		t.Errorf("30.48 m is %v ft; want 100", got)
+	}
+}
+
+func TestExtremes(t *testing.T) {
+	tests := []struct {
+		name     string
+		k        Kinematics
+		from, to float64
+		wantMin  Extremum
+		wantMax  Extremum
+	}{
+		// Thrown up at 20 m/s: the top is 20 m high, 2 s later.
+		{name: "thrown up", k: New(-10, 20, 0), from: 0, to: 5, wantMin: Extremum{5, -25}, wantMax: Extremum{2, 20}},
+		{name: "top out of the range", k: New(-10, 20, 0), from: 0, to: 1, wantMin: Extremum{0, 0}, wantMax: Extremum{1, 15}},
+		{name: "lowest point", k: New(2, -4, 1), from: 0, to: 3, wantMin: Extremum{2, -3}, wantMax: Extremum{0, 1}},
+		{name: "constant velocity", k: New(0, -1, 0), from: 1, to: 4, wantMin: Extremum{4, -4}, wantMax: Extremum{1, -1}},
+		{name: "not moving", k: New(0, 0, 7), from: 0, to: 4, wantMin: Extremum{0, 7}, wantMax: Extremum{0, 7}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			min, max := tc.k.Extremes(tc.from, tc.to)
+			if !closeTo(min.Time, tc.wantMin.Time) || !closeTo(min.Displacement, tc.wantMin.Displacement) {
+				t.Errorf("the minimum is %+v; want %+v", min, tc.wantMin)
+			}
+			if !closeTo(max.Time, tc.wantMax.Time) || !closeTo(max.Displacement, tc.wantMax.Displacement) {
+				t.Errorf("the maximum is %+v; want %+v", max, tc.wantMax)
+			}
+		})
+	}
+}
+
+// vectorsClose tells if two vectors are equal, give or take the rounding errors.
+func vectorsClose(a, b Vector) bool {
+	return closeTo(a.X, b.X) && closeTo(a.Y, b.Y) && closeTo(a.Z, b.Z)
+}
+
+func TestGenDisplaceVectorFn(t *testing.T) {
+	tests := []struct {
+		name       string
+		a, v0, s0  Vector
+		t          float64
+		want       Vector
+		wantLaunch Vector
+	}{
+		{name: "2-D", a: Vector{Y: -10}, v0: Vector{X: 3, Y: 20}, s0: Vector{X: 1, Y: 2}, t: 2, want: Vector{X: 7, Y: 22}},
+		{name: "3-D", a: Vector{X: 1, Y: -10, Z: 2}, v0: Vector{X: 0, Y: 5, Z: -1}, s0: Vector{Z: 4}, t: 1, want: Vector{X: 0.5, Y: 0, Z: 4}},
+		{name: "matches the scalar version on each axis", a: Vector{X: 10, Y: 20, Z: -3}, v0: Vector{X: 2, Y: 40, Z: 1}, s0: Vector{X: 1, Y: 120, Z: 0}, t: 3,
+			want: Vector{X: New(10, 2, 1).Displacement(3), Y: New(20, 40, 120).Displacement(3), Z: New(-3, 1, 0).Displacement(3)}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := GenDisplaceVectorFn(tc.a, tc.v0, tc.s0)(tc.t); !vectorsClose(got, tc.want) {
+				t.Errorf("position at %v = %v; want %v", tc.t, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestLaunch(t *testing.T) {
+	tests := []struct {
+		speed, elevation, azimuth float64
+		want                      Vector
+	}{
+		{speed: 10, elevation: 0, azimuth: 0, want: Vector{X: 10}},
+		{speed: 10, elevation: 90, azimuth: 0, want: Vector{Y: 10}},
+		{speed: 10, elevation: 30, azimuth: 0, want: Vector{X: 10 * math.Sqrt(3) / 2, Y: 5}},
+		{speed: 10, elevation: 0, azimuth: 90, want: Vector{Z: 10}},
+	}
+	for _, tc := range tests {
+		got := Launch(tc.speed, tc.elevation, tc.azimuth)
+		// The cosine of 90° is not exactly 0 in floats, so we compare the difference with the speed.
+		if got.Add(tc.want.Scale(-1)).Norm() > 1e-9*tc.speed {
+			t.Errorf("Launch(%v, %v, %v) = %v; want %v", tc.speed, tc.elevation, tc.azimuth, got, tc.want)
+		}
+	}
+}
+
+func TestFlight(t *testing.T) {
+	const g = 9.80665
+	tests := []struct {
+		name    string
+		motion  Motion
+		ground  float64
+		want    Flight
+		wantErr bool
+	}{
+		{
+			// From the ground, at 45°: the time of flight is 2 v sin θ / g, the top is v² sin² θ / 2g, the range is v² sin 2θ / g.
+			name:   "45° from the ground",
+			motion: Motion{A: Gravity, V0: Launch(20, 45, 0)},
+			want: Flight{
+				TimeOfFlight: 2 * 20 * math.Sin(math.Pi/4) / g, MaxHeight: 400 * 0.5 / (2 * g), TimeOfMaxHeight: 20 * math.Sin(math.Pi/4) / g,
+				Range: 400 / g, Impact: Vector{X: 400 / g}, ImpactVelocity: Vector{X: 20 * math.Cos(math.Pi/4), Y: -20 * math.Sin(math.Pi/4)},
+			},
+		},
+		{
+			// Thrown horizontally from a 20 m cliff at 10 m/s, with g = 10: it falls for 2 s and lands 20 m away.
+			name:   "from a cliff",
+			motion: Motion{A: Vector{Y: -10}, V0: Vector{X: 10}, S0: Vector{Y: 20}},
+			want:   Flight{TimeOfFlight: 2, MaxHeight: 20, TimeOfMaxHeight: 0, Range: 20, Impact: Vector{X: 20}, ImpactVelocity: Vector{X: 10, Y: -20}},
+		},
+		{
+			// Same, but in 3-D, thrown at 45° between X and Z, and with a raised ground.
+			name:   "3-D with a raised ground",
+			motion: Motion{A: Vector{Y: -10}, V0: Vector{X: 6, Z: 8}, S0: Vector{X: 1, Y: 25, Z: 1}},
+			ground: 5,
+			want:   Flight{TimeOfFlight: 2, MaxHeight: 25, TimeOfMaxHeight: 0, Range: 20, Impact: Vector{X: 13, Y: 5, Z: 17}, ImpactVelocity: Vector{X: 6, Y: -20, Z: 8}},
+		},
+		{name: "thrown up without gravity", motion: Motion{V0: Vector{Y: 1}}, wantErr: true},
+		{name: "sliding on the ground", motion: Motion{V0: Vector{X: 1}}, wantErr: true},
+		{name: "below the ground", motion: Motion{A: Gravity, S0: Vector{Y: -1}}, wantErr: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := tc.motion.Flight(tc.ground)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("Flight(%v) returned the error %v; want an error: %t", tc.ground, err, tc.wantErr)
+			}
+			if tc.wantErr {
+				return
+			}
+			if !closeTo(got.TimeOfFlight, tc.want.TimeOfFlight) || !closeTo(got.MaxHeight, tc.want.MaxHeight) ||
+				!closeTo(got.TimeOfMaxHeight, tc.want.TimeOfMaxHeight) || !closeTo(got.Range, tc.want.Range) {
+				t.Errorf("Flight(%v) = %+v; want %+v", tc.ground, got, tc.want)
+			}
+			// The impact is on the ground, which the float formulas only get close to.
+			if got.Impact.Add(tc.want.Impact.Scale(-1)).Norm() > 1e-9 || !vectorsClose(got.ImpactVelocity, tc.want.ImpactVelocity) {
+				t.Errorf("Flight(%v) lands at %v at %v; want %v at %v", tc.ground, got.Impact, got.ImpactVelocity, tc.want.Impact, tc.want.ImpactVelocity)
+			}
+		})
+	}
+}
+
+func TestIntegratorsWithConstantAcceleration(t *testing.T) {
+	const a, v0, s0, step = 10, 2, 1, 0.01
+	exact := New(a, v0, s0)
+	constant := func(t, s, v float64) float64 { return a }
+
+	for _, method := range Methods {
+		t.Run(method.String(), func(t *testing.T) {
+			fn, err := GenIntegratedDisplaceFn(constant, v0, s0, method, step)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, time := range []float64{0, 0.5, 1, 2.345, 3, -1.5} {
+				got, want := fn(time), exact.Displacement(time)
+				// Verlet and RK4 are exact with a constant acceleration. The Euler methods make an error of ½ a t h:
+				// Euler moves with the velocity of the start of each step, which is too slow, and the semi-implicit one with the velocity of the end, which is too fast.
+				tolerance := 1e-9 * math.Max(1, math.Abs(want))
+				if method.Order() == 1 {
+					tolerance = 0.5*a*math.Abs(time)*step + 1e-9
+				}
+				if math.Abs(got-want) > tolerance {
+					t.Errorf("at t=%v, the displacement is %v; want %v ± %v", time, got, want, tolerance)
+				}
+			}
+		})
+	}
+}
+
+func TestIntegratedDisplaceFnNonFiniteTime(t *testing.T) {
+	constant := func(t, s, v float64) float64 { return 1 }
+	tests := []struct {
+		name string
+		time float64
+	}{
+		{name: "+Inf", time: math.Inf(1)},
+		{name: "-Inf", time: math.Inf(-1)},
+		{name: "NaN", time: math.NaN()},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			for _, method := range Methods {
+				fn, err := GenIntegratedDisplaceFn(constant, 0, 0, method, 0.1)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got := fn(tc.time); !math.IsNaN(got) {
+					t.Errorf("%v: at t=%v, the displacement is %v; want NaN", method, tc.time, got)
+				}
+				// The function still works afterwards (the first order methods are not exact, see above).
+				if got := fn(1); math.Abs(got-0.5) > 1e-9 && method.Order() > 1 {
+					t.Errorf("%v: after t=%v, the displacement at t=1 is %v; want 0.5", method, tc.time, got)
+				}
+			}
+		})
+	}
+}
+
+// TestIntegratorsErrorOrder measures how fast the error of each method goes down with the step, on a spring,
+// and checks that it is the order the method is known for.
+func TestIntegratorsErrorOrder(t *testing.T) {
+	// A spring of stiffness 1 (for a mass of 1), released at 1 m: the position is cos t.
+	spring := func(t, s, v float64) float64 { return -s }
+	const time = 2.0
+	want := math.Cos(time)
+
+	errorWith := func(method Method, step float64) float64 {
+		fn, err := GenIntegratedDisplaceFn(spring, 0, 1, method, step)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return math.Abs(fn(time) - want)
+	}
+
+	for _, method := range Methods {
+		t.Run(method.String(), func(t *testing.T) {
+			coarse, fine := errorWith(method, 0.02), errorWith(method, 0.01)
+			order := math.Log2(coarse / fine)
+			t.Logf("%-20s error %.3g with a step of 0.02, %.3g with 0.01: order %.2f", method, coarse, fine, order)
+			if math.Abs(order-float64(method.Order())) > 0.3 {
+				t.Errorf("%v has an error order of %.2f; want %d", method, order, method.Order())
+			}
+		})
+	}
+}
+
+func TestIntegratorsWithDrag(t *testing.T) {
+	// With a drag proportional to the velocity, v = v0 e^(-kt) and s = s0 + v0/k (1 - e^(-kt)).
+	const k, v0, s0 = 0.5, 10, 3
+	drag := func(t, s, v float64) float64 { return -k * v }
+	fn, err := GenIntegratedDisplaceFn(drag, v0, s0, RK4, 0.01)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, time := range []float64{1, 4, 10} {
+		want := s0 + v0/k*(1-math.Exp(-k*time))
+		if got := fn(time); math.Abs(got-want) > 1e-8 {
+			t.Errorf("at t=%v, the displacement is %v; want %v", time, got, want)
+		}
+	}
+}
+
+func TestIntegratedDisplaceFnCallOrder(t *testing.T) {
+	// Thrust that grows with time: the result must not depend on the order of the calls, even though the function goes on from the last one.
+	thrust := func(t, s, v float64) float64 { return 2 * t }
+	times := []float64{0.35, 1.7, 0.2, 3.05, 3.05, 2.5}
+	in := func() func(float64) float64 {
+		fn, err := GenIntegratedDisplaceFn(thrust, 1, 0, Verlet, 0.1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return fn
+	}
+
+	reused := in()
+	for _, time := range times {
+		if got, want := reused(time), in()(time); got != want {
+			t.Errorf("at t=%v, the reused function gives %v, a new one %v", time, got, want)
+		}
+	}
+}
+
+func TestGenIntegratedDisplaceFnErrors(t *testing.T) {
+	constant := func(t, s, v float64) float64 { return 1 }
+	for _, step := range []float64{0, -0.1, math.NaN(), math.Inf(1)} {
+		if _, err := GenIntegratedDisplaceFn(constant, 0, 0, RK4, step); err == nil {
+			t.Errorf("a step of %v was accepted", step)
+		}
+	}
+	if _, err := GenIntegratedDisplaceFn(constant, 0, 0, Method(42), 0.1); err == nil {
+		t.Errorf("an unknown method was accepted")
+	}
+}
+
+func TestStudentQuantile(t *testing.T) {
+	// Values from the tables of the t-distribution.
+	tests := []struct {
+		p, dof float64
+		want   float64
+	}{
+		{p: 0.975, dof: 1, want: 12.7062},
+		{p: 0.975, dof: 3, want: 3.1824},
+		{p: 0.975, dof: 10, want: 2.2281},
+		{p: 0.975, dof: 30, want: 2.0423},
+		{p: 0.995, dof: 5, want: 4.0321},
+		{p: 0.95, dof: 1000, want: 1.6464},
+	}
+	for _, tc := range tests {
+		if got := studentQuantile(tc.p, tc.dof); math.Abs(got-tc.want) > 1e-4 {
+			t.Errorf("studentQuantile(%v, %v) = %v; want %v", tc.p, tc.dof, got, tc.want)
+		}
+	}
+}
+
+func TestFitSamples(t *testing.T) {
+	// noise is a fixed, made up, measurement error, so that the test always gives the same result.
+	noise := []float64{0.3, -0.2, 0.1, 0.4, -0.5, 0.2, -0.1, -0.3, 0.25, -0.15}
+	samplesOf := func(k Kinematics, start, step float64, noisy bool) []Sample {
+		samples := make([]Sample, len(noise))
+		for i := range samples {
+			time := start + float64(i)*step
+			samples[i] = Sample{T: time, S: k.Displacement(time)}
+			if noisy {
+				samples[i].S += noise[i]
+			}
+		}
+		return samples
+	}
+
+	tests := []struct {
+		name    string
+		motion  Kinematics
+		samples []Sample
+		exact   bool
+	}{
+		{name: "exact", motion: New(-9.81, 20, 2), samples: samplesOf(New(-9.81, 20, 2), 0, 0.5, false), exact: true},
+		{name: "noisy", motion: New(-9.81, 20, 2), samples: samplesOf(New(-9.81, 20, 2), 0, 0.5, true)},
+		{name: "constant velocity", motion: New(0, 3, -1), samples: samplesOf(New(0, 3, -1), 1, 1, true)},
+		// Times read from a clock: without changing the variable, the equations would lose all their precision.
+		{name: "far from time 0", motion: New(0.5, 1, 0), samples: samplesOf(New(0.5, 1, 0), 1e4, 1, false), exact: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			fit, err := FitSamples(tc.samples, 0.95)
+			if err != nil {
+				t.Fatal(err)
+			}
+			estimates := []struct {
+				name     string
+				estimate Estimate
+				want     float64
+			}{
+				{"a", fit.Acceleration, tc.motion.A},
+				{"v0", fit.InitialVelocity, tc.motion.V0},
+				{"s0", fit.InitialDisplacement, tc.motion.S0},
+			}
+			for _, e := range estimates {
+				// With the noise we chose, the true values are within the 95% intervals.
+				if e.want < e.estimate.Low-1e-6 || e.want > e.estimate.High+1e-6 {
+					t.Errorf("the true %s is %v, out of the interval %v to %v", e.name, e.want, e.estimate.Low, e.estimate.High)
+				}
+				if tc.exact && math.Abs(e.estimate.Value-e.want) > 1e-6*math.Max(1, math.Abs(e.want)) {
+					t.Errorf("%s = %v; want %v", e.name, e.estimate.Value, e.want)
+				}
+			}
+
+			if len(fit.Residuals) != len(tc.samples) {
+				t.Fatalf("%d residuals for %d samples", len(fit.Residuals), len(tc.samples))
+			}
+			fn := fit.DisplaceFn()
+			for i, sample := range tc.samples {
+				if !closeTo(fit.Residuals[i], sample.S-fn(sample.T)) {
+					t.Errorf("residual %d is %v; want %v", i, fit.Residuals[i], sample.S-fn(sample.T))
+				}
+			}
+			if tc.exact && fit.RSquared < 1-1e-9 {
+				t.Errorf("R² = %v; want 1 for exact samples", fit.RSquared)
+			}
+			if fit.RSquared > 1 || fit.RSquared < 0.99 {
+				t.Errorf("R² = %v; want between 0.99 and 1", fit.RSquared)
+			}
+		})
+	}
+}
+
+func TestFitSamplesErrors(t *testing.T) {
+	tests := []struct {
+		name       string
+		samples    []Sample
+		confidence float64
+	}{
+		{name: "three samples", samples: []Sample{{0, 0}, {1, 1}, {2, 4}}, confidence: 0.95},
+		{name: "two different times", samples: []Sample{{0, 0}, {0, 1}, {1, 1}, {1, 2}}, confidence: 0.95},
+		{name: "one time", samples: []Sample{{1, 0}, {1, 1}, {1, 1}, {1, 2}}, confidence: 0.95},
+		{name: "confidence of 1", samples: []Sample{{0, 0}, {1, 1}, {2, 4}, {3, 9}}, confidence: 1},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := FitSamples(tc.samples, tc.confidence); err == nil {
+				t.Errorf("FitSamples(%v, %v) did not return an error", tc.samples, tc.confidence)
+			}
+		})
+	}
+}
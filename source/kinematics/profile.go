@@ -0,0 +1,88 @@
+package kinematics
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// Phase is a part of a motion profile, with a constant acceleration during a given duration.
+type Phase struct {
+	Duration     float64
+	Acceleration float64
+}
+
+// Profile is a motion made of several phases of constant acceleration, one after the other.
+// For example, a car that speeds up for 10 s, keeps its speed for a minute, then brakes for 5 s.
+//
+// Before time 0, the motion is the one of the first phase. After the last phase, the body keeps the velocity it had (it stops accelerating).
+type Profile struct {
+	segments []segment
+}
+
+// segment is a phase, with the time it starts and the motion during it (with time counted from the start of the phase).
+type segment struct {
+	start    float64
+	duration float64
+	motion   Kinematics
+}
+
+// NewProfile returns the profile starting at the position s0 with the velocity v0, and going through the phases in order.
+// Every phase must have a positive duration.
+func NewProfile(s0, v0 float64, phases ...Phase) (*Profile, error) {
+	if len(phases) == 0 {
+		return nil, fmt.Errorf("a motion profile needs at least one phase")
+	}
+
+	p := &Profile{segments: make([]segment, 0, len(phases)+1)}
+	start, s, v := 0.0, s0, v0
+	for i, phase := range phases {
+		if !(phase.Duration > 0) || math.IsInf(phase.Duration, 0) {
+			return nil, fmt.Errorf("phase %d: the duration must be a positive number, got %v", i+1, phase.Duration)
+		}
+
+		// Each phase starts where and how fast the previous one ended.
+		motion := New(phase.Acceleration, v, s)
+		p.segments = append(p.segments, segment{start: start, duration: phase.Duration, motion: motion})
+		start += phase.Duration
+		s, v = motion.Displacement(phase.Duration), motion.Velocity(phase.Duration)
+	}
+
+	// After the last phase, the body keeps going at the same speed.
+	p.segments = append(p.segments, segment{start: start, duration: math.Inf(1), motion: New(0, v, s)})
+	return p, nil
+}
+
+// Duration returns the total duration of the phases.
+func (p *Profile) Duration() float64 {
+	return p.segments[len(p.segments)-1].start
+}
+
+// at returns the segment that contains the time t, and the time since its start.
+func (p *Profile) at(t float64) (segment, float64) {
+	// Index of the first segment that starts after t: t is in the one before it (or in the first one, for times before 0).
+	i := sort.Search(len(p.segments), func(i int) bool { return p.segments[i].start > t })
+	if i > 0 {
+		i--
+	}
+	seg := p.segments[i]
+	return seg, t - seg.start
+}
+
+// Displacement returns the position at time t.
+func (p *Profile) Displacement(t float64) float64 {
+	seg, dt := p.at(t)
+	return seg.motion.Displacement(dt)
+}
+
+// Velocity returns the velocity at time t. At the exact time one phase ends and the next starts, it is the same in both, so there is no ambiguity.
+func (p *Profile) Velocity(t float64) float64 {
+	seg, dt := p.at(t)
+	return seg.motion.Velocity(dt)
+}
+
+// Acceleration returns the acceleration at time t. When t is the exact time a phase starts, it is the acceleration of that phase.
+func (p *Profile) Acceleration(t float64) float64 {
+	seg, _ := p.at(t)
+	return seg.motion.A
+}
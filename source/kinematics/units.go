@@ -0,0 +1,218 @@
+package kinematics
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Dimension is what a value measures, as the powers of length and time in its unit.
+// For example, a velocity is a length divided by a time: {Length: 1, Time: -1}.
+type Dimension struct {
+	Length int
+	Time   int
+}
+
+// The dimensions of the values we work with.
+var (
+	Dimensionless = Dimension{}
+	Length        = Dimension{Length: 1}
+	Duration      = Dimension{Time: 1}
+	Velocity      = Dimension{Length: 1, Time: -1}
+	Acceleration  = Dimension{Length: 1, Time: -2}
+)
+
+// String returns the name of the dimension, like "velocity", or its SI unit when it has no name.
+func (d Dimension) String() string {
+	switch d {
+	case Dimensionless:
+		return "number without unit"
+	case Length:
+		return "length"
+	case Duration:
+		return "duration"
+	case Velocity:
+		return "velocity"
+	case Acceleration:
+		return "acceleration"
+	}
+	return "quantity in m^" + strconv.Itoa(d.Length) + "·s^" + strconv.Itoa(d.Time)
+}
+
+// article returns the dimension with "a" or "an" in front of it, for the error messages.
+func (d Dimension) article() string {
+	name := d.String()
+	if strings.IndexByte("aeiou", name[0]) >= 0 {
+		return "an " + name
+	}
+	return "a " + name
+}
+
+// Unit is a unit of measure, like km/h. Factor is the value of one unit in SI units (1 km/h is 1/3.6 m/s).
+type Unit struct {
+	Symbol    string
+	Factor    float64
+	Dimension Dimension
+}
+
+// ToSI converts a value in this unit to SI units (metres and seconds).
+func (u Unit) ToSI(value float64) float64 {
+	return value * u.Factor
+}
+
+// FromSI converts a value in SI units to this unit.
+func (u Unit) FromSI(value float64) float64 {
+	return value / u.Factor
+}
+
+// units are the symbols that can be combined in a unit, like "km" and "h" in "km/h".
+// A few units of velocity and acceleration have their own symbol, so they are here too.
+var units = map[string]Unit{
+	// Lengths.
+	"m":   {Factor: 1, Dimension: Length},
+	"km":  {Factor: 1000, Dimension: Length},
+	"cm":  {Factor: 0.01, Dimension: Length},
+	"mm":  {Factor: 0.001, Dimension: Length},
+	"in":  {Factor: 0.0254, Dimension: Length},
+	"ft":  {Factor: 0.3048, Dimension: Length},
+	"yd":  {Factor: 0.9144, Dimension: Length},
+	"mi":  {Factor: 1609.344, Dimension: Length},
+	"nmi": {Factor: 1852, Dimension: Length},
+
+	// Durations.
+	"s":   {Factor: 1, Dimension: Duration},
+	"ms":  {Factor: 0.001, Dimension: Duration},
+	"min": {Factor: 60, Dimension: Duration},
+	"h":   {Factor: 3600, Dimension: Duration},
+
+	// Velocities.
+	"mph": {Factor: 1609.344 / 3600, Dimension: Velocity},
+	"kph": {Factor: 1000.0 / 3600, Dimension: Velocity},
+	"kn":  {Factor: 1852.0 / 3600, Dimension: Velocity},
+
+	// Accelerations: g is the standard gravity (there are no grams here, we don't deal with masses).
+	"g": {Factor: 9.80665, Dimension: Acceleration},
+}
+
+// ParseUnit reads a unit like "m", "km/h", "m/s^2", "m/s²" or "ft*s^-2".
+// It is made of symbols, each one with an optional power, separated by * (multiplied) or / (divided).
+func ParseUnit(s string) (Unit, error) {
+	unit := Unit{Symbol: s, Factor: 1}
+	if s == "" {
+		return unit, errors.New("empty unit")
+	}
+
+	// We read the unit one symbol at a time. sign is -1 after a /, so that the powers of what follows are negative.
+	rest, sign := s, 1
+	for {
+		end := strings.IndexAny(rest, "*·/")
+		if end < 0 {
+			end = len(rest)
+		}
+		term, power, err := splitPower(rest[:end])
+		if err != nil {
+			return unit, fmt.Errorf("unit %q: %w", s, err)
+		}
+		base, ok := units[term]
+		if !ok {
+			return unit, fmt.Errorf("unknown unit %q in %q", term, s)
+		}
+
+		power *= sign
+		for i := 0; i < abs(power); i++ {
+			if power > 0 {
+				unit.Factor *= base.Factor
+			} else {
+				unit.Factor /= base.Factor
+			}
+		}
+		unit.Dimension.Length += power * base.Dimension.Length
+		unit.Dimension.Time += power * base.Dimension.Time
+
+		if end == len(rest) {
+			return unit, nil
+		}
+		sign = 1
+		if strings.HasPrefix(rest[end:], "/") {
+			sign = -1
+		}
+		// The separator is one character, but · takes two bytes.
+		_, size := utf8.DecodeRuneInString(rest[end:])
+		rest = rest[end+size:]
+	}
+}
+
+// splitPower splits a symbol with a power, like "s^2" or "s²", into the symbol and the power. Without a power, it is 1.
+func splitPower(term string) (symbol string, power int, err error) {
+	switch {
+	case strings.HasSuffix(term, "²"):
+		return strings.TrimSuffix(term, "²"), 2, nil
+	case strings.HasSuffix(term, "³"):
+		return strings.TrimSuffix(term, "³"), 3, nil
+	}
+
+	symbol, exponent, found := strings.Cut(term, "^")
+	if !found {
+		return term, 1, nil
+	}
+	power, err = strconv.Atoi(exponent)
+	if err != nil {
+		return "", 0, fmt.Errorf("%q is not a valid power", exponent)
+	}
+	return symbol, power, nil
+}
+
+// DimensionError is returned when a value does not measure what was expected, like a velocity given for an acceleration.
+type DimensionError struct {
+	Input string
+	Got   Dimension
+	Want  Dimension
+}
+
+func (e *DimensionError) Error() string {
+	return fmt.Sprintf("%q is %s, not %s", e.Input, e.Got.article(), e.Want.article())
+}
+
+// ParseQuantity reads a number with an optional unit, like "9.81m/s^2", "36km/h", "2min" or "100 ft", and returns it in SI units
+// (metres, seconds, and what comes from them).
+// A number without unit is taken as already in SI units. A unit that does not measure want is a *DimensionError.
+func ParseQuantity(s string, want Dimension) (float64, error) {
+	s = strings.TrimSpace(s)
+
+	// The number is the longest beginning of s that is a valid number, the unit is what comes after it.
+	end := 0
+	for end < len(s) && strings.IndexByte("0123456789.+-eE", s[end]) >= 0 {
+		end++
+	}
+	for ; end > 0; end-- {
+		if _, err := strconv.ParseFloat(s[:end], 64); err == nil {
+			break
+		}
+	}
+	if end == 0 {
+		return 0, fmt.Errorf("%q is not a number", s)
+	}
+	value, _ := strconv.ParseFloat(s[:end], 64)
+
+	symbol := strings.TrimSpace(s[end:])
+	if symbol == "" {
+		return value, nil
+	}
+	unit, err := ParseUnit(symbol)
+	if err != nil {
+		return 0, err
+	}
+	if unit.Dimension != want {
+		return 0, &DimensionError{Input: s, Got: unit.Dimension, Want: want}
+	}
+	return unit.ToSI(value), nil
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
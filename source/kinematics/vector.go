@@ -0,0 +1,130 @@
+package kinematics
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+// Vector is a position, a velocity or an acceleration in 2-D or 3-D.
+// Y is the vertical axis, going up, like on a drawing: a 2-D motion uses X and Y, and leaves Z at 0.
+type Vector struct {
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+	Z float64 `json:"z,omitempty"`
+}
+
+// Gravity is the standard gravity on Earth, pulling down.
+var Gravity = Vector{Y: -9.80665}
+
+// Add returns v + w.
+func (v Vector) Add(w Vector) Vector {
+	return Vector{v.X + w.X, v.Y + w.Y, v.Z + w.Z}
+}
+
+// Scale returns v multiplied by k.
+func (v Vector) Scale(k float64) Vector {
+	return Vector{k * v.X, k * v.Y, k * v.Z}
+}
+
+// Norm returns the length of v.
+func (v Vector) Norm() float64 {
+	return math.Sqrt(v.X*v.X + v.Y*v.Y + v.Z*v.Z)
+}
+
+func (v Vector) String() string {
+	return fmt.Sprintf("(%g, %g, %g)", v.X, v.Y, v.Z)
+}
+
+// Launch returns the velocity of something thrown at the given speed, with an elevation angle above the horizontal
+// and an azimuth angle around the vertical (0 is along X, 90 along Z), both in degrees.
+// In 2-D, the azimuth is 0: Launch(20, 45, 0) is thrown at 20 m/s, at 45° towards X.
+func Launch(speed, elevation, azimuth float64) Vector {
+	elevation, azimuth = elevation*math.Pi/180, azimuth*math.Pi/180
+	horizontal := speed * math.Cos(elevation)
+	return Vector{X: horizontal * math.Cos(azimuth), Y: speed * math.Sin(elevation), Z: horizontal * math.Sin(azimuth)}
+}
+
+// GenDisplaceVectorFn is GenDisplaceFn with vectors: it returns a function which computes the position at time t,
+// for the acceleration a, the initial velocity v0 and the initial position s0.
+func GenDisplaceVectorFn(a, v0, s0 Vector) func(t float64) Vector {
+	return Motion{A: a, V0: v0, S0: s0}.Position
+}
+
+// Motion is a motion with a constant acceleration in 2-D or 3-D, like a projectile.
+// Each axis is a Kinematics of its own, the vector formulas are the same as the scalar ones.
+type Motion struct {
+	A  Vector // Acceleration, usually Gravity.
+	V0 Vector // Initial velocity.
+	S0 Vector // Initial position.
+}
+
+// axis returns the motion along one axis.
+func (m Motion) axis(component func(Vector) float64) Kinematics {
+	return New(component(m.A), component(m.V0), component(m.S0))
+}
+
+func (m Motion) x() Kinematics { return m.axis(func(v Vector) float64 { return v.X }) }
+func (m Motion) y() Kinematics { return m.axis(func(v Vector) float64 { return v.Y }) }
+func (m Motion) z() Kinematics { return m.axis(func(v Vector) float64 { return v.Z }) }
+
+// Position returns the position at time t: s = ½ a t² + v0 t + s0
+func (m Motion) Position(t float64) Vector {
+	return Vector{m.x().Displacement(t), m.y().Displacement(t), m.z().Displacement(t)}
+}
+
+// Velocity returns the velocity at time t: v = a t + v0
+func (m Motion) Velocity(t float64) Vector {
+	return Vector{m.x().Velocity(t), m.y().Velocity(t), m.z().Velocity(t)}
+}
+
+// Flight is what happens to a projectile between its launch and the moment it hits the ground.
+type Flight struct {
+	TimeOfFlight    float64 // Time from the launch to the impact.
+	MaxHeight       float64 // Highest Y reached.
+	TimeOfMaxHeight float64
+	Range           float64 // Horizontal distance between the launch point and the impact point.
+	Impact          Vector  // Where the projectile hits the ground.
+	ImpactVelocity  Vector
+}
+
+// ErrNeverLands is returned by Flight when the projectile never comes back to the ground, for example when it is thrown up without gravity.
+var ErrNeverLands = errors.New("the projectile never comes back to the ground")
+
+// Flight returns the flight of the projectile until it hits the ground, which is the horizontal plane at the height ground.
+// The projectile must start on the ground or above it.
+func (m Motion) Flight(ground float64) (Flight, error) {
+	vertical := m.y()
+	if vertical.S0 < ground {
+		return Flight{}, fmt.Errorf("the projectile starts below the ground (at %g, the ground is at %g)", vertical.S0, ground)
+	}
+
+	// The impact is the first time after the launch at which the height is the ground's.
+	// When the projectile starts on the ground, time 0 is a solution too, but it is the launch, not the impact.
+	times, err := vertical.TimesAt(ground)
+	if errors.Is(err, ErrAlwaysThere) {
+		// It slides on the ground: it never flies, so it never lands either.
+		return Flight{}, ErrNeverLands
+	}
+	impact := math.NaN()
+	for _, t := range times {
+		if t > 0 {
+			impact = t
+			break
+		}
+	}
+	if math.IsNaN(impact) {
+		return Flight{}, ErrNeverLands
+	}
+
+	_, highest := vertical.Extremes(0, impact)
+	landing := m.Position(impact)
+	return Flight{
+		TimeOfFlight:    impact,
+		MaxHeight:       highest.Displacement,
+		TimeOfMaxHeight: highest.Time,
+		Range:           math.Hypot(landing.X-m.S0.X, landing.Z-m.S0.Z),
+		Impact:          landing,
+		ImpactVelocity:  m.Velocity(impact),
+	}, nil
+}
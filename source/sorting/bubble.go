@@ -0,0 +1,87 @@
+package sorting
+
+// BubbleSort sorts a slice of any ordered type (integers, floats, strings...) using the bubble sort algorithm.
+
+// Here is how a bubble sort works:
+// 1. Start at the beginning of the list.
+// 2. Compare the first two elements.
+// 3. If the first is greater than the second, swap them.
+// 4. Go to the next pair, and so on, continuously making sweeps of the list until sorted.
+// 5. In doing so, the smaller items slowly "bubble" up to the beginning of the list.
+// 6. If we ever make a sweep without swapping, the list is sorted and we can stop.
+
+// We do it N-1 times, where N is the number of items in the list, to guarantee that it is sorted.
+// If we make a sweep and make no swaps, the list is sorted and we can stop
+
+// Bubble sort is stable: we only swap two neighbours when the first one is strictly greater than the second,
+// so equal elements never jump over each other and keep their original order.
+// The returned Stats tell how much work the sort took.
+func BubbleSort[T Ordered](s []T) Stats {
+	return BubbleSortFunc(s, less[T])
+}
+
+// BubbleSortFunc sorts a slice of any type using the bubble sort algorithm and the given less function.
+// less(a, b) must return true if a has to be placed before b. It is what allows us to sort our own records (by name, by age...).
+// Like BubbleSort, it is stable: elements for which neither less(a, b) nor less(b, a) is true keep their original order.
+func BubbleSortFunc[T any](s []T, less func(a, b T) bool) Stats {
+	return BubbleSortObserve(s, less, nil)
+}
+
+// BubbleSortObserve is BubbleSortFunc with an observer, which is told about each comparison, each swap and each finished sweep.
+// It is used to trace and replay the sort step by step. A nil observer is allowed and observes nothing.
+func BubbleSortObserve[T any](s []T, less func(a, b T) bool, observe Observer[T]) (stats Stats) {
+	if observe == nil {
+		// Nobody is watching: we observe with a function that does nothing, and we don't bother copying the slice for the start event.
+		observe = func(Event[T]) {}
+	} else {
+		observe(Event[T]{Kind: EventStart, Slice: snapshot(s)})
+	}
+
+	// After N-1 sweeps, the N-1 biggest elements are at their place, so the last one is too: there is no need for an Nth sweep.
+	for i := 0; i < len(s)-1; i++ {
+		stats.Passes++
+
+		// At the beginning of each sweep, we setup a flag to indicate if we made a swap (we did not yet, so false)
+		didSwap := false
+		firstIndex := 0
+
+		// We need to make sure that we don't go out of bounds
+		for firstIndex < (len(s) - i - 1) {
+			first, second := s[firstIndex], s[firstIndex+1]
+			stats.Comparisons++
+			observe(Event[T]{Kind: EventCompare, Sweep: i, I: firstIndex, J: firstIndex + 1, A: first, B: second})
+
+			// If the second element has to be placed before the first one, swap them.
+			// Note that we ask "is the second less than the first?" and not "is the first not less than the second?",
+			// that way equal elements are never swapped, which is what makes the sort stable.
+			if less(second, first) {
+				observe(Event[T]{Kind: EventSwap, Sweep: i, I: firstIndex, J: firstIndex + 1, A: first, B: second})
+				Swap(s, firstIndex)
+				stats.Swaps++
+				// We made a swap, so set the flag to true
+				didSwap = true
+			}
+			firstIndex++
+		}
+		observe(Event[T]{Kind: EventSweep, Sweep: i, DidSwap: didSwap})
+
+		// if we didn't make a swap, the list is sorted and we can stop
+		if !didSwap {
+			break
+		}
+	}
+
+	// We stopped early if a sweep without swaps spared us some of the N-1 sweeps.
+	stats.EarlyExit = stats.Passes < len(s)-1
+	return stats
+}
+
+// Swap swaps the position of two neighbour elements in a slice: the one at index and the one at index+1.
+func Swap[T any](s []T, index int) {
+	// We need to make sure that we don't go out of bounds
+	// We already checked this in BubbleSort, but we check again here for safety (if this code is later on called from another function)
+	if index < 0 || index >= len(s)-1 {
+		return
+	}
+	s[index], s[index+1] = s[index+1], s[index]
+}
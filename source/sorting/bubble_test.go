@@ -0,0 +1,154 @@
+package sorting
+
+import (
+	"reflect"
+	"testing"
+)
+
+// sortTestCase is used by the table tests below. It is generic so that we can run the same kind of table for every type.
+type sortTestCase[T any] struct {
+	name  string
+	given []T
+	want  []T
+}
+
+// runBubbleSortCases runs BubbleSort on each test case, on a copy of the given slice so that the table is never modified.
+func runBubbleSortCases[T Ordered](t *testing.T, testCases []sortTestCase[T]) {
+	t.Helper()
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := make([]T, len(tc.given))
+			copy(got, tc.given)
+
+			BubbleSort(got)
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("BubbleSort(%v) = %v; want %v", tc.given, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestBubbleSortInt(t *testing.T) {
+	runBubbleSortCases(t, []sortTestCase[int]{
+		{name: "Multiple numbers in reverse order", given: []int{5, 4, 3, 2, 1}, want: []int{1, 2, 3, 4, 5}},
+		{name: "Empty slice", given: []int{}, want: []int{}},
+		{name: "Single value", given: []int{1}, want: []int{1}},
+		{name: "Negative numbers", given: []int{-5, -1, -3, -2, -4}, want: []int{-5, -4, -3, -2, -1}},
+		{name: "Negative and positive numbers", given: []int{-5, -1, 3, -2, 4}, want: []int{-5, -2, -1, 3, 4}},
+		{name: "Already sorted", given: []int{1, 2, 3, 4, 5}, want: []int{1, 2, 3, 4, 5}},
+		{name: "Duplicates", given: []int{3, 1, 3, 1, 2}, want: []int{1, 1, 2, 3, 3}},
+	})
+}
+
+func TestBubbleSortFloat64(t *testing.T) {
+	runBubbleSortCases(t, []sortTestCase[float64]{
+		{name: "Reverse order", given: []float64{2.5, 1.5, 0.5}, want: []float64{0.5, 1.5, 2.5}},
+		{name: "Negative and positive numbers", given: []float64{3.14, -2.71, 0, -0.5}, want: []float64{-2.71, -0.5, 0, 3.14}},
+		{name: "Very close values", given: []float64{1.0000001, 1.0000000}, want: []float64{1.0000000, 1.0000001}},
+		{name: "Empty slice", given: []float64{}, want: []float64{}},
+	})
+}
+
+func TestBubbleSortString(t *testing.T) {
+	runBubbleSortCases(t, []sortTestCase[string]{
+		{name: "Animals", given: []string{"snake", "cow", "bird"}, want: []string{"bird", "cow", "snake"}},
+		{name: "Upper case before lower case", given: []string{"b", "B", "a", "A"}, want: []string{"A", "B", "a", "b"}},
+		{name: "Prefixes", given: []string{"abc", "ab", "a", ""}, want: []string{"", "a", "ab", "abc"}},
+	})
+}
+
+func TestBubbleSortUint8(t *testing.T) {
+	runBubbleSortCases(t, []sortTestCase[uint8]{
+		{name: "Full range", given: []uint8{255, 0, 128, 1}, want: []uint8{0, 1, 128, 255}},
+	})
+}
+
+// celsius is a named type, to check that types based on an ordered type are accepted too.
+type celsius float64
+
+func TestBubbleSortNamedType(t *testing.T) {
+	runBubbleSortCases(t, []sortTestCase[celsius]{
+		{name: "Temperatures", given: []celsius{21.5, -3, 37.2, 0}, want: []celsius{-3, 0, 21.5, 37.2}},
+	})
+}
+
+// person is the kind of record we want to be able to sort with BubbleSortFunc.
+type person struct {
+	name string
+	age  int
+}
+
+func TestBubbleSortFunc(t *testing.T) {
+	testCases := []struct {
+		name  string
+		given []person
+		less  func(a, b person) bool
+		want  []person
+	}{
+		{
+			name:  "By age",
+			given: []person{{"alice", 30}, {"bob", 25}, {"carol", 35}},
+			less:  func(a, b person) bool { return a.age < b.age },
+			want:  []person{{"bob", 25}, {"alice", 30}, {"carol", 35}},
+		},
+		{
+			name:  "By name, descending",
+			given: []person{{"alice", 30}, {"carol", 35}, {"bob", 25}},
+			less:  func(a, b person) bool { return a.name > b.name },
+			want:  []person{{"carol", 35}, {"bob", 25}, {"alice", 30}},
+		},
+		{
+			name:  "Empty slice",
+			given: []person{},
+			less:  func(a, b person) bool { return a.age < b.age },
+			want:  []person{},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := make([]person, len(tc.given))
+			copy(got, tc.given)
+
+			BubbleSortFunc(got, tc.less)
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("BubbleSortFunc(%v) = %v; want %v", tc.given, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestBubbleSortFuncIsStable(t *testing.T) {
+	// Several people have the same age: once sorted by age, they must stay in the order they were given.
+	given := []person{{"alice", 30}, {"bob", 25}, {"carol", 30}, {"dave", 25}, {"eve", 30}}
+	want := []person{{"bob", 25}, {"dave", 25}, {"alice", 30}, {"carol", 30}, {"eve", 30}}
+
+	BubbleSortFunc(given, func(a, b person) bool { return a.age < b.age })
+	if !reflect.DeepEqual(given, want) {
+		t.Errorf("BubbleSortFunc is not stable: got %v; want %v", given, want)
+	}
+}
+
+func TestSwap(t *testing.T) {
+	testCases := []struct {
+		name  string
+		given []int
+		index int
+		want  []int
+	}{
+		{name: "First pair", given: []int{1, 2, 3}, index: 0, want: []int{2, 1, 3}},
+		{name: "Last pair", given: []int{1, 2, 3}, index: 1, want: []int{1, 3, 2}},
+		{name: "Out of bounds is ignored", given: []int{1, 2, 3}, index: 2, want: []int{1, 2, 3}},
+		{name: "Negative index is ignored", given: []int{1, 2, 3}, index: -1, want: []int{1, 2, 3}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			Swap(tc.given, tc.index)
+			if !reflect.DeepEqual(tc.given, tc.want) {
+				t.Errorf("Swap(..., %d) = %v; want %v", tc.index, tc.given, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package sorting
+
+// CocktailSort sorts a slice of any ordered type using the cocktail shaker sort, a bubble sort that goes both ways.
+
+// A bubble sort only moves big elements fast: they go all the way to the right in one sweep,
+// but a small element at the end of the slice only moves one step to the left per sweep (these are called "turtles").
+// The cocktail shaker sort fixes that by sweeping from left to right, then from right to left, and so on:
+// 1. The left to right sweep moves the biggest element to the end, like a bubble sort.
+// 2. The right to left sweep moves the smallest element to the beginning.
+// 3. Both ends are then at their place, so the next sweeps are one element shorter on each side.
+// 4. As with the bubble sort, if a sweep makes no swap, the slice is sorted and we can stop.
+
+// Like the bubble sort, it only swaps neighbours when the second is strictly smaller, so it is stable.
+// In the returned Stats, a pass is a round trip: one sweep to the right and one sweep back to the left.
+func CocktailSort[T Ordered](s []T) Stats {
+	return CocktailSortFunc(s, less[T])
+}
+
+// CocktailSortFunc is CocktailSort for any type, ordered with the given less function.
+func CocktailSortFunc[T any](s []T, less func(a, b T) bool) (stats Stats) {
+	start, end := 0, len(s)-1
+
+	// compareAndSwap swaps s[i] and s[i+1] if they are in the wrong order, and tells if it did.
+	compareAndSwap := func(i int) bool {
+		stats.Comparisons++
+		if less(s[i+1], s[i]) {
+			Swap(s, i)
+			stats.Swaps++
+			return true
+		}
+		return false
+	}
+
+	for start < end {
+		stats.Passes++
+
+		// Left to right: the biggest element of s[start:end+1] ends up at end.
+		didSwap := false
+		for i := start; i < end; i++ {
+			if compareAndSwap(i) {
+				didSwap = true
+			}
+		}
+		end--
+		if !didSwap {
+			stats.EarlyExit = start < end
+			break
+		}
+
+		// Right to left: the smallest element of s[start:end+1] ends up at start.
+		didSwap = false
+		for i := end - 1; i >= start; i-- {
+			if compareAndSwap(i) {
+				didSwap = true
+			}
+		}
+		start++
+		if !didSwap {
+			stats.EarlyExit = start < end
+			break
+		}
+	}
+	return stats
+}
+
+// Cocktail is the cocktail shaker sort, see CocktailSortFunc.
+type Cocktail struct{}
+
+func (Cocktail) Name() string  { return "cocktail" }
+func (Cocktail) Stable() bool  { return true }
+func (Cocktail) InPlace() bool { return true }
+func (Cocktail) Sort(s []int)  { CocktailSort(s) }
@@ -0,0 +1,66 @@
+package sorting
+
+// Counting is the counting sort.
+// Instead of comparing elements, we count how many times each value appears, and use these counts to compute where each element goes.
+// It is O(n + k), where k is the distance between the smallest and the biggest value, so it is great for values in a small range.
+// It is stable, but needs memory for the counts and for a copy of the input.
+// When the range of values is much bigger than the input, the counts would not fit in memory,
+// so we fall back to the radix sort, which is also stable.
+type Counting struct{}
+
+func (Counting) Name() string  { return "counting" }
+func (Counting) Stable() bool  { return true }
+func (Counting) InPlace() bool { return false }
+func (Counting) Sort(s []int)  { countingSort(s, identity) }
+
+// CountingSortFunc sorts a slice of any type with the counting sort, by the integer key of each element. It is stable.
+func CountingSortFunc[T any](s []T, key func(T) int) { countingSort(s, key) }
+
+// countingSortMaxRange is the range of values we are always ready to count. Above it, we only count if the range is not much bigger than the input.
+const countingSortMaxRange = 1 << 20
+
+func countingSort[T any](s []T, key func(T) int) {
+	if len(s) < 2 {
+		return
+	}
+
+	min, max := key(s[0]), key(s[0])
+	for _, v := range s {
+		k := key(v)
+		if k < min {
+			min = k
+		}
+		if k > max {
+			max = k
+		}
+	}
+
+	// We compute the range as an unsigned number, as max - min overflows an int for very distant values.
+	valueRange := uint64(max) - uint64(min)
+	if valueRange >= countingSortMaxRange && valueRange/4 >= uint64(len(s)) {
+		radixSort(s, key)
+		return
+	}
+
+	// counts[v] is the number of elements with the value min+v.
+	counts := make([]int, valueRange+1)
+	for _, v := range s {
+		counts[key(v)-min]++
+	}
+
+	// We turn the counts into positions: counts[v] becomes where the first element with the value min+v goes.
+	position := 0
+	for v, count := range counts {
+		counts[v] = position
+		position += count
+	}
+
+	// We place the elements in the order we find them, which keeps the sort stable.
+	sorted := make([]T, len(s))
+	for _, v := range s {
+		k := key(v) - min
+		sorted[counts[k]] = v
+		counts[k]++
+	}
+	copy(s, sorted)
+}
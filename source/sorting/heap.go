@@ -0,0 +1,47 @@
+package sorting
+
+// Heap is the heap sort.
+// We first turn the slice into a max-heap (a binary tree stored in the slice, where each parent is bigger than its children).
+// Then we repeatedly swap the root (the biggest element) to the end of the slice and repair the heap on what is left.
+// It is O(n log n) in every case and needs no extra memory, but it is not stable.
+type Heap struct{}
+
+func (Heap) Name() string  { return "heap" }
+func (Heap) Stable() bool  { return false }
+func (Heap) InPlace() bool { return true }
+func (Heap) Sort(s []int)  { heapSort(s, less[int]) }
+
+// HeapSortFunc sorts a slice of any type with the heap sort, ordered with the given less function. It is not stable.
+func HeapSortFunc[T any](s []T, less func(a, b T) bool) { heapSort(s, less) }
+
+func heapSort[T any](s []T, less func(a, b T) bool) {
+	// Build the heap: the second half of the slice are leaves, so we start from the last parent.
+	for i := len(s)/2 - 1; i >= 0; i-- {
+		siftDown(s, i, len(s), less)
+	}
+
+	// Move the biggest element to the end, and repair the heap on the rest.
+	for end := len(s) - 1; end > 0; end-- {
+		s[0], s[end] = s[end], s[0]
+		siftDown(s, 0, end, less)
+	}
+}
+
+// siftDown moves the element at index root down the heap s[:end] until both its children are smaller.
+func siftDown[T any](s []T, root, end int, less func(a, b T) bool) {
+	for {
+		child := 2*root + 1
+		if child >= end {
+			return
+		}
+		// Pick the biggest of the two children.
+		if child+1 < end && less(s[child], s[child+1]) {
+			child++
+		}
+		if !less(s[root], s[child]) {
+			return
+		}
+		s[root], s[child] = s[child], s[root]
+		root = child
+	}
+}
@@ -0,0 +1,28 @@
+package sorting
+
+// Insertion is the insertion sort.
+// We take the elements one by one and insert each of them at its place in the already sorted beginning of the slice,
+// shifting the bigger elements one step to the right to make room.
+// It is O(n²), but very fast on small or nearly sorted slices, which is why other algorithms use it for their small parts.
+type Insertion struct{}
+
+func (Insertion) Name() string  { return "insertion" }
+func (Insertion) Stable() bool  { return true }
+func (Insertion) InPlace() bool { return true }
+func (Insertion) Sort(s []int)  { insertionSort(s, less[int]) }
+
+// InsertionSortFunc sorts a slice of any type with the insertion sort, ordered with the given less function. It is stable.
+func InsertionSortFunc[T any](s []T, less func(a, b T) bool) { insertionSort(s, less) }
+
+func insertionSort[T any](s []T, less func(a, b T) bool) {
+	for i := 1; i < len(s); i++ {
+		current := s[i]
+		j := i
+		// We only move elements that are strictly bigger, so equal elements keep their order (the sort is stable).
+		for j > 0 && less(current, s[j-1]) {
+			s[j] = s[j-1]
+			j--
+		}
+		s[j] = current
+	}
+}
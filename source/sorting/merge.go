@@ -0,0 +1,60 @@
+package sorting
+
+// Merge is the merge sort.
+// We cut the slice in two halves, sort each half the same way, and merge the two sorted halves.
+// It is O(n log n) in every case and stable, but it needs a buffer as big as the input: it does not sort in place.
+type Merge struct{}
+
+func (Merge) Name() string  { return "merge" }
+func (Merge) Stable() bool  { return true }
+func (Merge) InPlace() bool { return false }
+func (Merge) Sort(s []int)  { mergeSort(s, less[int]) }
+
+// MergeSortFunc sorts a slice of any type with the merge sort, ordered with the given less function. It is stable.
+func MergeSortFunc[T any](s []T, less func(a, b T) bool) { mergeSort(s, less) }
+
+// mergeSortCutoff is the size under which we switch to insertion sort (which is stable too).
+const mergeSortCutoff = 12
+
+func mergeSort[T any](s []T, less func(a, b T) bool) {
+	// We allocate the buffer once, and all the merges share it.
+	buffer := make([]T, len(s))
+	mergeSortWithBuffer(s, buffer, less)
+}
+
+func mergeSortWithBuffer[T any](s, buffer []T, less func(a, b T) bool) {
+	if len(s) <= mergeSortCutoff {
+		insertionSort(s, less)
+		return
+	}
+
+	middle := len(s) / 2
+	mergeSortWithBuffer(s[:middle], buffer[:middle], less)
+	mergeSortWithBuffer(s[middle:], buffer[middle:], less)
+
+	// If the two halves are already in order, there is nothing to merge.
+	if !less(s[middle], s[middle-1]) {
+		return
+	}
+
+	copy(buffer, s)
+	mergeInto(s, buffer[:middle], buffer[middle:len(s)], less)
+}
+
+// mergeInto merges the sorted slices left and right into dst, which must be exactly as long as both of them.
+// When two elements are equal, the one from left goes first, which keeps the merge stable.
+func mergeInto[T any](dst, left, right []T, less func(a, b T) bool) {
+	i, j, k := 0, 0, 0
+	for i < len(left) && j < len(right) {
+		if less(right[j], left[i]) {
+			dst[k] = right[j]
+			j++
+		} else {
+			dst[k] = left[i]
+			i++
+		}
+		k++
+	}
+	k += copy(dst[k:], left[i:])
+	copy(dst[k:], right[j:])
+}
@@ -0,0 +1,168 @@
+package sorting
+
+import (
+	"runtime"
+)
+
+// OddEvenSort sorts a slice of any ordered type using the odd-even transposition sort.
+
+// It is a bubble sort where all the comparisons of a sweep are independent of each other:
+// 1. In the even phases, we compare and swap the pairs (0,1), (2,3), (4,5)...
+// 2. In the odd phases, we compare and swap the pairs (1,2), (3,4), (5,6)...
+// 3. After N phases, the slice is sorted. If an even phase and an odd phase in a row make no swap, it is sorted already and we can stop.
+
+// As no two pairs of a phase share an element, the pairs of a phase can all be handled at the same time:
+// this is what ParallelOddEvenSort does, with goroutines.
+// It only swaps neighbours when the second is strictly smaller, so it is stable.
+// In the returned Stats, a pass is a phase.
+func OddEvenSort[T Ordered](s []T) Stats {
+	return OddEvenSortFunc(s, less[T])
+}
+
+// OddEvenSortFunc is OddEvenSort for any type, ordered with the given less function.
+func OddEvenSortFunc[T any](s []T, less func(a, b T) bool) (stats Stats) {
+	quietPhases := 0
+	for phase := 0; phase < len(s); phase++ {
+		stats.Passes++
+
+		comparisons, swaps := oddEvenPairs(s, less, phase%2, 0, len(s))
+		stats.Comparisons += comparisons
+		stats.Swaps += swaps
+
+		if swaps == 0 {
+			quietPhases++
+		} else {
+			quietPhases = 0
+		}
+		// An even and an odd phase without swaps: every pair of neighbours is in order.
+		if quietPhases == 2 {
+			break
+		}
+	}
+	stats.EarlyExit = stats.Passes < len(s)
+	return stats
+}
+
+// oddEvenPairs compares and swaps the pairs of a phase that start in s[from:to]. Pairs start at even indexes if parity is 0, odd ones if it is 1.
+// It returns the number of comparisons and swaps it made.
+func oddEvenPairs[T any](s []T, less func(a, b T) bool, parity, from, to int) (comparisons, swaps int) {
+	// Make sure that we start on an index of the right parity.
+	if from%2 != parity {
+		from++
+	}
+	for i := from; i < to && i+1 < len(s); i += 2 {
+		comparisons++
+		if less(s[i+1], s[i]) {
+			Swap(s, i)
+			swaps++
+		}
+	}
+	return comparisons, swaps
+}
+
+// ParallelOddEvenSort sorts a slice of any ordered type using the odd-even transposition sort,
+// with the pairs of each phase split between several goroutines.
+// If workers is 0 or less, we use one worker per CPU.
+func ParallelOddEvenSort[T Ordered](s []T, workers int) Stats {
+	return ParallelOddEvenSortFunc(s, less[T], workers)
+}
+
+// phaseResult is what a worker sends back to the coordinator at the end of each phase.
+type phaseResult struct {
+	comparisons, swaps int
+}
+
+// ParallelOddEvenSortFunc is ParallelOddEvenSort for any type, ordered with the given less function.
+//
+// Each worker owns a part of the slice for the whole sort. For each phase:
+// 1. The coordinator (the calling goroutine) sends the phase number to every worker.
+// 2. Each worker compares and swaps the pairs that start in its part, and sends back how many swaps it made.
+// 3. The coordinator waits for all the workers before starting the next phase: a pair at the edge of a part uses an element of
+// the next part, so the phases must never overlap.
+func ParallelOddEvenSortFunc[T any](s []T, less func(a, b T) bool, workers int) (stats Stats) {
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	// There is no point in having more workers than pairs.
+	if workers > len(s)/2 {
+		workers = len(s) / 2
+	}
+	if workers <= 1 {
+		return OddEvenSortFunc(s, less)
+	}
+
+	phases := make([]chan int, workers)
+	results := make(chan phaseResult)
+
+	// Each part has the same size, give or take one element. Part boundaries are kept even, so that no pair is split.
+	partSize := (len(s)/workers + 1) &^ 1
+	for w := range phases {
+		phases[w] = make(chan int)
+		from, to := w*partSize, (w+1)*partSize
+		if w == workers-1 || to > len(s) {
+			to = len(s)
+		}
+		if from > len(s) {
+			from = len(s)
+		}
+
+		go func(phase chan int, from, to int) {
+			for p := range phase {
+				comparisons, swaps := oddEvenPairs(s, less, p%2, from, to)
+				results <- phaseResult{comparisons, swaps}
+			}
+		}(phases[w], from, to)
+	}
+
+	quietPhases := 0
+	for phase := 0; phase < len(s); phase++ {
+		stats.Passes++
+
+		for _, p := range phases {
+			p <- phase
+		}
+		swaps := 0
+		for range phases {
+			result := <-results
+			stats.Comparisons += result.comparisons
+			swaps += result.swaps
+		}
+		stats.Swaps += swaps
+
+		if swaps == 0 {
+			quietPhases++
+		} else {
+			quietPhases = 0
+		}
+		if quietPhases == 2 {
+			break
+		}
+	}
+
+	// Closing the phase channels stops the workers.
+	for _, p := range phases {
+		close(p)
+	}
+
+	stats.EarlyExit = stats.Passes < len(s)
+	return stats
+}
+
+// OddEven is the odd-even transposition sort, see OddEvenSortFunc.
+type OddEven struct{}
+
+func (OddEven) Name() string  { return "oddeven" }
+func (OddEven) Stable() bool  { return true }
+func (OddEven) InPlace() bool { return true }
+func (OddEven) Sort(s []int)  { OddEvenSort(s) }
+
+// ParallelOddEven is the odd-even transposition sort with goroutines, see ParallelOddEvenSortFunc.
+// Workers is the number of goroutines, 0 means one per CPU.
+type ParallelOddEven struct {
+	Workers int
+}
+
+func (ParallelOddEven) Name() string   { return "oddeven-parallel" }
+func (ParallelOddEven) Stable() bool   { return true }
+func (ParallelOddEven) InPlace() bool  { return true }
+func (p ParallelOddEven) Sort(s []int) { ParallelOddEvenSort(s, p.Workers) }
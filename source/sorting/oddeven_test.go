@@ -0,0 +1,93 @@
+package sorting
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// bubbleVariants are all the bubble sorts of the package, as functions on integers.
+var bubbleVariants = map[string]func(s []int){
+	"bubble":             func(s []int) { BubbleSort(s) },
+	"cocktail":           func(s []int) { CocktailSort(s) },
+	"oddeven":            func(s []int) { OddEvenSort(s) },
+	"oddeven-parallel-2": func(s []int) { ParallelOddEvenSort(s, 2) },
+	"oddeven-parallel-3": func(s []int) { ParallelOddEvenSort(s, 3) },
+	"oddeven-parallel-8": func(s []int) { ParallelOddEvenSort(s, 8) },
+}
+
+// FuzzBubbleVariants checks that every bubble sort variant gives the same result as sort.Ints.
+// Run it with: go test -fuzz=FuzzBubbleVariants ./source/sorting
+func FuzzBubbleVariants(f *testing.F) {
+	f.Add([]byte{})
+	f.Add([]byte{1})
+	f.Add([]byte{5, 4, 3, 2, 1})
+	f.Add([]byte{1, 2, 3, 4, 5, 6, 7, 8, 9})
+	f.Add([]byte{3, 3, 1, 1, 2, 2, 0, 255, 128, 127})
+	f.Add([]byte("the quick brown fox jumps over the lazy dog"))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		// Each byte becomes a number between -128 and 127, so that we get negative numbers and many duplicates.
+		given := make([]int, len(data))
+		for i, b := range data {
+			given[i] = int(int8(b))
+		}
+		want := append([]int{}, given...)
+		sort.Ints(want)
+
+		for name, variant := range bubbleVariants {
+			got := append([]int{}, given...)
+			variant(got)
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("%s(%v) = %v; want %v", name, given, got, want)
+			}
+		}
+	})
+}
+
+func TestCocktailSortStats(t *testing.T) {
+	testCases := []struct {
+		name  string
+		given []int
+		want  Stats
+	}{
+		{
+			name:  "Already sorted",
+			given: []int{1, 2, 3, 4, 5},
+			want:  Stats{Comparisons: 4, Swaps: 0, Passes: 1, EarlyExit: true},
+		},
+		{
+			// The turtle that takes a bubble sort 4 passes goes home in the first sweep back to the left.
+			name:  "Smallest at the end",
+			given: []int{2, 3, 4, 5, 1},
+			want:  Stats{Comparisons: 9, Swaps: 4, Passes: 2, EarlyExit: true},
+		},
+		{
+			name:  "Reverse order",
+			given: []int{5, 4, 3, 2, 1},
+			want:  Stats{Comparisons: 10, Swaps: 10, Passes: 2, EarlyExit: false},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := CocktailSort(append([]int{}, tc.given...))
+			if got != tc.want {
+				t.Errorf("CocktailSort(%v) stats = %+v; want %+v", tc.given, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestParallelOddEvenSortMatchesSequential(t *testing.T) {
+	// The parallel version makes the exact same swaps as the sequential one, just spread over several goroutines.
+	given := []int{9, 8, 7, 6, 5, 4, 3, 2, 1, 0, 10, 12, 11}
+	sequential := OddEvenSort(append([]int{}, given...))
+
+	for _, workers := range []int{0, 1, 2, 4, 6, 100} {
+		got := ParallelOddEvenSort(append([]int{}, given...), workers)
+		if got != sequential {
+			t.Errorf("ParallelOddEvenSort with %d workers stats = %+v; want %+v", workers, got, sequential)
+		}
+	}
+}
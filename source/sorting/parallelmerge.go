@@ -0,0 +1,170 @@
+package sorting
+
+import (
+	"context"
+	"runtime"
+	"sync/atomic"
+)
+
+// ParallelMergeSort sorts a slice of any ordered type with a merge sort that sorts the two halves in parallel, see ParallelMergeSortFunc.
+func ParallelMergeSort[T Ordered](s []T, cutoff, goroutines int) {
+	ParallelMergeSortFunc(s, less[T], cutoff, goroutines)
+}
+
+// minParallelSize is the smallest half that gets its own goroutine: starting a goroutine for less costs more than it saves.
+const minParallelSize = 4096
+
+// ParallelMergeSortFunc is ParallelMergeSort for any type, ordered with the given less function.
+//
+// Like the merge sort, it cuts the slice in two halves, sorts them, and merges them, down to parts of cutoff elements,
+// which are sorted with the insertion sort. The difference is that the first half is sorted by a new goroutine while the
+// current one sorts the second half, as long as fewer than goroutines goroutines are sorting (the calling one included).
+// When they are all busy, the current goroutine sorts both halves itself: the number of goroutines never goes over the limit,
+// and no goroutine ever waits for a free slot.
+//
+// A cutoff of 0 or less uses the same one as Merge, and goroutines of 0 or less means one per CPU. It is stable.
+func ParallelMergeSortFunc[T any](s []T, less func(a, b T) bool, cutoff, goroutines int) {
+	ParallelMergeSortFuncContext(context.Background(), s, less, cutoff, goroutines, nil)
+}
+
+// ParallelMergeSortContext is ParallelMergeSort, which stops when the context is done, see ParallelMergeSortFuncContext.
+func ParallelMergeSortContext[T Ordered](ctx context.Context, s []T, cutoff, goroutines int, progress func(merged, total int)) error {
+	return ParallelMergeSortFuncContext(ctx, s, less[T], cutoff, goroutines, progress)
+}
+
+// ParallelMergeSortFuncContext is ParallelMergeSortFunc, which stops when the context is done (cancelled, or after its timeout)
+// and then returns ctx.Err(). The slice is then left partly sorted. If the context is done once the last merge has started,
+// the slice ends up sorted anyway, and we return nil: the work was done. It only returns once all its goroutines are finished.
+// The halves being merged when the context is done are merged to the end, so stopping takes at most the time of one merge.
+//
+// If progress is not nil, it is called after each merge, with the number of elements merged so far and the number of
+// elements all the merges will have merged at the end. It is called by several goroutines, possibly at the same time,
+// so it must be safe for that, and the calls may arrive a little out of order.
+func ParallelMergeSortFuncContext[T any](ctx context.Context, s []T, less func(a, b T) bool, cutoff, goroutines int, progress func(merged, total int)) error {
+	if cutoff <= 0 {
+		cutoff = mergeSortCutoff
+	}
+	if goroutines <= 0 {
+		goroutines = runtime.NumCPU()
+	}
+
+	p := &parallelMerge[T]{
+		less:   less,
+		cutoff: cutoff,
+		// slots has one token per goroutine we may start: the calling goroutine does not need one.
+		slots:    make(chan struct{}, goroutines-1),
+		done:     ctx.Done(),
+		progress: progress,
+	}
+	if progress != nil {
+		p.total = mergeWork(len(s), cutoff)
+	}
+	// As with the merge sort, all the merges share one buffer. The two halves use different parts of it, so they can be sorted at the same time.
+	if !p.sort(s, make([]T, len(s))) {
+		return ctx.Err()
+	}
+	return nil
+}
+
+// parallelMerge holds what all the steps of a parallel merge sort share.
+type parallelMerge[T any] struct {
+	merged   int64 // Elements merged so far, changed atomically. It comes first so that it is aligned on 64 bits even on 32 bits machines.
+	less     func(a, b T) bool
+	cutoff   int
+	slots    chan struct{}
+	done     <-chan struct{} // Closed when the sort must stop. nil when it can't be cancelled.
+	progress func(merged, total int)
+	total    int
+}
+
+// stopped tells if the context is done.
+func (p *parallelMerge[T]) stopped() bool {
+	select {
+	case <-p.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// sort sorts s, and tells if it did: it returns false when it stopped early because the context is done.
+func (p *parallelMerge[T]) sort(s, buffer []T) bool {
+	if len(s) <= p.cutoff {
+		insertionSort(s, p.less)
+		return true
+	}
+	if p.stopped() {
+		return false
+	}
+
+	middle := len(s) / 2
+
+	// We try to take a slot without waiting: if there is none, we just do the work ourselves.
+	parallel := false
+	if middle >= minParallelSize {
+		select {
+		case p.slots <- struct{}{}:
+			parallel = true
+		default:
+		}
+	}
+
+	var first, second bool
+	if parallel {
+		done := make(chan struct{})
+		go func() {
+			first = p.sort(s[:middle], buffer[:middle])
+			<-p.slots // Give the slot back.
+			close(done)
+		}()
+		second = p.sort(s[middle:], buffer[middle:])
+		<-done
+	} else {
+		first = p.sort(s[:middle], buffer[:middle])
+		second = p.sort(s[middle:], buffer[middle:])
+	}
+
+	if !first || !second || p.stopped() {
+		return false
+	}
+	// If the two halves are already in order, there is nothing to merge.
+	if p.less(s[middle], s[middle-1]) {
+		copy(buffer, s)
+		mergeInto(s, buffer[:middle], buffer[middle:len(s)], p.less)
+	}
+	if p.progress != nil {
+		p.progress(int(atomic.AddInt64(&p.merged, int64(len(s)))), p.total)
+	}
+	return true
+}
+
+// mergeWork returns the number of elements all the merges of a slice of n elements merge, counting the ones that are skipped
+// because the halves are already in order.
+func mergeWork(n, cutoff int) int {
+	// All the parts of a level have one of two sizes, so remembering the sizes already seen makes it quick even for huge slices.
+	seen := map[int]int{}
+	var work func(n int) int
+	work = func(n int) int {
+		if n <= cutoff {
+			return 0
+		}
+		if w, ok := seen[n]; ok {
+			return w
+		}
+		seen[n] = n + work(n/2) + work(n-n/2)
+		return seen[n]
+	}
+	return work(n)
+}
+
+// ParallelMerge is the merge sort with goroutines, see ParallelMergeSortFunc.
+// Cutoff is the size under which the insertion sort is used, and Goroutines the most goroutines sorting at once (0 means the defaults).
+type ParallelMerge struct {
+	Cutoff     int
+	Goroutines int
+}
+
+func (ParallelMerge) Name() string   { return "merge-parallel" }
+func (ParallelMerge) Stable() bool   { return true }
+func (ParallelMerge) InPlace() bool  { return false }
+func (p ParallelMerge) Sort(s []int) { ParallelMergeSort(s, p.Cutoff, p.Goroutines) }
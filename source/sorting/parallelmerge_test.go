@@ -0,0 +1,198 @@
+package sorting
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"reflect"
+	"runtime"
+	"sort"
+	"sync"
+	"testing"
+)
+
+func TestParallelMergeSort(t *testing.T) {
+	// The conformance tests use small slices, which are never split between goroutines: here the slices are big enough to be.
+	random := rand.New(rand.NewSource(3))
+	input := make([]int, 100_000)
+	for i := range input {
+		input[i] = random.Intn(1000) - 500
+	}
+	want := append([]int{}, input...)
+	sort.Ints(want)
+
+	tests := []struct {
+		name               string
+		cutoff, goroutines int
+	}{
+		{name: "defaults", cutoff: 0, goroutines: 0},
+		{name: "one goroutine", cutoff: 12, goroutines: 1},
+		{name: "two goroutines", cutoff: 12, goroutines: 2},
+		{name: "many goroutines", cutoff: 12, goroutines: 64},
+		{name: "cutoff of 1", cutoff: 1, goroutines: 4},
+		{name: "big cutoff", cutoff: 500, goroutines: 4},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := append([]int{}, input...)
+			ParallelMergeSort(got, tc.cutoff, tc.goroutines)
+			for i := range got {
+				if got[i] != want[i] {
+					t.Fatalf("at index %d: got %d; want %d", i, got[i], want[i])
+				}
+			}
+
+			// And it is stable.
+			records := make([]record, len(input))
+			for i, v := range input {
+				records[i] = record{key: v, id: i}
+			}
+			ParallelMergeSortFunc(records, byKey, tc.cutoff, tc.goroutines)
+			for i := 1; i < len(records); i++ {
+				if records[i].key == records[i-1].key && records[i].id < records[i-1].id {
+					t.Fatalf("%v ended up after %v", records[i-1], records[i])
+				}
+			}
+		})
+	}
+}
+
+func TestParallelMergeSortGoroutineLimit(t *testing.T) {
+	// We look at the number of goroutines at every comparison: it must never go over the limit (the test's own goroutines excluded).
+	const limit = 3
+	before := runtime.NumGoroutine()
+	var mutex sync.Mutex
+	most := 0
+	less := func(a, b int) bool {
+		n := runtime.NumGoroutine() - before
+		mutex.Lock()
+		if n > most {
+			most = n
+		}
+		mutex.Unlock()
+		return a < b
+	}
+
+	random := rand.New(rand.NewSource(4))
+	s := make([]int, 200_000)
+	for i := range s {
+		s[i] = random.Int()
+	}
+	ParallelMergeSortFunc(s, less, 0, limit)
+
+	// The calling goroutine is one of them, so at most limit-1 goroutines are started.
+	if most > limit-1 {
+		t.Errorf("%d goroutines were started; want %d at most", most, limit-1)
+	}
+	if !sort.IntsAreSorted(s) {
+		t.Errorf("the slice is not sorted")
+	}
+}
+
+func TestParallelMergeSortContext(t *testing.T) {
+	random := rand.New(rand.NewSource(5))
+	input := make([]int, 100_000)
+	for i := range input {
+		input[i] = random.Int()
+	}
+
+	t.Run("progress", func(t *testing.T) {
+		var mutex sync.Mutex
+		calls, most, total := 0, 0, 0
+		s := append([]int{}, input...)
+		err := ParallelMergeSortContext(context.Background(), s, 0, 4, func(merged, all int) {
+			mutex.Lock()
+			defer mutex.Unlock()
+			calls++
+			if merged > most {
+				most = merged
+			}
+			total = all
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !sort.IntsAreSorted(s) {
+			t.Error("the slice is not sorted")
+		}
+		if calls == 0 || most != total || total != mergeWork(len(s), mergeSortCutoff) {
+			t.Errorf("got %d calls, %d merged out of %d; want %d merged at the end", calls, most, total, mergeWork(len(s), mergeSortCutoff))
+		}
+	})
+
+	t.Run("already cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		s := append([]int{}, input...)
+		if err := ParallelMergeSortContext(ctx, s, 0, 4, nil); !errors.Is(err, context.Canceled) {
+			t.Errorf("got %v; want context.Canceled", err)
+		}
+		if !reflect.DeepEqual(s, input) {
+			t.Error("the slice was changed anyway")
+		}
+	})
+
+	t.Run("cancelled after the last merge", func(t *testing.T) {
+		// The slice is sorted when the context is done: the work was done, so it is not an error.
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		s := append([]int{}, input...)
+		err := ParallelMergeSortContext(ctx, s, 0, 4, func(merged, all int) {
+			if merged == all {
+				cancel()
+			}
+		})
+		if ctx.Err() == nil {
+			t.Fatal("the last merge did not cancel the context")
+		}
+		if err != nil {
+			t.Errorf("got %v; want nil, the slice was sorted before the cancellation", err)
+		}
+		if !sort.IntsAreSorted(s) {
+			t.Error("the slice is not sorted")
+		}
+	})
+
+	t.Run("cancelled while sorting", func(t *testing.T) {
+		// We cancel after the first merges: the sort must stop long before the end, and leave no goroutine behind.
+		before := runtime.NumGoroutine()
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		var mutex sync.Mutex
+		most, total := 0, 0
+		s := append([]int{}, input...)
+		err := ParallelMergeSortContext(ctx, s, 0, 4, func(merged, all int) {
+			mutex.Lock()
+			defer mutex.Unlock()
+			if merged > most {
+				most, total = merged, all
+			}
+			if merged > 1000 {
+				cancel()
+			}
+		})
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("got %v; want context.Canceled", err)
+		}
+		if most > total/2 {
+			t.Errorf("%d elements out of %d were merged after the cancellation", most, total)
+		}
+		if after := runtime.NumGoroutine(); after > before {
+			t.Errorf("%d goroutines are still running", after-before)
+		}
+	})
+}
+
+func TestMergeWork(t *testing.T) {
+	tests := []struct{ n, cutoff, want int }{
+		{n: 10, cutoff: 12, want: 0},
+		{n: 13, cutoff: 12, want: 13},
+		{n: 8, cutoff: 1, want: 24}, // 3 levels of 8 elements.
+		{n: 5, cutoff: 1, want: 12}, // 5, then 2 + 3, then 2 (from the 3).
+	}
+	for _, tc := range tests {
+		if got := mergeWork(tc.n, tc.cutoff); got != tc.want {
+			t.Errorf("mergeWork(%d, %d): got %d; want %d", tc.n, tc.cutoff, got, tc.want)
+		}
+	}
+}
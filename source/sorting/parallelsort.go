@@ -0,0 +1,100 @@
+package sorting
+
+import "context"
+
+// ParallelSort sorts a slice of any type with the parallel merge sort, see ParallelMergeSortFunc. It is stable:
+// records that are equal for less keep their order, so sorting by one field then by another gives records sorted by
+// the second field, then by the first one.
+//
+// The options change the cutoff, the number of goroutines, or report the progress, for example:
+//
+//	sorting.ParallelSort(people, func(a, b Person) bool { return a.Age < b.Age }, sorting.WithGoroutines(4))
+func ParallelSort[T any](s []T, less func(a, b T) bool, opts ...Option) {
+	ParallelSortContext(context.Background(), s, less, opts...)
+}
+
+// ParallelSortContext is ParallelSort, which stops and returns ctx.Err() when the context is done, see ParallelMergeSortFuncContext.
+func ParallelSortContext[T any](ctx context.Context, s []T, less func(a, b T) bool, opts ...Option) error {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return ParallelMergeSortFuncContext(ctx, s, less, o.cutoff, o.goroutines, o.progress)
+}
+
+// Option changes how ParallelSort sorts. Without any, it uses the defaults of ParallelMergeSortFunc.
+type Option func(*options)
+
+// options are the settings changed by the options. Their zero value is the defaults.
+type options struct {
+	cutoff     int
+	goroutines int
+	progress   func(merged, total int)
+}
+
+// WithCutoff sets the size under which the parts are sorted with the insertion sort.
+func WithCutoff(cutoff int) Option {
+	return func(o *options) { o.cutoff = cutoff }
+}
+
+// WithGoroutines sets the most goroutines sorting at once, the calling one included.
+func WithGoroutines(goroutines int) Option {
+	return func(o *options) { o.goroutines = goroutines }
+}
+
+// WithProgress sets the function told about the progress of the merges, see ParallelMergeSortFuncContext.
+func WithProgress(progress func(merged, total int)) Option {
+	return func(o *options) { o.progress = progress }
+}
+
+// Key is one field to sort records by, in increasing or decreasing order. Get one with Ascending or Descending.
+type Key[T any] struct {
+	// compare returns a negative number when a comes before b, a positive one when b comes before a, and 0 when they are equal.
+	compare func(a, b T) int
+}
+
+// Ascending sorts records by a field in increasing order. field returns the field of a record, for example:
+//
+//	sorting.Ascending(func(p Person) string { return p.Name })
+func Ascending[T any, K Ordered](field func(T) K) Key[T] {
+	return Key[T]{compare: func(a, b T) int {
+		x, y := field(a), field(b)
+		switch {
+		case x < y:
+			return -1
+		case y < x:
+			return 1
+		default:
+			// Equal, or NaN floats, which are not smaller nor bigger than anything: we keep them where they are.
+			return 0
+		}
+	}}
+}
+
+// Descending sorts records by a field in decreasing order, see Ascending.
+func Descending[T any, K Ordered](field func(T) K) Key[T] {
+	ascending := Ascending(field)
+	return Key[T]{compare: func(a, b T) int { return ascending.compare(b, a) }}
+}
+
+// ByKeys returns the less function that orders records by the first key, then by the second one when the first
+// fields are equal, and so on. Records equal on all the keys are equal for the less function.
+func ByKeys[T any](keys ...Key[T]) func(a, b T) bool {
+	return func(a, b T) bool {
+		for _, k := range keys {
+			if c := k.compare(a, b); c != 0 {
+				return c < 0
+			}
+		}
+		return false
+	}
+}
+
+// SortByKey sorts records by one or more fields with ParallelSort, for example people by city, then from the oldest to the youngest:
+//
+//	sorting.SortByKey(people, sorting.Ascending(func(p Person) string { return p.City }), sorting.Descending(func(p Person) int { return p.Age }))
+//
+// Records equal on all the keys keep their order. Use ParallelSort with ByKeys to give options too.
+func SortByKey[T any](s []T, keys ...Key[T]) {
+	ParallelSort(s, ByKeys(keys...))
+}
@@ -0,0 +1,161 @@
+package sorting
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"math/rand"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestParallelSort(t *testing.T) {
+	// Big enough to be split between goroutines, with a lot of equal keys to check that it is stable.
+	random := rand.New(rand.NewSource(5))
+	input := make([]record, 50_000)
+	for i := range input {
+		input[i] = record{key: random.Intn(100), id: i}
+	}
+
+	tests := []struct {
+		name string
+		opts []Option
+	}{
+		{name: "no option"},
+		{name: "one goroutine", opts: []Option{WithGoroutines(1)}},
+		{name: "cutoff and goroutines", opts: []Option{WithCutoff(4), WithGoroutines(8)}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := append([]record{}, input...)
+			ParallelSort(got, byKey, tc.opts...)
+			for i := 1; i < len(got); i++ {
+				if got[i].key < got[i-1].key || (got[i].key == got[i-1].key && got[i].id < got[i-1].id) {
+					t.Fatalf("%v ended up after %v", got[i-1], got[i])
+				}
+			}
+		})
+	}
+}
+
+func TestParallelSortContext(t *testing.T) {
+	s := []string{"pear", "apple", "fig", "banana"}
+	last := 0
+	err := ParallelSortContext(context.Background(), s, func(a, b string) bool { return a < b }, WithCutoff(1), WithProgress(func(merged, total int) { last = total }))
+	if err != nil || !reflect.DeepEqual(s, []string{"apple", "banana", "fig", "pear"}) || last == 0 {
+		t.Errorf("got %v, the error %v and a total of %d", s, err, last)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := ParallelSortContext(ctx, make([]string, 1000), func(a, b string) bool { return a < b }); !errors.Is(err, context.Canceled) {
+		t.Errorf("cancelled: got error %v; want %v", err, context.Canceled)
+	}
+}
+
+// city is a record read from a CSV file, like the ones SortByKey is meant for.
+type city struct {
+	name       string
+	country    string
+	population int
+	area       float64
+}
+
+// cities is a small CSV file, with cities of the same country and the same population to check the keys after the first one.
+const cities = `name,country,population,area
+Lyon,France,522,47.9
+Porto,Portugal,232,41.4
+Paris,France,2102,105.4
+Lille,France,236,34.8
+Braga,Portugal,193,183.4
+Lisbon,Portugal,545,100.1
+Nantes,France,320,65.2
+Toulouse,France,504,118.3
+Coimbra,Portugal,143,319.4
+Rennes,France,222,50.4
+Nice,France,342,71.9
+Faro,Portugal,64,202.6
+Amadora,Portugal,171,23.8
+Brest,France,139,49.5
+Setubal,Portugal,123,230.3
+Limoges,France,130,77.5
+Vila Real,Portugal,51,378.8
+Metz,France,120,41.9
+Évora,Portugal,53,1307.1
+Tours,France,137,34.4
+Aveiro,Portugal,80,197.6
+`
+
+// readCities parses the CSV file into records.
+func readCities(t *testing.T) []city {
+	rows, err := csv.NewReader(strings.NewReader(cities)).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var records []city
+	for _, row := range rows[1:] {
+		population, err := strconv.Atoi(row[2])
+		if err != nil {
+			t.Fatal(err)
+		}
+		area, err := strconv.ParseFloat(row[3], 64)
+		if err != nil {
+			t.Fatal(err)
+		}
+		records = append(records, city{row[0], row[1], population, area})
+	}
+	return records
+}
+
+func TestSortByKey(t *testing.T) {
+	name := func(c city) string { return c.name }
+	country := func(c city) string { return c.country }
+	population := func(c city) int { return c.population }
+	area := func(c city) float64 { return c.area }
+
+	tests := []struct {
+		name string
+		keys []Key[city]
+		want []string // The first names once sorted.
+	}{
+		{name: "by name", keys: []Key[city]{Ascending(name)}, want: []string{"Amadora", "Aveiro", "Braga", "Brest"}},
+		{name: "by population, descending", keys: []Key[city]{Descending(population)}, want: []string{"Paris", "Lisbon", "Lyon", "Toulouse"}},
+		{name: "by country, then population descending", keys: []Key[city]{Ascending(country), Descending(population)}, want: []string{"Paris", "Lyon", "Toulouse", "Nice"}},
+		{name: "by country descending, then area", keys: []Key[city]{Descending(country), Ascending(area)}, want: []string{"Amadora", "Porto", "Lisbon", "Braga"}},
+		// Records equal on all the keys keep the order of the file.
+		{name: "by country only", keys: []Key[city]{Ascending(country)}, want: []string{"Lyon", "Paris", "Lille", "Nantes"}},
+		{name: "no key", keys: nil, want: []string{"Lyon", "Porto", "Paris", "Lille"}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			records := readCities(t)
+			SortByKey(records, tc.keys...)
+			var got []string
+			for _, r := range records[:len(tc.want)] {
+				got = append(got, r.name)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got %v; want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestSortByKeyLarge(t *testing.T) {
+	// Enough records for several goroutines: they must be in order for both keys, and in their first order when equal on both.
+	type row struct{ a, b, id int }
+	random := rand.New(rand.NewSource(8))
+	records := make([]row, 20_000)
+	for i := range records {
+		records[i] = row{random.Intn(10), random.Intn(10), i}
+	}
+	SortByKey(records, Descending(func(r row) int { return r.a }), Ascending(func(r row) int { return r.b }))
+	for i := 1; i < len(records); i++ {
+		p, r := records[i-1], records[i]
+		if p.a < r.a || (p.a == r.a && (p.b > r.b || (p.b == r.b && p.id > r.id))) {
+			t.Fatalf("%v ended up before %v", p, r)
+		}
+	}
+}
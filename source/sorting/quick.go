@@ -0,0 +1,73 @@
+package sorting
+
+// Quick is the quick sort, with a median-of-three pivot.
+// We pick a pivot, move the smaller elements to its left and the bigger ones to its right, and sort both sides the same way.
+// Taking the median of the first, middle and last elements as pivot avoids the O(n²) worst case on sorted or reversed slices.
+// Small parts are finished with an insertion sort, which is faster there.
+// It is not stable. It sorts in place: it only uses O(log n) memory for the recursion, as we always recurse on the smaller side.
+type Quick struct{}
+
+func (Quick) Name() string  { return "quick" }
+func (Quick) Stable() bool  { return false }
+func (Quick) InPlace() bool { return true }
+func (Quick) Sort(s []int)  { quickSort(s, less[int]) }
+
+// QuickSortFunc sorts a slice of any type with the quick sort, ordered with the given less function. It is not stable.
+func QuickSortFunc[T any](s []T, less func(a, b T) bool) { quickSort(s, less) }
+
+// quickSortCutoff is the size under which we switch to insertion sort.
+const quickSortCutoff = 12
+
+func quickSort[T any](s []T, less func(a, b T) bool) {
+	for len(s) > quickSortCutoff {
+		p := partition(s, less)
+
+		// Recurse on the smaller side and loop on the bigger one, so that the recursion depth stays O(log n).
+		if p+1 < len(s)-p-1 {
+			quickSort(s[:p+1], less)
+			s = s[p+1:]
+		} else {
+			quickSort(s[p+1:], less)
+			s = s[:p+1]
+		}
+	}
+	insertionSort(s, less)
+}
+
+// partition splits the slice in two around a pivot and returns the index p of the end of the left part:
+// everything in s[:p+1] is smaller or equal to the pivot, everything in s[p+1:] is bigger or equal.
+// Both sides stop on elements equal to the pivot, so a slice full of duplicates is still split in two halves.
+func partition[T any](s []T, less func(a, b T) bool) int {
+	middle := (len(s) - 1) / 2
+	medianOfThree(s, 0, middle, len(s)-1, less)
+	pivot := s[middle]
+
+	i, j := -1, len(s)
+	for {
+		i++
+		for less(s[i], pivot) {
+			i++
+		}
+		j--
+		for less(pivot, s[j]) {
+			j--
+		}
+		if i >= j {
+			return j
+		}
+		s[i], s[j] = s[j], s[i]
+	}
+}
+
+// medianOfThree orders s[a], s[b] and s[c], so that the median of the three ends up in s[b].
+func medianOfThree[T any](s []T, a, b, c int, less func(a, b T) bool) {
+	if less(s[b], s[a]) {
+		s[a], s[b] = s[b], s[a]
+	}
+	if less(s[c], s[b]) {
+		s[b], s[c] = s[c], s[b]
+		if less(s[b], s[a]) {
+			s[a], s[b] = s[b], s[a]
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package sorting
+
+// Radix is the least significant digit radix sort, working on bytes.
+// We do a stable counting sort on the lowest byte of the values, then on the second byte, and so on up to the highest byte.
+// As each pass is stable, the order given by the previous bytes is kept for equal bytes, and at the end the slice is sorted.
+// It is O(n) for a fixed size of integer, stable, but needs a buffer as big as the input.
+type Radix struct{}
+
+func (Radix) Name() string  { return "radix" }
+func (Radix) Stable() bool  { return true }
+func (Radix) InPlace() bool { return false }
+func (Radix) Sort(s []int)  { radixSort(s, identity) }
+
+// RadixSortFunc sorts a slice of any type with the radix sort, by the integer key of each element. It is stable.
+func RadixSortFunc[T any](s []T, key func(T) int) { radixSort(s, key) }
+
+func radixSort[T any](s []T, key func(T) int) {
+	if len(s) < 2 {
+		return
+	}
+
+	// Flipping the sign bit turns the integers into unsigned numbers in the same order:
+	// negative numbers become smaller than positive ones.
+	const signBit = uint64(1) << 63
+	digits := func(v T) uint64 {
+		return uint64(key(v)) ^ signBit
+	}
+
+	buffer := make([]T, len(s))
+	src, dst := s, buffer
+
+	for shift := uint(0); shift < 64; shift += 8 {
+		var counts [256]int
+		for _, v := range src {
+			counts[(digits(v)>>shift)&0xff]++
+		}
+
+		// If all the elements have the same byte here, this pass would not change anything.
+		if counts[(digits(src[0])>>shift)&0xff] == len(src) {
+			continue
+		}
+
+		position := 0
+		for b, count := range counts {
+			counts[b] = position
+			position += count
+		}
+		for _, v := range src {
+			b := (digits(v) >> shift) & 0xff
+			dst[counts[b]] = v
+			counts[b]++
+		}
+		src, dst = dst, src
+	}
+
+	// After an odd number of passes, the result is in the buffer.
+	if &src[0] != &s[0] {
+		copy(s, src)
+	}
+}
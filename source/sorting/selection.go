@@ -0,0 +1,27 @@
+package sorting
+
+// Selection is the selection sort.
+// For each position, we look for the smallest element in the rest of the slice and swap it into place.
+// It always does O(n²) comparisons but at most n-1 swaps.
+// It is not stable: the swap can send an element behind another one that is equal to it.
+type Selection struct{}
+
+func (Selection) Name() string  { return "selection" }
+func (Selection) Stable() bool  { return false }
+func (Selection) InPlace() bool { return true }
+func (Selection) Sort(s []int)  { selectionSort(s, less[int]) }
+
+// SelectionSortFunc sorts a slice of any type with the selection sort, ordered with the given less function. It is not stable.
+func SelectionSortFunc[T any](s []T, less func(a, b T) bool) { selectionSort(s, less) }
+
+func selectionSort[T any](s []T, less func(a, b T) bool) {
+	for i := 0; i < len(s)-1; i++ {
+		smallest := i
+		for j := i + 1; j < len(s); j++ {
+			if less(s[j], s[smallest]) {
+				smallest = j
+			}
+		}
+		s[i], s[smallest] = s[smallest], s[i]
+	}
+}
@@ -0,0 +1,39 @@
+package sorting
+
+// Shell is the Shell sort.
+// It is an insertion sort on elements that are "gap" positions apart, with a gap that gets smaller at each round.
+// The last round uses a gap of 1, which is a regular insertion sort, but by then the slice is nearly sorted so it is fast.
+// It is not stable, as elements jump over their neighbours when the gap is bigger than 1.
+type Shell struct{}
+
+func (Shell) Name() string  { return "shell" }
+func (Shell) Stable() bool  { return false }
+func (Shell) InPlace() bool { return true }
+func (Shell) Sort(s []int)  { shellSort(s, less[int]) }
+
+// ShellSortFunc sorts a slice of any type with the Shell sort, ordered with the given less function. It is not stable.
+func ShellSortFunc[T any](s []T, less func(a, b T) bool) { shellSort(s, less) }
+
+// shellGaps is the gap sequence found by Marcin Ciura, which is one of the best known.
+// Above 1750, we continue the sequence by multiplying the gap by 2.25.
+var shellGaps = []int{701, 301, 132, 57, 23, 10, 4, 1}
+
+func shellSort[T any](s []T, less func(a, b T) bool) {
+	// Build the big gaps first, if the slice is big enough to need them.
+	var bigGaps []int
+	for gap := 1750; gap < len(s); gap = gap * 9 / 4 {
+		bigGaps = append([]int{gap}, bigGaps...)
+	}
+
+	for _, gap := range append(bigGaps, shellGaps...) {
+		for i := gap; i < len(s); i++ {
+			current := s[i]
+			j := i
+			for j >= gap && less(current, s[j-gap]) {
+				s[j] = s[j-gap]
+				j -= gap
+			}
+			s[j] = current
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package sorting
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Sorter is implemented by every sorting algorithm of this package, and can be implemented outside of it too.
+// All of them sort slices of integers, as this is what the BubbleSort and SortingGoroutines programs work with:
+// to sort other types, use the generic function of the algorithm (BubbleSortFunc, QuickSortFunc...).
+type Sorter interface {
+	// Name is the short name used to pick the algorithm on the command line (for example "quick").
+	Name() string
+	// Stable tells if equal elements keep their original order.
+	Stable() bool
+	// InPlace tells if the algorithm sorts without allocating memory proportional to the size of the input.
+	InPlace() bool
+	// Sort sorts the slice in increasing order.
+	Sort(s []int)
+}
+
+// identity is the key function given to the generic implementations of the algorithms that sort by key.
+func identity(v int) int { return v }
+
+// sorters is the list of all the algorithms, in the order they are shown to the user.
+var sorters = []Sorter{
+	Bubble{},
+	Cocktail{},
+	OddEven{},
+	ParallelOddEven{},
+	Insertion{},
+	Selection{},
+	Shell{},
+	Heap{},
+	Quick{},
+	Merge{},
+	ParallelMerge{},
+	Counting{},
+	Radix{},
+	Standard{},
+}
+
+// Sorters returns all the available algorithms.
+func Sorters() []Sorter {
+	all := make([]Sorter, len(sorters))
+	copy(all, sorters)
+	return all
+}
+
+// Names returns the names of all the available algorithms, to be shown in help messages.
+func Names() []string {
+	names := make([]string, len(sorters))
+	for i, s := range sorters {
+		names[i] = s.Name()
+	}
+	return names
+}
+
+// Lookup returns the algorithm with the given name.
+func Lookup(name string) (Sorter, error) {
+	for _, s := range sorters {
+		if s.Name() == name {
+			return s, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown sorting algorithm %q (available: %s)", name, strings.Join(Names(), ", "))
+}
+
+// Bubble is the bubble sort, see BubbleSortFunc.
+type Bubble struct{}
+
+func (Bubble) Name() string  { return "bubble" }
+func (Bubble) Stable() bool  { return true }
+func (Bubble) InPlace() bool { return true }
+func (Bubble) Sort(s []int)  { BubbleSort(s) }
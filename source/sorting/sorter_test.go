@@ -0,0 +1,184 @@
+package sorting
+
+import (
+	"math"
+	"math/rand"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// record is an integer key with an identifier, so that we can see where each element ended up after sorting.
+type record struct {
+	key int
+	id  int
+}
+
+// byKey and recordKey order records by their key, for the algorithms that compare elements and the ones that sort by key.
+func byKey(a, b record) bool { return a.key < b.key }
+func recordKey(r record) int { return r.key }
+
+// recordSorts sorts records with the exact same algorithm as the Sorter of each name, through its generic function.
+// Sorting plain integers can't tell if an algorithm is stable, so the tests use these to check the Stable claim.
+var recordSorts = map[string]func(r []record){
+	"bubble":           func(r []record) { BubbleSortFunc(r, byKey) },
+	"cocktail":         func(r []record) { CocktailSortFunc(r, byKey) },
+	"oddeven":          func(r []record) { OddEvenSortFunc(r, byKey) },
+	"oddeven-parallel": func(r []record) { ParallelOddEvenSortFunc(r, byKey, ParallelOddEven{}.Workers) },
+	"insertion":        func(r []record) { InsertionSortFunc(r, byKey) },
+	"selection":        func(r []record) { SelectionSortFunc(r, byKey) },
+	"shell":            func(r []record) { ShellSortFunc(r, byKey) },
+	"heap":             func(r []record) { HeapSortFunc(r, byKey) },
+	"quick":            func(r []record) { QuickSortFunc(r, byKey) },
+	"merge":            func(r []record) { MergeSortFunc(r, byKey) },
+	"merge-parallel":   func(r []record) { ParallelMergeSortFunc(r, byKey, ParallelMerge{}.Cutoff, ParallelMerge{}.Goroutines) },
+	"counting":         func(r []record) { CountingSortFunc(r, recordKey) },
+	"radix":            func(r []record) { RadixSortFunc(r, recordKey) },
+	"std":              func(r []record) { sort.Slice(r, func(i, j int) bool { return r[i].key < r[j].key }) },
+}
+
+// sortRecords sorts records with the algorithm of the sorter, and fails the test if there is no generic function for it.
+func sortRecords(t *testing.T, sorter Sorter, r []record) {
+	t.Helper()
+	sortFunc, ok := recordSorts[sorter.Name()]
+	if !ok {
+		t.Fatalf("no generic function to sort records with %s: add it to recordSorts", sorter.Name())
+	}
+	sortFunc(r)
+}
+
+// conformanceFixtures are the inputs every Sorter must handle. They are shared by all the algorithms, so that they are all held to the same standard.
+func conformanceFixtures() map[string][]int {
+	random := rand.New(rand.NewSource(42))
+	randomSlice := make([]int, 1000)
+	for i := range randomSlice {
+		randomSlice[i] = random.Intn(2000) - 1000
+	}
+	manyDuplicates := make([]int, 500)
+	for i := range manyDuplicates {
+		manyDuplicates[i] = random.Intn(3)
+	}
+	ascending := make([]int, 300)
+	descending := make([]int, 300)
+	for i := range ascending {
+		ascending[i] = i
+		descending[i] = len(descending) - i
+	}
+
+	return map[string][]int{
+		"empty":           {},
+		"single value":    {42},
+		"two values":      {2, 1},
+		"reverse order":   {5, 4, 3, 2, 1},
+		"already sorted":  {1, 2, 3, 4, 5},
+		"negative":        {-5, -1, -3, -2, -4},
+		"mixed signs":     {-5, -1, 3, -2, 4, 0},
+		"all equal":       {7, 7, 7, 7, 7, 7, 7, 7, 7, 7, 7, 7, 7, 7, 7, 7},
+		"extreme values":  {math.MaxInt64, 0, math.MinInt64, -1, 1, math.MaxInt64, math.MinInt64},
+		"random":          randomSlice,
+		"many duplicates": manyDuplicates,
+		"long ascending":  ascending,
+		"long descending": descending,
+	}
+}
+
+func TestSortersConformance(t *testing.T) {
+	for _, sorter := range Sorters() {
+		sorter := sorter
+		t.Run(sorter.Name(), func(t *testing.T) {
+			for name, fixture := range conformanceFixtures() {
+				t.Run(name, func(t *testing.T) {
+					want := make([]int, len(fixture))
+					copy(want, fixture)
+					sort.Ints(want)
+
+					got := make([]int, len(fixture))
+					copy(got, fixture)
+					sorter.Sort(got)
+
+					if !reflect.DeepEqual(got, want) {
+						t.Errorf("%s.Sort(%v) = %v; want %v", sorter.Name(), fixture, got, want)
+					}
+				})
+			}
+		})
+	}
+}
+
+func TestSortersStability(t *testing.T) {
+	// Many records share the same key, and their id is their original position:
+	// after a stable sort, ids must be increasing within each key.
+	random := rand.New(rand.NewSource(7))
+	records := make([]record, 500)
+	for i := range records {
+		records[i] = record{key: random.Intn(10) - 5, id: i}
+	}
+
+	for _, sorter := range Sorters() {
+		if !sorter.Stable() {
+			continue
+		}
+		t.Run(sorter.Name(), func(t *testing.T) {
+			got := make([]record, len(records))
+			copy(got, records)
+			sortRecords(t, sorter, got)
+
+			for i := 1; i < len(got); i++ {
+				if got[i].key < got[i-1].key {
+					t.Fatalf("records are not sorted at index %d: %v then %v", i, got[i-1], got[i])
+				}
+				if got[i].key == got[i-1].key && got[i].id < got[i-1].id {
+					t.Fatalf("%s claims to be stable but %v ended up after %v", sorter.Name(), got[i-1], got[i])
+				}
+			}
+		})
+	}
+}
+
+func TestSortersRecordsAreSorted(t *testing.T) {
+	// recordSorts are what we use to check stability, so we make sure they really sort for every algorithm, even the unstable ones.
+	random := rand.New(rand.NewSource(3))
+	records := make([]record, 200)
+	for i := range records {
+		records[i] = record{key: random.Intn(50), id: i}
+	}
+
+	for _, sorter := range Sorters() {
+		t.Run(sorter.Name(), func(t *testing.T) {
+			got := make([]record, len(records))
+			copy(got, records)
+			sortRecords(t, sorter, got)
+
+			if !sort.SliceIsSorted(got, func(i, j int) bool { return got[i].key < got[j].key }) {
+				t.Errorf("%s did not sort the records: %v", sorter.Name(), got)
+			}
+		})
+	}
+}
+
+func TestLookup(t *testing.T) {
+	for _, name := range Names() {
+		sorter, err := Lookup(name)
+		if err != nil {
+			t.Fatalf("Lookup(%q) returned unexpected error: %v", name, err)
+		}
+		if sorter.Name() != name {
+			t.Errorf("Lookup(%q).Name() = %q", name, sorter.Name())
+		}
+	}
+
+	if _, err := Lookup("bogo"); err == nil {
+		t.Errorf("Lookup(%q) expected an error but got nil", "bogo")
+	}
+}
+
+func TestCountingSortWideRange(t *testing.T) {
+	// With only a few values spread over a huge range, counting sort falls back to radix sort instead of allocating the counts.
+	given := []int{1 << 40, -(1 << 40), 0, 1 << 30}
+	want := []int{-(1 << 40), 0, 1 << 30, 1 << 40}
+
+	Counting{}.Sort(given)
+	if !reflect.DeepEqual(given, want) {
+		t.Errorf("Counting.Sort = %v; want %v", given, want)
+	}
+}
@@ -0,0 +1,17 @@
+// Package sorting holds the sorting algorithms used by the BubbleSort and SortingGoroutines programs.
+// They used to live in package main, which meant they could not be reused anywhere else.
+package sorting
+
+// Ordered is the set of types that can be compared with the < operator.
+// It is the same as golang.org/x/exp/constraints.Ordered, we just declare it here so that the module keeps having no dependencies.
+type Ordered interface {
+	~int | ~int8 | ~int16 | ~int32 | ~int64 |
+		~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64 | ~uintptr |
+		~float32 | ~float64 |
+		~string
+}
+
+// less is the comparison used by the generic functions that work on Ordered types.
+func less[T Ordered](a, b T) bool {
+	return a < b
+}
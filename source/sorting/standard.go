@@ -0,0 +1,12 @@
+package sorting
+
+import "sort"
+
+// Standard is the sort of the standard library (sort.Ints), which is a pattern-defeating quicksort.
+// It is there so that we can compare our algorithms with it, and so that SortingGoroutines can keep using it by default.
+type Standard struct{}
+
+func (Standard) Name() string  { return "std" }
+func (Standard) Stable() bool  { return false }
+func (Standard) InPlace() bool { return true }
+func (Standard) Sort(s []int)  { sort.Ints(s) }
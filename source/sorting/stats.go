@@ -0,0 +1,67 @@
+package sorting
+
+import "math"
+
+// Stats counts the work done by a bubble sort, or one of its variants (cocktail shaker and odd-even transposition).
+type Stats struct {
+	Comparisons int  // Number of times two elements were compared.
+	Swaps       int  // Number of times two elements were swapped.
+	Passes      int  // Number of sweeps of the slice.
+	EarlyExit   bool // True if sweeps without swaps stopped the sort before the number of passes of the worst case.
+}
+
+// Complexity is the theoretical amount of work of a bubble sort. Values are float64, as averages are not whole numbers.
+type Complexity struct {
+	Comparisons float64
+	Swaps       float64
+	Passes      float64
+}
+
+// BubbleSortComplexity returns the number of comparisons, swaps and passes our bubble sort makes on n elements:
+// in the best case (already sorted), on average (over all the orders of n distinct elements), and in the worst case (reversed).
+//
+// Here is how we get the average:
+//   - Each swap fixes exactly one inversion (a pair of elements in the wrong order), and a random order has n(n-1)/4 inversions on average.
+//   - An element moves one step to the left at each sweep, so the number of sweeps that swap something is the largest number of bigger elements
+//     found on the left of an element. Let's call it M. The chance that M ≤ k is k!(k+1)^(n-k)/n! (see Knuth, The Art of Computer Programming, 5.2.2).
+//     We then need one more sweep to see that nothing moves, unless we already made the n-1 sweeps.
+//   - Sweep number p makes n-p comparisons, so we get the comparisons from the distribution of the number of sweeps.
+func BubbleSortComplexity(n int) (best, average, worst Complexity) {
+	if n < 2 {
+		return
+	}
+
+	N := float64(n)
+	best = Complexity{Comparisons: N - 1, Swaps: 0, Passes: 1}
+	worst = Complexity{Comparisons: N * (N - 1) / 2, Swaps: N * (N - 1) / 2, Passes: N - 1}
+
+	average.Swaps = N * (N - 1) / 4
+
+	// comparisonsAfter returns the number of comparisons made by the first p sweeps.
+	comparisonsAfter := func(p float64) float64 {
+		return p*(N-1) - p*(p-1)/2
+	}
+
+	previous := 0.0 // Chance that M ≤ k-1.
+	for k := 0; k < n; k++ {
+		// Chance that M ≤ k, computed with logarithms as the factorials are way too big for a float64.
+		atMost := 1.0
+		if k < n-1 {
+			K := float64(k)
+			lgK, _ := math.Lgamma(K + 1)
+			lgN, _ := math.Lgamma(N + 1)
+			atMost = math.Exp(lgK + (N-K)*math.Log(K+1) - lgN)
+		}
+		chance := atMost - previous
+		previous = atMost
+
+		passes := float64(k + 1)
+		if k == n-1 {
+			passes = N - 1
+		}
+		average.Passes += chance * passes
+		average.Comparisons += chance * comparisonsAfter(passes)
+	}
+
+	return best, average, worst
+}
@@ -0,0 +1,139 @@
+package sorting
+
+import (
+	"math"
+	"testing"
+)
+
+func TestBubbleSortStats(t *testing.T) {
+	testCases := []struct {
+		name  string
+		given []int
+		want  Stats
+	}{
+		{
+			name:  "Already sorted",
+			given: []int{1, 2, 3, 4, 5},
+			want:  Stats{Comparisons: 4, Swaps: 0, Passes: 1, EarlyExit: true},
+		},
+		{
+			name:  "Reverse order",
+			given: []int{5, 4, 3, 2, 1},
+			want:  Stats{Comparisons: 10, Swaps: 10, Passes: 4, EarlyExit: false},
+		},
+		{
+			// 3 inversions: (4,1), (4,3) and (2,1). The biggest number of bigger elements on the left of an element is 2 (for the 1),
+			// so two sweeps swap something and a third one sees that the slice is sorted.
+			name:  "Random order",
+			given: []int{2, 4, 1, 3, 5},
+			want:  Stats{Comparisons: 9, Swaps: 3, Passes: 3, EarlyExit: true},
+		},
+		{
+			name:  "Only the last pair",
+			given: []int{1, 2, 3, 5, 4},
+			want:  Stats{Comparisons: 7, Swaps: 1, Passes: 2, EarlyExit: true},
+		},
+		{
+			name:  "Smallest at the end",
+			given: []int{2, 3, 4, 5, 1},
+			want:  Stats{Comparisons: 10, Swaps: 4, Passes: 4, EarlyExit: false},
+		},
+		{
+			name:  "Empty slice",
+			given: []int{},
+			want:  Stats{},
+		},
+		{
+			name:  "Single value",
+			given: []int{1},
+			want:  Stats{},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := BubbleSort(append([]int{}, tc.given...))
+			if got != tc.want {
+				t.Errorf("BubbleSort(%v) stats = %+v; want %+v", tc.given, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestBubbleSortComplexityBounds(t *testing.T) {
+	for _, n := range []int{2, 3, 10, 100} {
+		best, _, worst := BubbleSortComplexity(n)
+
+		sorted := make([]int, n)
+		reversed := make([]int, n)
+		for i := range sorted {
+			sorted[i] = i
+			reversed[i] = n - i
+		}
+
+		if got := BubbleSort(sorted); !sameCounts(got, best) {
+			t.Errorf("n=%d: sorted input gave %+v; best case is %+v", n, got, best)
+		}
+		if got := BubbleSort(reversed); !sameCounts(got, worst) {
+			t.Errorf("n=%d: reversed input gave %+v; worst case is %+v", n, got, worst)
+		}
+	}
+}
+
+func TestBubbleSortComplexityAverage(t *testing.T) {
+	// For small n, we can sort every permutation and compare the mean of the counts with the theoretical average.
+	for n := 2; n <= 7; n++ {
+		var total Complexity
+		count := 0
+		permutations(n, func(p []int) {
+			stats := BubbleSort(append([]int{}, p...))
+			total.Comparisons += float64(stats.Comparisons)
+			total.Swaps += float64(stats.Swaps)
+			total.Passes += float64(stats.Passes)
+			count++
+		})
+
+		_, average, _ := BubbleSortComplexity(n)
+		mean := Complexity{
+			Comparisons: total.Comparisons / float64(count),
+			Swaps:       total.Swaps / float64(count),
+			Passes:      total.Passes / float64(count),
+		}
+		if !closeTo(mean.Comparisons, average.Comparisons) || !closeTo(mean.Swaps, average.Swaps) || !closeTo(mean.Passes, average.Passes) {
+			t.Errorf("n=%d: measured average %+v; theoretical average %+v", n, mean, average)
+		}
+	}
+}
+
+// sameCounts tells if the measured stats are exactly the theoretical ones.
+func sameCounts(s Stats, c Complexity) bool {
+	return float64(s.Comparisons) == c.Comparisons && float64(s.Swaps) == c.Swaps && float64(s.Passes) == c.Passes
+}
+
+func closeTo(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+// permutations calls f with every order of the numbers 0 to n-1 (Heap's algorithm).
+func permutations(n int, f func([]int)) {
+	p := make([]int, n)
+	for i := range p {
+		p[i] = i
+	}
+	var generate func(k int)
+	generate = func(k int) {
+		if k == 1 {
+			f(p)
+			return
+		}
+		for i := 0; i < k; i++ {
+			generate(k - 1)
+			if k%2 == 0 {
+				p[i], p[k-1] = p[k-1], p[i]
+			} else {
+				p[0], p[k-1] = p[k-1], p[0]
+			}
+		}
+	}
+	generate(n)
+}
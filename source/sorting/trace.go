@@ -0,0 +1,101 @@
+package sorting
+
+import "fmt"
+
+// EventKind tells what happened during a sort.
+type EventKind string
+
+const (
+	// EventStart is sent once, before anything else. It holds a copy of the slice to sort, which is what a replay starts from.
+	EventStart EventKind = "start"
+	// EventCompare is sent each time two neighbours are compared.
+	EventCompare EventKind = "compare"
+	// EventSwap is sent each time two neighbours are swapped. A and B are their values before the swap.
+	EventSwap EventKind = "swap"
+	// EventSweep is sent at the end of each sweep of the slice, with DidSwap telling if the sweep swapped anything.
+	EventSweep EventKind = "sweep"
+)
+
+// Event is one step of a sort, as seen by an Observer.
+// The field tags are the ones used when writing a trace as JSON Lines.
+type Event[T any] struct {
+	Kind    EventKind `json:"kind"`
+	Sweep   int       `json:"sweep"`
+	I       int       `json:"i"`
+	J       int       `json:"j"`
+	A       T         `json:"a"`
+	B       T         `json:"b"`
+	DidSwap bool      `json:"didSwap,omitempty"`
+	Slice   []T       `json:"slice,omitempty"`
+}
+
+// Observer is called by the sort for each Event. It is how we can watch the algorithm move through the data.
+type Observer[T any] func(Event[T])
+
+// String formats the event as one line of human readable text, used by the text traces.
+func (e Event[T]) String() string {
+	switch e.Kind {
+	case EventStart:
+		return fmt.Sprintf("start    %v", e.Slice)
+	case EventCompare:
+		return fmt.Sprintf("compare  sweep=%d [%d]=%v [%d]=%v", e.Sweep, e.I, e.A, e.J, e.B)
+	case EventSwap:
+		return fmt.Sprintf("swap     sweep=%d [%d]=%v <-> [%d]=%v", e.Sweep, e.I, e.A, e.J, e.B)
+	case EventSweep:
+		return fmt.Sprintf("sweep    sweep=%d didSwap=%t", e.Sweep, e.DidSwap)
+	default:
+		return fmt.Sprintf("unknown event %q", e.Kind)
+	}
+}
+
+// Replay rebuilds every intermediate state of the slice from the events of a trace.
+// The first state is the one given by the start event, then there is one new state after each swap.
+// The values recorded in the swap events are checked against the rebuilt slice, so that a trace that does not match its start is reported.
+func Replay[T comparable](events []Event[T]) (states [][]T, err error) {
+	var current []T
+	started := false
+
+	for n, e := range events {
+		switch e.Kind {
+		case EventStart:
+			if started {
+				return nil, fmt.Errorf("event %d: the trace has a second start event", n+1)
+			}
+			started = true
+			current = make([]T, len(e.Slice))
+			copy(current, e.Slice)
+			states = append(states, snapshot(current))
+
+		case EventSwap:
+			if !started {
+				return nil, fmt.Errorf("event %d: swap before the start event", n+1)
+			}
+			if e.I < 0 || e.J < 0 || e.I >= len(current) || e.J >= len(current) {
+				return nil, fmt.Errorf("event %d: swap of [%d] and [%d] is out of bounds for %d elements", n+1, e.I, e.J, len(current))
+			}
+			if current[e.I] != e.A || current[e.J] != e.B {
+				return nil, fmt.Errorf("event %d: the trace swaps %v and %v but the slice holds %v and %v", n+1, e.A, e.B, current[e.I], current[e.J])
+			}
+			current[e.I], current[e.J] = current[e.J], current[e.I]
+			states = append(states, snapshot(current))
+
+		case EventCompare, EventSweep:
+			// These events don't change the slice.
+
+		default:
+			return nil, fmt.Errorf("event %d: unknown kind %q", n+1, e.Kind)
+		}
+	}
+
+	if !started {
+		return nil, fmt.Errorf("the trace has no start event")
+	}
+	return states, nil
+}
+
+// snapshot returns a copy of the slice, so that the caller can keep it while the original continues to change.
+func snapshot[T any](s []T) []T {
+	c := make([]T, len(s))
+	copy(c, s)
+	return c
+}
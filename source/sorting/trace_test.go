@@ -0,0 +1,73 @@
+package sorting
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestBubbleSortObserveEvents(t *testing.T) {
+	var events []Event[int]
+	BubbleSortObserve([]int{3, 1, 2}, less[int], func(e Event[int]) {
+		events = append(events, e)
+	})
+
+	want := []Event[int]{
+		{Kind: EventStart, Slice: []int{3, 1, 2}},
+		{Kind: EventCompare, Sweep: 0, I: 0, J: 1, A: 3, B: 1},
+		{Kind: EventSwap, Sweep: 0, I: 0, J: 1, A: 3, B: 1},
+		{Kind: EventCompare, Sweep: 0, I: 1, J: 2, A: 3, B: 2},
+		{Kind: EventSwap, Sweep: 0, I: 1, J: 2, A: 3, B: 2},
+		{Kind: EventSweep, Sweep: 0, DidSwap: true},
+		{Kind: EventCompare, Sweep: 1, I: 0, J: 1, A: 1, B: 2},
+		{Kind: EventSweep, Sweep: 1, DidSwap: false},
+	}
+	if !reflect.DeepEqual(events, want) {
+		t.Errorf("BubbleSortObserve events =\n%v\nwant\n%v", events, want)
+	}
+}
+
+func TestReplay(t *testing.T) {
+	var events []Event[int]
+	BubbleSortObserve([]int{4, 3, 1, 2}, less[int], func(e Event[int]) {
+		events = append(events, e)
+	})
+
+	states, err := Replay(events)
+	if err != nil {
+		t.Fatalf("Replay returned unexpected error: %v", err)
+	}
+
+	want := [][]int{
+		{4, 3, 1, 2},
+		{3, 4, 1, 2},
+		{3, 1, 4, 2},
+		{3, 1, 2, 4},
+		{1, 3, 2, 4},
+		{1, 2, 3, 4},
+	}
+	if !reflect.DeepEqual(states, want) {
+		t.Errorf("Replay states = %v; want %v", states, want)
+	}
+}
+
+func TestReplayErrors(t *testing.T) {
+	testCases := []struct {
+		name   string
+		events []Event[int]
+	}{
+		{name: "no start", events: []Event[int]{{Kind: EventSweep}}},
+		{name: "swap before start", events: []Event[int]{{Kind: EventSwap, I: 0, J: 1}, {Kind: EventStart, Slice: []int{1, 2}}}},
+		{name: "two starts", events: []Event[int]{{Kind: EventStart, Slice: []int{1}}, {Kind: EventStart, Slice: []int{1}}}},
+		{name: "out of bounds", events: []Event[int]{{Kind: EventStart, Slice: []int{2, 1}}, {Kind: EventSwap, I: 1, J: 2, A: 1, B: 0}}},
+		{name: "values do not match", events: []Event[int]{{Kind: EventStart, Slice: []int{2, 1}}, {Kind: EventSwap, I: 0, J: 1, A: 5, B: 1}}},
+		{name: "unknown kind", events: []Event[int]{{Kind: EventStart, Slice: []int{2, 1}}, {Kind: "shuffle"}}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := Replay(tc.events); err == nil {
+				t.Errorf("Replay(%v) expected an error but got nil", tc.events)
+			}
+		})
+	}
+}